@@ -0,0 +1,95 @@
+// libs/go-common/entitlements/cache.go
+package entitlements
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache keeps entitlements for a short TTL, keyed by user ID. Entries are dropped early when
+// billing-service publishes a change (see Listen); the TTL bounds how stale they get if a
+// notification is missed. Expired entries are removed when they are looked up and by a sweep
+// at most once per TTL, so users who are never looked up again do not pile up.
+type Cache struct {
+	source Source
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[int64]cacheEntry
+	// generation counts invalidations, so a lookup that raced one does not store what it read.
+	generation uint64
+	nextSweep  time.Time
+}
+
+type cacheEntry struct {
+	entitlements Entitlements
+	expiresAt    time.Time
+}
+
+func NewCache(source Source, ttl time.Duration) *Cache {
+	return &Cache{source: source, ttl: ttl, now: time.Now, entries: map[int64]cacheEntry{}}
+}
+
+func (c *Cache) Entitlements(ctx context.Context, userID int64) (Entitlements, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	if ok && !c.now().Before(entry.expiresAt) {
+		delete(c.entries, userID)
+		ok = false
+	}
+	generation := c.generation
+	c.mu.Unlock()
+	if ok {
+		return entry.entitlements, nil
+	}
+
+	e, err := c.source.Entitlements(ctx, userID)
+	if err != nil {
+		return Entitlements{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if c.generation == generation {
+		c.entries[userID] = cacheEntry{entitlements: e, expiresAt: now.Add(c.ttl)}
+	}
+	if !now.Before(c.nextSweep) {
+		c.sweep(now)
+	}
+	return e, nil
+}
+
+// sweep removes the expired entries. c.mu must be held.
+func (c *Cache) sweep(now time.Time) {
+	for userID, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, userID)
+		}
+	}
+	c.nextSweep = now.Add(c.ttl)
+}
+
+// Len returns the number of cached entries, including expired ones not removed yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Invalidate drops the user's entry, so the next lookup asks the source again.
+func (c *Cache) Invalidate(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+	c.generation++
+}
+
+func (c *Cache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[int64]cacheEntry{}
+	c.generation++
+}
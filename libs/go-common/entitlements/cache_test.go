@@ -0,0 +1,84 @@
+// libs/go-common/entitlements/cache_test.go
+package entitlements
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for the cache.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestCache(ttl time.Duration) (*Cache, *countingSource, *fakeClock) {
+	source := &countingSource{lookups: map[int64]int{}}
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := NewCache(source, ttl)
+	cache.now = clock.now
+	return cache, source, clock
+}
+
+func TestCacheServesUntilExpiry(t *testing.T) {
+	cache, source, clock := newTestCache(time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Entitlements(ctx, 1); err != nil {
+			t.Fatalf("lookup: %v", err)
+		}
+	}
+	if got := source.count(1); got != 1 {
+		t.Fatalf("source lookups before expiry = %d, want 1", got)
+	}
+
+	clock.advance(time.Minute)
+	if _, err := cache.Entitlements(ctx, 1); err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if got := source.count(1); got != 2 {
+		t.Fatalf("source lookups after expiry = %d, want 2", got)
+	}
+}
+
+func TestCacheSweepsExpiredEntries(t *testing.T) {
+	cache, _, clock := newTestCache(time.Minute)
+	ctx := context.Background()
+
+	for userID := int64(1); userID <= 100; userID++ {
+		if _, err := cache.Entitlements(ctx, userID); err != nil {
+			t.Fatalf("lookup: %v", err)
+		}
+	}
+	if got := cache.Len(); got != 100 {
+		t.Fatalf("entries = %d, want 100", got)
+	}
+
+	// Users 1-100 are never looked up again; the next store sweeps them out.
+	clock.advance(time.Minute)
+	if _, err := cache.Entitlements(ctx, 101); err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if got := cache.Len(); got != 1 {
+		t.Fatalf("entries after sweep = %d, want 1", got)
+	}
+}
+
+func TestCacheDropsExpiredEntryOnLookup(t *testing.T) {
+	cache, source, clock := newTestCache(time.Minute)
+	ctx := context.Background()
+
+	if _, err := cache.Entitlements(ctx, 1); err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	clock.advance(2 * time.Minute)
+	source.fail = true
+	if _, err := cache.Entitlements(ctx, 1); err == nil {
+		t.Fatal("lookup with a failing source succeeded")
+	}
+	if got := cache.Len(); got != 0 {
+		t.Fatalf("entries after expired lookup = %d, want 0", got)
+	}
+}
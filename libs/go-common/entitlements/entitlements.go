@@ -0,0 +1,58 @@
+// libs/go-common/entitlements/entitlements.go
+
+// Package entitlements defines the permissions a user's plan and add-ons grant, and gives
+// services their live value as billing-service computes it. Permissions in a JWT are frozen
+// at login; use these instead to enforce limits.
+package entitlements
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Channel is the Postgres NOTIFY channel billing-service publishes permission changes on. The
+// payload is the ID of the user whose permissions changed, or AllUsers.
+const Channel = "entitlements_changed"
+
+// AllUsers is the payload of a change that may affect any user, e.g. a plan version migration.
+const AllUsers = "*"
+
+// Source looks up a user's current entitlements.
+type Source interface {
+	Entitlements(ctx context.Context, userID int64) (Entitlements, error)
+}
+
+type billingSource struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewBillingSource asks billing-service's internal API, e.g. at "http://billing-service:8082".
+func NewBillingSource(baseURL string) Source {
+	return &billingSource{baseURL: baseURL, httpClient: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (s *billingSource) Entitlements(ctx context.Context, userID int64) (Entitlements, error) {
+	url := s.baseURL + "/internal/v1/permissions/" + strconv.FormatInt(userID, 10)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return Entitlements{}, err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return Entitlements{}, fmt.Errorf("failed to call billing service: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Entitlements{}, fmt.Errorf("billing service returned status %d", resp.StatusCode)
+	}
+	var e Entitlements
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		return Entitlements{}, fmt.Errorf("failed to decode permissions response: %w", err)
+	}
+	return e, nil
+}
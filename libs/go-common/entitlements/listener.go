@@ -0,0 +1,65 @@
+// libs/go-common/entitlements/listener.go
+package entitlements
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// listenRetryDelay is the wait before listening again after the connection was lost.
+const listenRetryDelay = 5 * time.Second
+
+// Listen invalidates cache entries as billing-service publishes permission changes, blocking
+// until ctx is canceled. Changes published while it is not connected are missed, so the whole
+// cache is dropped whenever it (re)connects.
+func Listen(ctx context.Context, db *pgxpool.Pool, cache *Cache) {
+	for {
+		err := listen(ctx, db, cache)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Listening for entitlement changes failed, retrying in %s: %v", listenRetryDelay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func listen(ctx context.Context, db *pgxpool.Pool, cache *Cache) error {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection keeps listening after its release; never hand it to anyone else. Hijack
+	// detaches it from the pool, so only the returned connection may be used from here on.
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+	cache.InvalidateAll()
+
+	for {
+		n, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if n.Payload == AllUsers {
+			cache.InvalidateAll()
+			continue
+		}
+		userID, err := strconv.ParseInt(n.Payload, 10, 64)
+		if err != nil {
+			log.Printf("Ignoring entitlement change with invalid payload '%s'", n.Payload)
+			continue
+		}
+		cache.Invalidate(userID)
+	}
+}
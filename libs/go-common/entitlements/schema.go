@@ -0,0 +1,330 @@
+// libs/go-common/entitlements/schema.go
+package entitlements
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// SchemaVersion is the current layout of Entitlements. Adding an optional key needs no new
+// version; renaming a key or changing its meaning does, together with a step in upgrade that
+// converts the previous layout. Consumers must be deployed before billing-service writes it.
+const SchemaVersion = 1
+
+// Entitlements are the features and limits a user's plan and add-ons grant. They are stored
+// with every plan version, served by billing-service and carried in the JWT.
+type Entitlements struct {
+	Version int `json:"version"`
+	// StorageQuotaGB is the user's Nextcloud storage.
+	StorageQuotaGB int `json:"storage_quota_gb"`
+	// MaxUploadSizeMB caps the size of a single video upload.
+	MaxUploadSizeMB int `json:"max_upload_size_mb"`
+	// NextcloudGroups are the Nextcloud groups the user is kept in, e.g. ["pro"]. Apps such as
+	// Talk are enabled for these groups in Nextcloud.
+	NextcloudGroups []string `json:"nextcloud_groups,omitempty"`
+	// ReadOnly is set by billing-service while the user stores more than their plan allows,
+	// e.g. after a downgrade. Uploads are refused until they are within their limits again.
+	// It is not part of any plan, so the schema does not accept it.
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// Default returns the entitlements of a user without a subscription, which grant nothing.
+func Default() Entitlements {
+	return Entitlements{Version: SchemaVersion}
+}
+
+// UnmarshalJSON decodes entitlements of any version up to SchemaVersion, upgrading them to it.
+// Unknown keys are ignored, so that consumers keep working while new optional keys roll out.
+func (e *Entitlements) UnmarshalJSON(data []byte) error {
+	type plain Entitlements
+	if err := json.Unmarshal(data, (*plain)(e)); err != nil {
+		return err
+	}
+	if len(e.NextcloudGroups) == 0 {
+		e.NextcloudGroups = nil
+	}
+	return e.upgrade()
+}
+
+func (e *Entitlements) upgrade() error {
+	if e.Version > SchemaVersion {
+		return fmt.Errorf("entitlements version %d is newer than the supported version %d", e.Version, SchemaVersion)
+	}
+	if e.Version == 0 {
+		// Stored before entitlements were versioned, in the layout of version 1.
+		e.Version = 1
+	}
+	return nil
+}
+
+// Validate checks the entitlements against the schema, e.g. before a plan is saved.
+func (e Entitlements) Validate() error {
+	if e.Version == 0 {
+		e.Version = SchemaVersion
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = Parse(data)
+	return err
+}
+
+// Deltas are what one unit of an add-on adds to the entitlements of any plan, e.g.
+// {"storage_quota_gb": 100}. Only numeric keys stack.
+type Deltas struct {
+	StorageQuotaGB  int `json:"storage_quota_gb,omitempty"`
+	MaxUploadSizeMB int `json:"max_upload_size_mb,omitempty"`
+}
+
+// Validate checks that the deltas add something, e.g. before an add-on is saved.
+func (d Deltas) Validate() error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	_, err = ParseDeltas(data)
+	return err
+}
+
+// Add returns e with `quantity` units of d added. Numeric keys are summed; the other keys
+// are the plan's alone.
+func (e Entitlements) Add(d Deltas, quantity int) Entitlements {
+	e.StorageQuotaGB += d.StorageQuotaGB * quantity
+	e.MaxUploadSizeMB += d.MaxUploadSizeMB * quantity
+	return e
+}
+
+// field describes one key of Entitlements. The table drives both validation and the JSON
+// Schema document, so the two cannot disagree.
+type field struct {
+	key       string
+	required  bool
+	stackable bool // Add-ons may add to it
+	schema    map[string]interface{}
+	validate  func(value interface{}) error
+}
+
+var fields = []field{
+	{
+		key:      "version",
+		schema:   map[string]interface{}{"type": "integer", "const": SchemaVersion, "description": "Layout version, set by billing-service if omitted"},
+		validate: currentVersion,
+	},
+	{
+		key:       "storage_quota_gb",
+		required:  true,
+		stackable: true,
+		schema:    map[string]interface{}{"type": "integer", "minimum": 0, "description": "Nextcloud storage in GB"},
+		validate:  nonNegativeInteger,
+	},
+	{
+		key:       "max_upload_size_mb",
+		required:  true,
+		stackable: true,
+		schema:    map[string]interface{}{"type": "integer", "exclusiveMinimum": 0, "description": "Largest video upload in MB"},
+		validate:  positiveInteger,
+	},
+	{
+		key: "nextcloud_groups",
+		schema: map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":      "string",
+				"minLength": 1,
+				"not":       map[string]interface{}{"enum": reservedGroups},
+			},
+			"uniqueItems": true,
+			"description": "Nextcloud groups the user is kept in",
+		},
+		validate: groupList,
+	},
+}
+
+func findField(key string) (field, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// Parse decodes entitlements sent by a client, rejecting unknown keys, wrong types and
+// missing required keys.
+func Parse(data []byte) (Entitlements, error) {
+	values, err := decodeObject(data)
+	if err != nil {
+		return Entitlements{}, err
+	}
+	for _, key := range sortedKeys(values) {
+		f, ok := findField(key)
+		if !ok {
+			return Entitlements{}, fmt.Errorf("unknown permission '%s'", key)
+		}
+		if err := f.validate(values[key]); err != nil {
+			return Entitlements{}, fmt.Errorf("permission '%s': %w", key, err)
+		}
+	}
+	for _, f := range fields {
+		if _, ok := values[f.key]; f.required && !ok {
+			return Entitlements{}, fmt.Errorf("permission '%s' is required", f.key)
+		}
+	}
+
+	var e Entitlements
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Entitlements{}, err
+	}
+	return e, nil
+}
+
+// ParseDeltas decodes add-on deltas sent by a client. Every key must be a stackable
+// permission with a positive amount.
+func ParseDeltas(data []byte) (Deltas, error) {
+	values, err := decodeObject(data)
+	if err != nil {
+		return Deltas{}, err
+	}
+	if len(values) == 0 {
+		return Deltas{}, fmt.Errorf("at least one permission delta is required")
+	}
+	for _, key := range sortedKeys(values) {
+		f, ok := findField(key)
+		if !ok {
+			return Deltas{}, fmt.Errorf("unknown permission '%s'", key)
+		}
+		if !f.stackable {
+			return Deltas{}, fmt.Errorf("permission '%s' cannot be added to", key)
+		}
+		if err := positiveInteger(values[key]); err != nil {
+			return Deltas{}, fmt.Errorf("permission delta '%s': %w", key, err)
+		}
+	}
+
+	var d Deltas
+	if err := json.Unmarshal(data, &d); err != nil {
+		return Deltas{}, err
+	}
+	return d, nil
+}
+
+// Schema returns the JSON Schema (draft 2020-12) of Entitlements, e.g. for admin UIs.
+func Schema() map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, f := range fields {
+		properties[f.key] = f.schema
+		if f.required {
+			required = append(required, f.key)
+		}
+	}
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"$id":                  fmt.Sprintf("urn:jcloud:entitlements:v%d", SchemaVersion),
+		"title":                "Entitlements",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func decodeObject(data []byte) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil || values == nil {
+		return nil, fmt.Errorf("permissions must be a JSON object")
+	}
+	return values, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func integer(value interface{}) (int, error) {
+	n, ok := value.(float64)
+	if !ok || n != math.Trunc(n) || math.Abs(n) > math.MaxInt32 {
+		return 0, fmt.Errorf("must be an integer")
+	}
+	return int(n), nil
+}
+
+func currentVersion(value interface{}) error {
+	n, err := integer(value)
+	if err != nil {
+		return err
+	}
+	if n != SchemaVersion {
+		return fmt.Errorf("must be %d", SchemaVersion)
+	}
+	return nil
+}
+
+func nonNegativeInteger(value interface{}) error {
+	n, err := integer(value)
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return fmt.Errorf("must not be negative")
+	}
+	return nil
+}
+
+func positiveInteger(value interface{}) error {
+	n, err := integer(value)
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return fmt.Errorf("must be positive")
+	}
+	return nil
+}
+
+// reservedGroups are Nextcloud groups with built-in powers. Granting one with a plan would make
+// every subscriber an admin, and the group sync would take it from real admins on downgrades.
+var reservedGroups = []string{"admin"}
+
+// IsReservedGroup reports whether a Nextcloud group must never be granted or revoked by a plan.
+// Nextcloud compares group IDs case-insensitively.
+func IsReservedGroup(group string) bool {
+	for _, reserved := range reservedGroups {
+		if strings.EqualFold(group, reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupList accepts a list of distinct, unreserved Nextcloud group IDs.
+func groupList(value interface{}) error {
+	list, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("must be a list of group names")
+	}
+	seen := make(map[string]bool, len(list))
+	for _, item := range list {
+		group, ok := item.(string)
+		if !ok || strings.TrimSpace(group) == "" || group != strings.TrimSpace(group) {
+			return fmt.Errorf("must be a list of group names")
+		}
+		if IsReservedGroup(group) {
+			return fmt.Errorf("group '%s' is reserved", group)
+		}
+		if seen[group] {
+			return fmt.Errorf("group '%s' is listed twice", group)
+		}
+		seen[group] = true
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+// libs/go-common/entitlements/schema_test.go
+package entitlements
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRejectsReservedGroups(t *testing.T) {
+	for _, group := range []string{"admin", "Admin"} {
+		_, err := Parse([]byte(`{"storage_quota_gb": 5, "max_upload_size_mb": 100, "nextcloud_groups": ["pro", "` + group + `"]}`))
+		if err == nil || !strings.Contains(err.Error(), "is reserved") {
+			t.Errorf("Parse with group %q = %v, want a reserved group error", group, err)
+		}
+	}
+
+	e := Entitlements{StorageQuotaGB: 5, MaxUploadSizeMB: 100, NextcloudGroups: []string{"admin"}}
+	if err := e.Validate(); err == nil {
+		t.Error("Validate with the admin group = nil, want an error")
+	}
+}
+
+func TestParseAcceptsPlanGroups(t *testing.T) {
+	e, err := Parse([]byte(`{"storage_quota_gb": 5, "max_upload_size_mb": 100, "nextcloud_groups": ["pro", "talk-users"]}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(e.NextcloudGroups) != 2 {
+		t.Errorf("groups = %v, want [pro talk-users]", e.NextcloudGroups)
+	}
+}
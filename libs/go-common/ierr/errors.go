@@ -10,4 +10,5 @@ var (
 	ErrForbidden          = errors.New("access forbidden")
 	ErrConflict           = errors.New("resource conflict or duplicate")
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidInput       = errors.New("invalid input")
 )
@@ -1,13 +1,19 @@
 // libs/go-common/types/jwt/claims.go
 package jwt
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"jcloud-project/libs/go-common/entitlements"
+
+	"github.com/golang-jwt/jwt/v5"
+)
 
 // JwtCustomClaims определяет стандартную структуру данных,
 // которую мы помещаем в JWT. Все сервисы будут использовать ее.
 type JwtCustomClaims struct {
-	UserID      int64                  `json:"user_id"`
-	Role        string                 `json:"role,omitempty"`
-	Permissions map[string]interface{} `json:"perms,omitempty"`
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role,omitempty"`
+	// Permissions are a snapshot taken at login, for clients; services enforce limits with
+	// the live entitlements instead.
+	Permissions *entitlements.Entitlements `json:"perms,omitempty"`
 	jwt.RegisteredClaims
 }
@@ -7,10 +7,13 @@ import (
 	"log"
 
 	"jcloud-project/billing-service/internal/client"
+	"jcloud-project/billing-service/internal/client/ocs"
 	"jcloud-project/billing-service/internal/config"
+	"jcloud-project/billing-service/internal/domain"
 	"jcloud-project/billing-service/internal/handler"
 	"jcloud-project/billing-service/internal/repository"
 	"jcloud-project/billing-service/internal/service"
+	"jcloud-project/billing-service/internal/worker"
 	commontypes "jcloud-project/libs/go-common/types/jwt"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -39,15 +42,92 @@ func main() {
 	//
 	planRepo := repository.NewPlanPostgresRepository(dbpool)
 	subRepo := repository.NewSubscriptionPostgresRepository(dbpool)
-
-	nextcloudClient := client.NewNextcloudClient(cfg.Nextcloud.ApiURL, cfg.Nextcloud.ApiUser, cfg.Nextcloud.ApiPassword)
+	invoiceRepo := repository.NewInvoicePostgresRepository(dbpool)
+	trialRepo := repository.NewTrialPostgresRepository(dbpool)
+	couponRepo := repository.NewCouponPostgresRepository(dbpool)
+	taxRateRepo := repository.NewTaxRatePostgresRepository(dbpool)
+	profileRepo := repository.NewBillingProfilePostgresRepository(dbpool)
+	usageRepo := repository.NewUsagePostgresRepository(dbpool)
+	addOnRepo := repository.NewAddOnPostgresRepository(dbpool)
+	walletRepo := repository.NewWalletPostgresRepository(dbpool)
+	creditNoteRepo := repository.NewCreditNotePostgresRepository(dbpool)
+	outboxRepo := repository.NewNextcloudOutboxPostgresRepository(dbpool)
+	storageUsageRepo := repository.NewStorageUsagePostgresRepository(dbpool)
+	reconciliationRepo := repository.NewQuotaReconciliationPostgresRepository(dbpool)
+	webhookRepo := repository.NewWebhookPostgresRepository(dbpool)
+	reportRepo := repository.NewReportPostgresRepository(dbpool)
+	overLimitRepo := repository.NewOverLimitPostgresRepository(dbpool)
+	idempotencyRepo := repository.NewIdempotencyPostgresRepository(dbpool)
+
+	nextcloudClient := client.NewNextcloudClient(ocs.Config{
+		BaseURL:     cfg.Nextcloud.ApiURL,
+		User:        cfg.Nextcloud.ApiUser,
+		Password:    cfg.Nextcloud.ApiPassword,
+		Timeout:     cfg.Nextcloud.Timeout,
+		MaxRetries:  cfg.Nextcloud.MaxRetries,
+		RetryDelay:  cfg.Nextcloud.RetryDelay,
+		LogRequests: cfg.Nextcloud.LogRequests,
+	})
 	userSvcClient := client.NewUserServiceClient()
+	notifier := client.NewLogNotifier()
+	paymentProvider := client.NewHostedCheckoutProvider(cfg.Payment.CheckoutURL, cfg.Payment.ApiURL, cfg.Payment.WebhookSecret)
+	webhookSender := client.NewHTTPWebhookSender(cfg.Webhook.Timeout)
 
-	billingService := service.NewBillingService(planRepo, subRepo, nextcloudClient, userSvcClient)
+	if !domain.IsOverLimitPolicy(cfg.Billing.OverLimitPolicy) {
+		log.Fatalf("Unknown over-limit policy '%s'", cfg.Billing.OverLimitPolicy)
+	}
+	overLimitService := service.NewOverLimitService(overLimitRepo, storageUsageRepo, notifier, cfg.Billing.OverLimitPolicy, cfg.Billing.OverLimitGrace)
+	billingService := service.NewBillingService(planRepo, subRepo, invoiceRepo, trialRepo, couponRepo, taxRateRepo, profileRepo, usageRepo, addOnRepo, walletRepo, creditNoteRepo,
+		userSvcClient, notifier, paymentProvider, overLimitService, cfg.Billing.DefaultCurrency, cfg.Billing.SellerCountry)
+	couponService := service.NewCouponService(couponRepo, planRepo)
+	planService := service.NewPlanService(planRepo, subRepo, notifier, cfg.Billing.DefaultCurrency)
+	taxService := service.NewTaxService(taxRateRepo, profileRepo)
+	addOnService := service.NewAddOnService(addOnRepo, cfg.Billing.DefaultCurrency)
+	walletService := service.NewWalletService(walletRepo, paymentProvider)
+	nextcloudSyncService := service.NewNextcloudSyncService(outboxRepo, planRepo, billingService, userSvcClient, nextcloudClient,
+		cfg.Nextcloud.SyncMaxAttempts, cfg.Nextcloud.SyncRetryDelay)
+	storageUsageService := service.NewStorageUsageService(storageUsageRepo, subRepo, billingService, userSvcClient,
+		nextcloudClient, notifier, overLimitService, cfg.Nextcloud.UsageCacheTTL)
+	reconciliationService := service.NewQuotaReconciliationService(reconciliationRepo, subRepo, outboxRepo, billingService,
+		userSvcClient, nextcloudClient)
+	webhookService := service.NewWebhookService(webhookRepo, webhookSender, cfg.Webhook.MaxAttempts, cfg.Webhook.RetryDelay)
+	reportService := service.NewReportService(reportRepo)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, cfg.Billing.IdempotencyTTL)
 
 	planHandler := handler.NewPlanHandler(billingService)
 	subHandler := handler.NewSubscriptionHandler(billingService)
 	internalApiHandler := handler.NewInternalApiHandler(billingService)
+	couponHandler := handler.NewCouponHandler(couponService)
+	planAdminHandler := handler.NewPlanAdminHandler(planService)
+	taxHandler := handler.NewTaxHandler(taxService)
+	addOnAdminHandler := handler.NewAddOnAdminHandler(addOnService)
+	walletHandler := handler.NewWalletHandler(walletService)
+	refundHandler := handler.NewRefundAdminHandler(billingService)
+	subAdminHandler := handler.NewSubscriptionAdminHandler(billingService)
+	nextcloudSyncHandler := handler.NewNextcloudSyncAdminHandler(nextcloudSyncService)
+	reconciliationHandler := handler.NewQuotaReconciliationHandler(reconciliationService)
+	storageUsageHandler := handler.NewStorageUsageHandler(storageUsageService)
+	webhookHandler := handler.NewWebhookAdminHandler(webhookService)
+	reportHandler := handler.NewReportHandler(reportService)
+	overLimitHandler := handler.NewOverLimitAdminHandler(overLimitService)
+
+	//
+	// Background Workers
+	//
+	renewalWorker := worker.NewRenewalWorker(billingService, cfg.Billing.RenewalInterval)
+	go renewalWorker.Run(context.Background())
+	nextcloudSyncWorker := worker.NewNextcloudSyncWorker(nextcloudSyncService, cfg.Nextcloud.SyncInterval)
+	go nextcloudSyncWorker.Run(context.Background())
+	reconciliationWorker := worker.NewQuotaReconciliationWorker(reconciliationService, cfg.Nextcloud.ReconcileInterval, !cfg.Nextcloud.ReconcileFix)
+	go reconciliationWorker.Run(context.Background())
+	storageUsageWorker := worker.NewStorageUsageWorker(storageUsageService, cfg.Nextcloud.UsageInterval)
+	go storageUsageWorker.Run(context.Background())
+	webhookWorker := worker.NewWebhookWorker(webhookService, cfg.Webhook.Interval)
+	go webhookWorker.Run(context.Background())
+	overLimitWorker := worker.NewOverLimitWorker(overLimitService, cfg.Billing.OverLimitInterval)
+	go overLimitWorker.Run(context.Background())
+	repairWorker := worker.NewSubscriptionRepairWorker(billingService, cfg.Billing.RepairInterval)
+	go repairWorker.Run(context.Background())
 
 	//
 	// HTTP Server (Echo)
@@ -69,17 +149,84 @@ func main() {
 
 	// Public routes
 	api.GET("/plans", planHandler.GetAllPlans)
+	api.GET("/add-ons", planHandler.GetAvailableAddOns)
+	api.POST("/payments/webhook", walletHandler.HandlePaymentWebhook) // Authenticated by its signature
 
 	// Protected routes
 	subscriptionsAPI := api.Group("/subscriptions")
 	subscriptionsAPI.Use(echojwt.WithConfig(jwtConfig))
 	subscriptionsAPI.GET("/me", subHandler.GetUserSubscription)
+	subscriptionsAPI.GET("/me/history", subHandler.GetSubscriptionHistory)
+	subscriptionsAPI.POST("/me/cancel", subHandler.CancelSubscription)
+	subscriptionsAPI.POST("/me/resume", subHandler.ResumeSubscription)
+	subscriptionsAPI.POST("/me/trial", subHandler.StartTrial)
+	subscriptionsAPI.GET("/me/usage", subHandler.GetCurrentUsage)
+	subscriptionsAPI.GET("/me/storage", storageUsageHandler.GetStorageUsage)
+	subscriptionsAPI.GET("/me/add-ons", subHandler.GetUserAddOns)
+	subscriptionsAPI.POST("/me/add-ons", subHandler.AddAddOn)
+	subscriptionsAPI.DELETE("/me/add-ons/:addOnId", subHandler.RemoveAddOn)
+	subscriptionsAPI.GET("/me/wallet", walletHandler.GetWallet)
+	subscriptionsAPI.GET("/me/wallet/transactions", walletHandler.GetTransactions)
+	subscriptionsAPI.POST("/me/wallet/top-ups", walletHandler.StartTopUp)
+	subscriptionsAPI.GET("/me/billing-profile", taxHandler.GetBillingProfile)
+	subscriptionsAPI.PUT("/me/billing-profile", taxHandler.SaveBillingProfile)
+	subscriptionsAPI.GET("/preview", subHandler.PreviewSubscriptionChange)
 	subscriptionsAPI.POST("", subHandler.ChangeSubscription)
 
+	// Admin routes
+	adminAPI := api.Group("/admin")
+	adminAPI.Use(echojwt.WithConfig(jwtConfig))
+	adminAPI.Use(handler.AdminMiddleware)
+	adminAPI.GET("/plans", planAdminHandler.GetAllPlans)
+	adminAPI.POST("/plans", planAdminHandler.CreatePlan)
+	adminAPI.PUT("/plans/order", planAdminHandler.ReorderPlans)
+	adminAPI.PATCH("/plans/:planId", planAdminHandler.PatchPlan)
+	adminAPI.DELETE("/plans/:planId", planAdminHandler.ArchivePlan)
+	adminAPI.GET("/plans/:planId/versions", planAdminHandler.GetPlanVersions)
+	adminAPI.POST("/plans/:planId/versions/:versionId/migrations", planAdminHandler.ScheduleVersionMigration)
+	adminAPI.GET("/entitlements/schema", planAdminHandler.GetEntitlementsSchema)
+	adminAPI.GET("/coupons", couponHandler.GetAllCoupons)
+	adminAPI.POST("/coupons", couponHandler.CreateCoupon)
+	adminAPI.GET("/coupons/:couponId", couponHandler.GetCoupon)
+	adminAPI.PATCH("/coupons/:couponId", couponHandler.PatchCoupon)
+	adminAPI.DELETE("/coupons/:couponId", couponHandler.DeactivateCoupon)
+	adminAPI.GET("/add-ons", addOnAdminHandler.GetAllAddOns)
+	adminAPI.POST("/add-ons", addOnAdminHandler.CreateAddOn)
+	adminAPI.PATCH("/add-ons/:addOnId", addOnAdminHandler.PatchAddOn)
+	adminAPI.DELETE("/add-ons/:addOnId", addOnAdminHandler.ArchiveAddOn)
+	adminAPI.GET("/users/:userId/subscription-history", subAdminHandler.GetSubscriptionHistory)
+	adminAPI.POST("/subscription-repairs", subAdminHandler.RepairSubscriptions)
+	adminAPI.POST("/users/:userId/wallet/refunds", walletHandler.RefundToBalance)
+	adminAPI.GET("/invoices/:invoiceId/credit-notes", refundHandler.GetCreditNotes)
+	adminAPI.POST("/invoices/:invoiceId/refunds", refundHandler.RefundInvoice)
+	adminAPI.GET("/nextcloud-syncs", nextcloudSyncHandler.GetSyncs)
+	adminAPI.POST("/nextcloud-syncs/:syncId/replay", nextcloudSyncHandler.ReplaySync)
+	adminAPI.GET("/quota-reconciliations", reconciliationHandler.GetReconciliations)
+	adminAPI.POST("/quota-reconciliations", reconciliationHandler.Reconcile)
+	adminAPI.GET("/webhooks/event-types", webhookHandler.GetEventTypes)
+	adminAPI.GET("/webhooks", webhookHandler.GetEndpoints)
+	adminAPI.POST("/webhooks", webhookHandler.CreateEndpoint)
+	adminAPI.PATCH("/webhooks/:endpointId", webhookHandler.PatchEndpoint)
+	adminAPI.DELETE("/webhooks/:endpointId", webhookHandler.DeleteEndpoint)
+	adminAPI.POST("/webhooks/:endpointId/secret", webhookHandler.RotateSecret)
+	adminAPI.GET("/webhooks/:endpointId/deliveries", webhookHandler.GetDeliveries)
+	adminAPI.GET("/webhook-deliveries/:deliveryId", webhookHandler.GetDelivery)
+	adminAPI.POST("/webhook-deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+	adminAPI.GET("/over-limit", overLimitHandler.GetCases)
+	adminAPI.GET("/reports/mrr", reportHandler.GetMRR)
+	adminAPI.GET("/reports/plan-mix", reportHandler.GetPlanMix)
+	adminAPI.GET("/reports/trial-conversions", reportHandler.GetTrialConversions)
+	adminAPI.GET("/tax-rates", taxHandler.GetTaxRates)
+	adminAPI.PUT("/tax-rates", taxHandler.SaveTaxRate)
+	adminAPI.DELETE("/tax-rates/:taxRateId", taxHandler.DeleteTaxRate)
+
 	// Internal routes
 	internalAPI := e.Group("/internal/v1")
 	internalAPI.GET("/permissions/:userId", internalApiHandler.GetUserPermissions)
-	internalAPI.POST("/subscriptions", internalApiHandler.CreateSubscription)
+	idempotency := handler.IdempotencyMiddleware(idempotencyService)
+	internalAPI.POST("/subscriptions", internalApiHandler.CreateSubscription, idempotency)
+	internalAPI.POST("/usage", internalApiHandler.RecordUsage, idempotency)
+	internalAPI.PUT("/storage/:userId/video", storageUsageHandler.RecordVideoUsage)
 
 	// Start server
 	log.Println("Starting billing-service on :8082")
@@ -4,98 +4,215 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"jcloud-project/billing-service/internal/client/ocs"
 	"net/url"
-	"strings"
 )
 
+var (
+	ErrNextcloudUserNotFound = errors.New("nextcloud user not found")
+	ErrNextcloudUserExists   = errors.New("nextcloud user already exists")
+)
+
+// ocsStatusExists is what creating a user or group that already exists returns.
+const ocsStatusExists = 102
+
 //
-// OCSResponse defines the structure for a typical Nextcloud OCS JSON response.
-// This allows us to properly parse the response and check the application-level status code.
+// Nextcloud Client
 //
 
-type OCSResponse struct {
-	Ocs OCS `json:"ocs"`
+type NextcloudClient interface {
+	SetUserQuota(ctx context.Context, username string, quotaGB int) error
+	// ListUsers returns one page of Nextcloud user IDs, in the order Nextcloud keeps them.
+	ListUsers(ctx context.Context, offset, limit int) ([]string, error)
+	// GetUserQuota returns the quota currently set for a user and how much of it is used.
+	GetUserQuota(ctx context.Context, username string) (*NextcloudQuota, error)
+	// UserExists reports whether a Nextcloud account with this user ID exists.
+	UserExists(ctx context.Context, username string) (bool, error)
+	// CreateUser creates an account without a password; Nextcloud emails the user a link to
+	// set one. Returns ErrNextcloudUserExists if it already exists.
+	CreateUser(ctx context.Context, user NextcloudNewUser) error
+	// DisableUser blocks logins and syncs of an account, keeping its files.
+	DisableUser(ctx context.Context, username string) error
+	EnableUser(ctx context.Context, username string) error
+	// DeleteUser deletes an account and its files. Returns ErrNextcloudUserNotFound if it does
+	// not exist, e.g. because an earlier attempt deleted it already.
+	DeleteUser(ctx context.Context, username string) error
+	// CreateGroup creates a group, doing nothing if it already exists.
+	CreateGroup(ctx context.Context, group string) error
+	GetUserGroups(ctx context.Context, username string) ([]string, error)
+	AddUserToGroup(ctx context.Context, username, group string) error
+	RemoveUserFromGroup(ctx context.Context, username, group string) error
+	// CreateAppPassword exchanges a user's login password for a new app password, e.g. for
+	// WebDAV or desktop clients. It authenticates as the user, not as the API user, and is not
+	// retried: every request creates another app password.
+	CreateAppPassword(ctx context.Context, username, password string) (string, error)
+	// DeleteAppPassword revokes an app password, authenticating with the app password itself.
+	DeleteAppPassword(ctx context.Context, username, appPassword string) error
 }
 
-type OCS struct {
-	Meta OCSMeta     `json:"meta"`
-	Data interface{} `json:"data"` // Data can be of any type, so we use interface{}
+// NextcloudQuota is a user's storage quota as Nextcloud reports it.
+type NextcloudQuota struct {
+	Bytes     int64 // The quota, meaningless if Unlimited
+	Unlimited bool
+	UsedBytes int64
+	FreeBytes int64 // Space left, bounded by the free disk space for unlimited users
 }
 
-type OCSMeta struct {
-	Status     string `json:"status"`
-	StatusCode int    `json:"statuscode"`
-	Message    string `json:"message"`
+// NextcloudNewUser describes an account to create. Email is required, it receives the link
+// to set the password.
+type NextcloudNewUser struct {
+	Username string
+	Email    string
+	QuotaGB  int
+	Groups   []string
 }
 
-//
-// Nextcloud Client
-//
+type nextcloudClient struct {
+	api *ocs.Client
+}
 
-type NextcloudClient interface {
-	SetUserQuota(ctx context.Context, username string, quotaGB int) error
+// NewNextcloudClient returns a client of the provisioning API. Failures are *ocs.Error values,
+// which match ocs.ErrNotFound, ocs.ErrInvalidInput and ocs.ErrUnauthorized.
+func NewNextcloudClient(cfg ocs.Config) NextcloudClient {
+	return &nextcloudClient{api: ocs.NewClient(cfg)}
 }
 
-type nextcloudClient struct {
-	baseURL     string
-	apiUser     string
-	apiPassword string
-	httpClient  *http.Client
-}
-
-func NewNextcloudClient(baseURL, apiUser, apiPassword string) NextcloudClient {
-	return &nextcloudClient{
-		baseURL:     baseURL,
-		apiUser:     apiUser,
-		apiPassword: apiPassword,
-		httpClient:  &http.Client{},
-	}
+func userPath(username string) string {
+	return "/ocs/v2.php/cloud/users/" + url.PathEscape(username)
 }
 
 // SetUserQuota updates a user's storage quota in Nextcloud using the v2 JSON API.
 func (c *nextcloudClient) SetUserQuota(ctx context.Context, username string, quotaGB int) error {
-	// Use the v2 API endpoint to get JSON responses
-	endpoint := fmt.Sprintf("%s/ocs/v2.php/cloud/users/%s", c.baseURL, url.PathEscape(username))
-
 	// The request body remains form-urlencoded as it's a simple key-value update
 	data := url.Values{}
 	data.Set("key", "quota")
 	data.Set("value", fmt.Sprintf("%d GB", quotaGB))
+	return c.api.Do(ctx, "PUT", userPath(username), data, nil)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, strings.NewReader(data.Encode()))
-	if err != nil {
-		return fmt.Errorf("failed to create nextcloud request: %w", err)
+func (c *nextcloudClient) ListUsers(ctx context.Context, offset, limit int) ([]string, error) {
+	var data struct {
+		Users []string `json:"users"`
+	}
+	path := fmt.Sprintf("/ocs/v2.php/cloud/users?offset=%d&limit=%d", offset, limit)
+	if err := c.api.Do(ctx, "GET", path, nil, &data); err != nil {
+		return nil, err
 	}
+	return data.Users, nil
+}
 
-	// Set required headers for OCS API v2
-	req.SetBasicAuth(c.apiUser, c.apiPassword)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("OCS-APIRequest", "true")
-	req.Header.Add("Accept", "application/json") // <-- Crucial for getting a JSON response
+func (c *nextcloudClient) GetUserQuota(ctx context.Context, username string) (*NextcloudQuota, error) {
+	var data struct {
+		Quota struct {
+			// Quota is a byte count, or a negative value or "none" for users without a limit.
+			Quota json.RawMessage `json:"quota"`
+			Used  int64           `json:"used"`
+			Free  int64           `json:"free"`
+		} `json:"quota"`
+	}
+	if err := c.api.Do(ctx, "GET", userPath(username), nil, &data); err != nil {
+		if errors.Is(err, ocs.ErrNotFound) {
+			return nil, ErrNextcloudUserNotFound
+		}
+		return nil, err
+	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to execute nextcloud request: %w", err)
+	quota := &NextcloudQuota{UsedBytes: data.Quota.Used, FreeBytes: data.Quota.Free}
+	var bytes int64
+	if err := json.Unmarshal(data.Quota.Quota, &bytes); err != nil {
+		quota.Unlimited = true
+		return quota, nil
 	}
-	defer resp.Body.Close()
+	quota.Bytes = bytes
+	quota.Unlimited = bytes < 0
+	return quota, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("nextcloud API returned non-200 HTTP status: %d", resp.StatusCode)
+func (c *nextcloudClient) UserExists(ctx context.Context, username string) (bool, error) {
+	err := c.api.Do(ctx, "GET", userPath(username), nil, nil)
+	if errors.Is(err, ocs.ErrNotFound) {
+		return false, nil
 	}
+	return err == nil, err
+}
 
-	// Decode the JSON response
-	var ocsResponse OCSResponse
-	if err := json.NewDecoder(resp.Body).Decode(&ocsResponse); err != nil {
-		return fmt.Errorf("failed to decode nextcloud JSON response: %w", err)
+func (c *nextcloudClient) CreateUser(ctx context.Context, user NextcloudNewUser) error {
+	data := url.Values{}
+	data.Set("userid", user.Username)
+	data.Set("email", user.Email)
+	data.Set("quota", fmt.Sprintf("%d GB", user.QuotaGB))
+	for _, group := range user.Groups {
+		data.Add("groups[]", group)
+	}
+	// A retry after the account was created fails with ocsStatusExists, which callers handle.
+	err := c.api.Do(ctx, "POST", "/ocs/v2.php/cloud/users", data, nil, ocs.RetryPost())
+	if ocs.IsStatus(err, ocsStatusExists) {
+		return ErrNextcloudUserExists
 	}
+	return err
+}
+
+func (c *nextcloudClient) DisableUser(ctx context.Context, username string) error {
+	return c.api.Do(ctx, "PUT", userPath(username)+"/disable", url.Values{}, nil)
+}
+
+func (c *nextcloudClient) EnableUser(ctx context.Context, username string) error {
+	return c.api.Do(ctx, "PUT", userPath(username)+"/enable", url.Values{}, nil)
+}
+
+func (c *nextcloudClient) DeleteUser(ctx context.Context, username string) error {
+	err := c.api.Do(ctx, "DELETE", userPath(username), nil, nil)
+	if errors.Is(err, ocs.ErrNotFound) {
+		return ErrNextcloudUserNotFound
+	}
+	return err
+}
+
+func (c *nextcloudClient) CreateGroup(ctx context.Context, group string) error {
+	data := url.Values{}
+	data.Set("groupid", group)
+	err := c.api.Do(ctx, "POST", "/ocs/v2.php/cloud/groups", data, nil, ocs.RetryPost())
+	if ocs.IsStatus(err, ocsStatusExists) {
+		return nil
+	}
+	return err
+}
 
-	// Check the application-level status code inside the JSON response
-	if ocsResponse.Ocs.Meta.StatusCode != 200 {
-		return fmt.Errorf("nextcloud OCS API returned an error: status=%d, message='%s'",
-			ocsResponse.Ocs.Meta.StatusCode, ocsResponse.Ocs.Meta.Message)
+func (c *nextcloudClient) GetUserGroups(ctx context.Context, username string) ([]string, error) {
+	var data struct {
+		Groups []string `json:"groups"`
 	}
+	if err := c.api.Do(ctx, "GET", userPath(username)+"/groups", nil, &data); err != nil {
+		return nil, err
+	}
+	return data.Groups, nil
+}
+
+func (c *nextcloudClient) AddUserToGroup(ctx context.Context, username, group string) error {
+	data := url.Values{}
+	data.Set("groupid", group)
+	// Adding a member twice is harmless.
+	return c.api.Do(ctx, "POST", userPath(username)+"/groups", data, nil, ocs.RetryPost())
+}
+
+func (c *nextcloudClient) RemoveUserFromGroup(ctx context.Context, username, group string) error {
+	data := url.Values{}
+	data.Set("groupid", group)
+	return c.api.Do(ctx, "DELETE", userPath(username)+"/groups", data, nil)
+}
+
+func (c *nextcloudClient) CreateAppPassword(ctx context.Context, username, password string) (string, error) {
+	var data struct {
+		AppPassword string `json:"apppassword"`
+	}
+	if err := c.api.Do(ctx, "GET", "/ocs/v2.php/core/getapppassword", nil, &data, ocs.As(username, password), ocs.NoRetry()); err != nil {
+		return "", err
+	}
+	return data.AppPassword, nil
+}
 
-	return nil
+func (c *nextcloudClient) DeleteAppPassword(ctx context.Context, username, appPassword string) error {
+	return c.api.Do(ctx, "DELETE", "/ocs/v2.php/core/apppassword", nil, nil, ocs.As(username, appPassword))
 }
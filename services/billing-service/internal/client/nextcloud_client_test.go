@@ -0,0 +1,199 @@
+// services/billing-service/internal/client/nextcloud_client_test.go
+package client
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/client/ocs"
+	"jcloud-project/billing-service/internal/client/ocs/ocstest"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestNextcloudClient(t *testing.T) (NextcloudClient, *ocstest.Server) {
+	t.Helper()
+	srv := ocstest.NewServer()
+	t.Cleanup(srv.Close)
+	c := NewNextcloudClient(ocs.Config{
+		BaseURL:    srv.URL,
+		User:       ocstest.AdminUser,
+		Password:   ocstest.AdminPassword,
+		RetryDelay: time.Millisecond,
+	})
+	return c, srv
+}
+
+func TestCreateUser(t *testing.T) {
+	c, srv := newTestNextcloudClient(t)
+	ctx := context.Background()
+	if err := c.CreateGroup(ctx, "pro"); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+
+	// A lost response must not fail the provisioning: the retry reports the account as existing.
+	srv.FailNext(http.StatusBadGateway)
+	user := NextcloudNewUser{Username: "alice@example.com", Email: "alice@example.com", QuotaGB: 5, Groups: []string{"pro"}}
+	if err := c.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	got, ok := srv.User("alice@example.com")
+	if !ok {
+		t.Fatal("account was not created")
+	}
+	if got.Email != "alice@example.com" || got.QuotaBytes != 5<<30 || !slices.Equal(got.Groups, []string{"pro"}) {
+		t.Errorf("account = %+v", got)
+	}
+
+	if err := c.CreateUser(ctx, user); !errors.Is(err, ErrNextcloudUserExists) {
+		t.Errorf("second CreateUser = %v, want ErrNextcloudUserExists", err)
+	}
+}
+
+func TestCreateUserRequiresEmail(t *testing.T) {
+	c, _ := newTestNextcloudClient(t)
+
+	// Without a password Nextcloud needs the email to send the set-password link.
+	err := c.CreateUser(context.Background(), NextcloudNewUser{Username: "bob", QuotaGB: 1})
+	if !ocs.IsStatus(err, 108) {
+		t.Errorf("CreateUser without email = %v, want OCS status 108", err)
+	}
+}
+
+func TestGetUserQuota(t *testing.T) {
+	c, srv := newTestNextcloudClient(t)
+	ctx := context.Background()
+	srv.AddUser(ocstest.User{ID: "limited", QuotaBytes: 10 << 30, UsedBytes: 4 << 30})
+	srv.AddUser(ocstest.User{ID: "unlimited", QuotaBytes: ocstest.Unlimited, UsedBytes: 1 << 30})
+
+	quota, err := c.GetUserQuota(ctx, "limited")
+	if err != nil {
+		t.Fatalf("GetUserQuota: %v", err)
+	}
+	if quota.Unlimited || quota.Bytes != 10<<30 || quota.UsedBytes != 4<<30 || quota.FreeBytes != 6<<30 {
+		t.Errorf("limited quota = %+v", quota)
+	}
+
+	quota, err = c.GetUserQuota(ctx, "unlimited")
+	if err != nil {
+		t.Fatalf("GetUserQuota: %v", err)
+	}
+	if !quota.Unlimited || quota.UsedBytes != 1<<30 {
+		t.Errorf("unlimited quota = %+v", quota)
+	}
+
+	if _, err := c.GetUserQuota(ctx, "nobody"); !errors.Is(err, ErrNextcloudUserNotFound) {
+		t.Errorf("GetUserQuota of a missing user = %v, want ErrNextcloudUserNotFound", err)
+	}
+}
+
+func TestUserGroups(t *testing.T) {
+	c, srv := newTestNextcloudClient(t)
+	ctx := context.Background()
+	srv.AddUser(ocstest.User{ID: "alice", QuotaBytes: ocstest.Unlimited})
+	if err := c.CreateGroup(ctx, "pro"); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if err := c.CreateGroup(ctx, "pro"); err != nil {
+		t.Errorf("CreateGroup of an existing group = %v, want nil", err)
+	}
+
+	if err := c.AddUserToGroup(ctx, "alice", "pro"); err != nil {
+		t.Fatalf("AddUserToGroup: %v", err)
+	}
+	groups, err := c.GetUserGroups(ctx, "alice")
+	if err != nil || !slices.Equal(groups, []string{"pro"}) {
+		t.Errorf("GetUserGroups = %v, %v, want [pro]", groups, err)
+	}
+
+	if err := c.RemoveUserFromGroup(ctx, "alice", "pro"); err != nil {
+		t.Fatalf("RemoveUserFromGroup: %v", err)
+	}
+	groups, err = c.GetUserGroups(ctx, "alice")
+	if err != nil || len(groups) != 0 {
+		t.Errorf("GetUserGroups after removal = %v, %v, want none", groups, err)
+	}
+}
+
+func TestListUsersAndUserExists(t *testing.T) {
+	c, srv := newTestNextcloudClient(t)
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		srv.AddUser(ocstest.User{ID: id, QuotaBytes: ocstest.Unlimited})
+	}
+
+	page, err := c.ListUsers(ctx, 1, 5)
+	if err != nil || !slices.Equal(page, []string{"b", "c"}) {
+		t.Errorf("ListUsers(1, 5) = %v, %v, want [b c]", page, err)
+	}
+
+	if exists, err := c.UserExists(ctx, "b"); err != nil || !exists {
+		t.Errorf("UserExists(b) = %t, %v, want true", exists, err)
+	}
+	if exists, err := c.UserExists(ctx, "z"); err != nil || exists {
+		t.Errorf("UserExists(z) = %t, %v, want false", exists, err)
+	}
+}
+
+func TestDisableEnableDeleteUser(t *testing.T) {
+	c, srv := newTestNextcloudClient(t)
+	ctx := context.Background()
+	srv.AddUser(ocstest.User{ID: "alice", QuotaBytes: ocstest.Unlimited})
+
+	if err := c.DisableUser(ctx, "alice"); err != nil {
+		t.Fatalf("DisableUser: %v", err)
+	}
+	if u, _ := srv.User("alice"); !u.Disabled {
+		t.Error("account is enabled after DisableUser")
+	}
+	if err := c.EnableUser(ctx, "alice"); err != nil {
+		t.Fatalf("EnableUser: %v", err)
+	}
+	if u, _ := srv.User("alice"); u.Disabled {
+		t.Error("account is disabled after EnableUser")
+	}
+
+	if err := c.DeleteUser(ctx, "alice"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, ok := srv.User("alice"); ok {
+		t.Error("account exists after DeleteUser")
+	}
+	if err := c.DeleteUser(ctx, "alice"); !errors.Is(err, ErrNextcloudUserNotFound) {
+		t.Errorf("second DeleteUser = %v, want ErrNextcloudUserNotFound", err)
+	}
+}
+
+func TestAppPasswords(t *testing.T) {
+	c, srv := newTestNextcloudClient(t)
+	ctx := context.Background()
+	srv.AddUser(ocstest.User{ID: "alice", Password: "login-password", QuotaBytes: ocstest.Unlimited})
+
+	if _, err := c.CreateAppPassword(ctx, "alice", "wrong"); !errors.Is(err, ocs.ErrUnauthorized) {
+		t.Errorf("CreateAppPassword with a wrong password = %v, want ErrUnauthorized", err)
+	}
+
+	// A lost response is not retried, which would leave an app password nobody knows.
+	srv.FailNext(http.StatusBadGateway)
+	if _, err := c.CreateAppPassword(ctx, "alice", "login-password"); err == nil {
+		t.Fatal("CreateAppPassword succeeded, want the 502 without a retry")
+	}
+	appPassword, err := c.CreateAppPassword(ctx, "alice", "login-password")
+	if err != nil {
+		t.Fatalf("CreateAppPassword: %v", err)
+	}
+	if u, _ := srv.User("alice"); !slices.Equal(u.AppPasswords, []string{appPassword}) {
+		t.Errorf("app passwords = %v, want [%s]", u.AppPasswords, appPassword)
+	}
+
+	if err := c.DeleteAppPassword(ctx, "alice", "login-password"); err == nil {
+		t.Error("DeleteAppPassword with the login password succeeded, want an error")
+	}
+	if err := c.DeleteAppPassword(ctx, "alice", appPassword); err != nil {
+		t.Fatalf("DeleteAppPassword: %v", err)
+	}
+	if u, _ := srv.User("alice"); len(u.AppPasswords) != 0 {
+		t.Errorf("app passwords after DeleteAppPassword = %v, want none", u.AppPasswords)
+	}
+}
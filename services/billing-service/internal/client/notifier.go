@@ -0,0 +1,29 @@
+// services/billing-service/internal/client/notifier.go
+package client
+
+import (
+	"context"
+	"log"
+)
+
+//
+// Notifier
+//
+
+// Notifier delivers billing related messages (reminders, warnings) to a user.
+type Notifier interface {
+	Notify(ctx context.Context, userID int64, subject, message string) error
+}
+
+type logNotifier struct{}
+
+// NewLogNotifier returns a Notifier that only writes messages to the service log.
+// It is used until the platform has a real delivery channel (e-mail, in-app).
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, userID int64, subject, message string) error {
+	log.Printf("Notification for user %d: %s: %s", userID, subject, message)
+	return nil
+}
@@ -0,0 +1,263 @@
+// services/billing-service/internal/client/ocs/client.go
+package ocs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config configures a Client. Zero durations and retry counts fall back to the defaults below.
+type Config struct {
+	BaseURL  string
+	User     string // The API user, usually a Nextcloud admin
+	Password string
+	// Timeout bounds a single attempt, including reading the response.
+	Timeout time.Duration
+	// MaxRetries is how often a GET, PUT or DELETE request is repeated after a network error or
+	// a 5xx response; POST requests only with RetryPost. A negative value disables retries.
+	MaxRetries int
+	// RetryDelay is the wait before the first retry; it doubles with every further one and is
+	// jittered so that clients do not retry in lockstep.
+	RetryDelay time.Duration
+	// LogRequests logs every request with its outcome. Credentials are never logged and
+	// secret form fields are redacted.
+	LogRequests bool
+}
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultMaxRetries = 3
+	defaultRetryDelay = 200 * time.Millisecond
+)
+
+// Client performs requests against the Nextcloud OCS API (v2, JSON).
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+func NewClient(cfg Config) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaultRetryDelay
+	}
+	return &Client{cfg: cfg, httpClient: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Option adjusts a single request.
+type Option func(*options)
+
+type options struct {
+	retryPost bool
+	noRetry   bool
+	user      string // Authenticates as this user instead of the API user if set
+	password  string
+}
+
+// RetryPost lets a failed POST be retried like GET, PUT and DELETE are. Only pass it if sending
+// the request twice is harmless, e.g. because the endpoint reports an existing resource with a
+// status the caller handles.
+func RetryPost() Option {
+	return func(o *options) { o.retryPost = true }
+}
+
+// NoRetry sends a request once, whatever its method, e.g. a GET that creates something.
+func NoRetry() Option {
+	return func(o *options) { o.noRetry = true }
+}
+
+// As authenticates the request as the given user instead of the API user, e.g. for the
+// endpoints that manage a user's own app passwords.
+func As(user, password string) Option {
+	return func(o *options) { o.user, o.password = user, password }
+}
+
+// Do performs a request as the API user, unless As is passed. `form` is sent form-urlencoded if set, the response
+// data is decoded into `data` if set. Failures of the OCS API are returned as *Error; network
+// errors and 5xx responses of idempotent requests are retried first.
+func (c *Client) Do(ctx context.Context, method, path string, form url.Values, data interface{}, opts ...Option) error {
+	o := options{user: c.cfg.User, password: c.cfg.Password}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err = c.attempt(ctx, o, method, path, form, data)
+		if c.cfg.LogRequests {
+			log.Printf("Nextcloud %s %s%s as %s: %s in %s", method, redactPath(path), redactForm(form), o.user,
+				outcome(err), time.Since(start).Round(time.Millisecond))
+		}
+		if err == nil || !o.retries(method) || !retryable(ctx, err) || attempt >= c.cfg.MaxRetries {
+			return err
+		}
+
+		delay := c.retryDelay(attempt)
+		log.Printf("Nextcloud %s %s failed (attempt %d), retrying in %s: %v", method, redactPath(path), attempt+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retries reports whether a request with this method may be sent again. A POST that failed
+// with a 5xx or a lost response may still have taken effect, so it is only repeated if the
+// caller allowed it.
+func (o options) retries(method string) bool {
+	if o.noRetry {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return o.retryPost
+	}
+	return false
+}
+
+func (c *Client) attempt(ctx context.Context, o options, method, path string, form url.Values, data interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.BaseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create nextcloud request: %w", err)
+	}
+	req.SetBasicAuth(o.user, o.password)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("OCS-APIRequest", "true")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute nextcloud request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The v2 API reports failures with a 4xx status, but still with an OCS body. The data is
+	// only decoded on success, error responses carry an empty list instead.
+	var ocsResponse struct {
+		Ocs struct {
+			Meta struct {
+				StatusCode int    `json:"statuscode"`
+				Message    string `json:"message"`
+			} `json:"meta"`
+			Data json.RawMessage `json:"data"`
+		} `json:"ocs"`
+	}
+	ocsErr := &Error{Method: method, Path: redactPath(path), HTTPStatus: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(&ocsResponse); err != nil || ocsResponse.Ocs.Meta.StatusCode == 0 {
+		if resp.StatusCode != http.StatusOK {
+			return ocsErr // Not an OCS response, e.g. from a proxy
+		}
+		return fmt.Errorf("failed to decode nextcloud JSON response: %w", err)
+	}
+
+	if ocsResponse.Ocs.Meta.StatusCode != StatusOK {
+		ocsErr.StatusCode = ocsResponse.Ocs.Meta.StatusCode
+		ocsErr.Message = ocsResponse.Ocs.Meta.Message
+		return ocsErr
+	}
+	if data != nil {
+		if err := json.Unmarshal(ocsResponse.Ocs.Data, data); err != nil {
+			return fmt.Errorf("failed to decode nextcloud response data: %w", err)
+		}
+	}
+	return nil
+}
+
+// retryable reports whether a failed attempt is worth repeating: server errors and network
+// errors are, unless the caller gave up.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var ocsErr *Error
+	if errors.As(err, &ocsErr) {
+		return ocsErr.temporary()
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// retryDelay is the exponential backoff before retry `attempt`+1, with "equal jitter": at
+// least half of it, plus a random share of the rest.
+func (c *Client) retryDelay(attempt int) time.Duration {
+	delay := c.cfg.RetryDelay << attempt
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func outcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return err.Error()
+}
+
+// secretFields are form and query fields whose values never appear in logs.
+var secretFields = []string{"password", "token", "secret"}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range secretFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValues(values url.Values) string {
+	redacted := url.Values{}
+	for key, vals := range values {
+		if isSecret(key) {
+			redacted[key] = []string{"REDACTED"}
+		} else {
+			redacted[key] = vals
+		}
+	}
+	return redacted.Encode()
+}
+
+// redactPath redacts the secret fields in the query string of path.
+func redactPath(path string) string {
+	p, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return p + "?REDACTED"
+	}
+	return p + "?" + redactValues(query)
+}
+
+func redactForm(form url.Values) string {
+	if len(form) == 0 {
+		return ""
+	}
+	return " [" + redactValues(form) + "]"
+}
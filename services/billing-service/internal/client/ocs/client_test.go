@@ -0,0 +1,270 @@
+// services/billing-service/internal/client/ocs/client_test.go
+package ocs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/client/ocs/ocstest"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, maxRetries int) (*Client, *ocstest.Server) {
+	t.Helper()
+	srv := ocstest.NewServer()
+	t.Cleanup(srv.Close)
+	c := NewClient(Config{
+		BaseURL:    srv.URL,
+		User:       ocstest.AdminUser,
+		Password:   ocstest.AdminPassword,
+		MaxRetries: maxRetries,
+		RetryDelay: time.Millisecond,
+	})
+	return c, srv
+}
+
+func groupForm(group string) url.Values {
+	return url.Values{"groupid": {group}}
+}
+
+func TestDoRetriesIdempotentRequests(t *testing.T) {
+	c, srv := newTestClient(t, 3)
+	srv.AddUser(ocstest.User{ID: "alice@example.com", QuotaBytes: ocstest.Unlimited})
+	srv.FailNext(http.StatusServiceUnavailable, http.StatusBadGateway)
+
+	var data struct {
+		Users []string `json:"users"`
+	}
+	if err := c.Do(context.Background(), http.MethodGet, "/ocs/v2.php/cloud/users", nil, &data); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if len(data.Users) != 1 || data.Users[0] != "alice@example.com" {
+		t.Errorf("users = %v, want [alice@example.com]", data.Users)
+	}
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestDoGivesUpAfterMaxRetries(t *testing.T) {
+	c, srv := newTestClient(t, 2)
+	srv.FailNext(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	err := c.Do(context.Background(), http.MethodGet, "/ocs/v2.php/cloud/users", nil, nil)
+	var ocsErr *Error
+	if !errors.As(err, &ocsErr) || ocsErr.HTTPStatus != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want an *Error with HTTP 503", err)
+	}
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestDoDoesNotRetryClientErrors(t *testing.T) {
+	c, srv := newTestClient(t, 3)
+
+	err := c.Do(context.Background(), http.MethodGet, "/ocs/v2.php/cloud/users/nobody", nil, nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestDoRetriesPostOnlyWhenAllowed(t *testing.T) {
+	c, srv := newTestClient(t, 3)
+
+	srv.FailNext(http.StatusServiceUnavailable)
+	err := c.Do(context.Background(), http.MethodPost, "/ocs/v2.php/cloud/groups", groupForm("pro"), nil)
+	if err == nil {
+		t.Fatal("POST succeeded, want the 503 without a retry")
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+
+	srv.FailNext(http.StatusServiceUnavailable)
+	if err := c.Do(context.Background(), http.MethodPost, "/ocs/v2.php/cloud/groups", groupForm("pro"), nil, RetryPost()); err != nil {
+		t.Fatalf("POST with RetryPost: %v", err)
+	}
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestDoStopsRetryingWhenContextEnds(t *testing.T) {
+	srv := ocstest.NewServer()
+	t.Cleanup(srv.Close)
+	c := NewClient(Config{BaseURL: srv.URL, User: ocstest.AdminUser, Password: ocstest.AdminPassword, MaxRetries: 5, RetryDelay: time.Hour})
+	srv.FailNext(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Do(ctx, http.MethodGet, "/ocs/v2.php/cloud/users", nil, nil); err == nil {
+		t.Fatal("Do succeeded, want the 503")
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestDoAsUser(t *testing.T) {
+	c, srv := newTestClient(t, 3)
+	srv.AddUser(ocstest.User{ID: "alice", Password: "login-password", QuotaBytes: ocstest.Unlimited})
+
+	var data struct {
+		AppPassword string `json:"apppassword"`
+	}
+	if err := c.Do(context.Background(), http.MethodGet, "/ocs/v2.php/core/getapppassword", nil, &data, As("alice", "login-password")); err != nil {
+		t.Fatalf("Do as alice: %v", err)
+	}
+	if data.AppPassword == "" {
+		t.Error("no app password returned")
+	}
+	// The provisioning API still authenticates as the API user, not as the last As user.
+	if err := c.Do(context.Background(), http.MethodGet, "/ocs/v2.php/cloud/users", nil, nil); err != nil {
+		t.Errorf("Do as the API user: %v", err)
+	}
+}
+
+func TestDoNoRetry(t *testing.T) {
+	c, srv := newTestClient(t, 3)
+	srv.FailNext(http.StatusServiceUnavailable)
+
+	if err := c.Do(context.Background(), http.MethodGet, "/ocs/v2.php/cloud/users", nil, nil, NoRetry()); err == nil {
+		t.Fatal("Do succeeded, want the 503 without a retry")
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestRetryDelayIsJittered(t *testing.T) {
+	c := NewClient(Config{RetryDelay: 100 * time.Millisecond})
+	for attempt := 0; attempt < 4; attempt++ {
+		full := 100 * time.Millisecond << attempt
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 200; i++ {
+			delay := c.retryDelay(attempt)
+			if delay < full/2 || delay > full {
+				t.Fatalf("retryDelay(%d) = %s, want between %s and %s", attempt, delay, full/2, full)
+			}
+			seen[delay] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("retryDelay(%d) always returned the same delay", attempt)
+		}
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	c, srv := newTestClient(t, -1)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		do     func() error
+		target error
+	}{
+		{
+			name:   "OCS not found",
+			do:     func() error { return c.Do(ctx, http.MethodGet, "/ocs/v2.php/cloud/users/nobody", nil, nil) },
+			target: ErrNotFound,
+		},
+		{
+			name: "HTTP not found without an OCS body",
+			do: func() error {
+				srv.FailNext(http.StatusNotFound)
+				return c.Do(ctx, http.MethodGet, "/ocs/v2.php/cloud/users", nil, nil)
+			},
+			target: ErrNotFound,
+		},
+		{
+			name: "wrong credentials",
+			do: func() error {
+				other := NewClient(Config{BaseURL: srv.URL, User: ocstest.AdminUser, Password: "wrong", MaxRetries: -1})
+				return other.Do(ctx, http.MethodGet, "/ocs/v2.php/cloud/users", nil, nil)
+			},
+			target: ErrUnauthorized,
+		},
+		{
+			name: "invalid input",
+			do: func() error {
+				return c.Do(ctx, http.MethodPost, "/ocs/v2.php/cloud/users", url.Values{"email": {"a@example.com"}}, nil)
+			},
+			target: ErrInvalidInput,
+		},
+	}
+	all := []error{ErrNotFound, ErrUnauthorized, ErrInvalidInput}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.do()
+			for _, target := range all {
+				if got, want := errors.Is(err, target), target == tt.target; got != want {
+					t.Errorf("errors.Is(%v, %v) = %t, want %t", err, target, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsStatus(t *testing.T) {
+	c, _ := newTestClient(t, -1)
+	ctx := context.Background()
+	if err := c.Do(ctx, http.MethodPost, "/ocs/v2.php/cloud/groups", groupForm("pro"), nil); err != nil {
+		t.Fatalf("creating group: %v", err)
+	}
+	err := c.Do(ctx, http.MethodPost, "/ocs/v2.php/cloud/groups", groupForm("pro"), nil)
+	if !IsStatus(err, 102) {
+		t.Errorf("IsStatus(%v, 102) = false, want true", err)
+	}
+	if IsStatus(errors.New("other"), 102) {
+		t.Error("IsStatus of a non-OCS error = true, want false")
+	}
+}
+
+func TestRequestLogRedactsSecrets(t *testing.T) {
+	srv := ocstest.NewServer()
+	t.Cleanup(srv.Close)
+	c := NewClient(Config{BaseURL: srv.URL, User: ocstest.AdminUser, Password: ocstest.AdminPassword, MaxRetries: -1, LogRequests: true})
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	form := url.Values{"userid": {"bob"}, "email": {"bob@example.com"}, "password": {"hunter22"}}
+	_ = c.Do(context.Background(), http.MethodPost, "/ocs/v2.php/cloud/users?token=t0ps3cret", form, nil)
+	_ = c.Do(context.Background(), http.MethodGet, "/ocs/v2.php/core/getapppassword", nil, nil, As("bob", "l0gin-pw"))
+
+	out := buf.String()
+	for _, secret := range []string{"hunter22", "t0ps3cret", "l0gin-pw", ocstest.AdminPassword} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log contains %q: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "userid=bob") {
+		t.Errorf("log lacks the non-secret form fields: %s", out)
+	}
+}
+
+func TestRedactPath(t *testing.T) {
+	tests := map[string]string{
+		"/ocs/v2.php/cloud/users":                   "/ocs/v2.php/cloud/users",
+		"/ocs/v2.php/cloud/users?offset=0&limit=10": "/ocs/v2.php/cloud/users?limit=10&offset=0",
+		"/ocs/v2.php/x?appPassword=abc&user=bob":    "/ocs/v2.php/x?appPassword=REDACTED&user=bob",
+		"/ocs/v2.php/x?API_SECRET=abc":              "/ocs/v2.php/x?API_SECRET=REDACTED",
+		"/ocs/v2.php/x?%zz":                         "/ocs/v2.php/x?REDACTED",
+	}
+	for path, want := range tests {
+		if got := redactPath(path); got != want {
+			t.Errorf("redactPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
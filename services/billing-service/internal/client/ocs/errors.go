@@ -0,0 +1,69 @@
+// services/billing-service/internal/client/ocs/errors.go
+package ocs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// OCS status codes with the same meaning on every endpoint. Other codes are endpoint specific,
+// e.g. 102 means "user exists" when creating a user; check those with IsStatus.
+const (
+	StatusOK           = 200
+	StatusInvalidInput = 101 // The provisioning API's "invalid input data"
+	StatusNotFound     = 404
+	StatusServerError  = 996
+	StatusUnauthorized = 997
+	StatusNotFoundV1   = 998 // What v1 endpoints and some v2 controllers return for 404
+)
+
+// Errors an *Error matches with errors.Is, independent of the endpoint.
+var (
+	ErrNotFound     = errors.New("nextcloud: not found")
+	ErrInvalidInput = errors.New("nextcloud: invalid input")
+	ErrUnauthorized = errors.New("nextcloud: unauthorized")
+)
+
+// Error is a failed OCS request, either reported by the OCS API or, if StatusCode is 0, by the
+// HTTP server in front of it.
+type Error struct {
+	Method     string
+	Path       string // Without the query string, which may hold secrets
+	HTTPStatus int
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("nextcloud %s %s: HTTP %d", e.Method, e.Path, e.HTTPStatus)
+	}
+	return fmt.Sprintf("nextcloud %s %s: OCS status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == StatusNotFound || e.StatusCode == StatusNotFoundV1 ||
+			(e.StatusCode == 0 && e.HTTPStatus == http.StatusNotFound)
+	case ErrUnauthorized:
+		return e.StatusCode == StatusUnauthorized ||
+			e.HTTPStatus == http.StatusUnauthorized || e.HTTPStatus == http.StatusForbidden
+	case ErrInvalidInput:
+		return e.StatusCode == StatusInvalidInput || e.StatusCode == http.StatusBadRequest
+	}
+	return false
+}
+
+// temporary reports whether the request may succeed if it is sent again.
+func (e *Error) temporary() bool {
+	return e.HTTPStatus >= 500 || e.StatusCode == StatusServerError
+}
+
+// IsStatus reports whether err is an OCS error with the given, usually endpoint specific,
+// status code.
+func IsStatus(err error, statusCode int) bool {
+	var ocsErr *Error
+	return errors.As(err, &ocsErr) && ocsErr.StatusCode == statusCode
+}
@@ -0,0 +1,427 @@
+// services/billing-service/internal/client/ocs/ocstest/server.go
+
+// Package ocstest provides an in-memory fake of the Nextcloud OCS endpoints billing-service
+// uses, for tests of the Nextcloud client and the services built on it.
+package ocstest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Credentials of the admin account the fake accepts for the provisioning API.
+const (
+	AdminUser     = "admin"
+	AdminPassword = "admin-password"
+)
+
+// Unlimited is the quota of users without a limit, as Nextcloud reports it.
+const Unlimited = -3
+
+// User is the state the fake keeps of an account.
+type User struct {
+	ID           string
+	Email        string
+	Password     string // Login password, empty if the user has not set one yet
+	Disabled     bool
+	QuotaBytes   int64 // Unlimited if the user has no limit
+	UsedBytes    int64
+	Groups       []string
+	AppPasswords []string
+}
+
+// Server is a fake Nextcloud. It is safe for concurrent use; the zero value is not usable,
+// create one with NewServer and Close it when done.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	users    map[string]*User
+	order    []string // User IDs in creation order, for paging
+	groups   map[string]bool
+	failures []int // HTTP statuses the next requests fail with
+	requests int
+}
+
+func NewServer() *Server {
+	s := &Server{users: map[string]*User{}, groups: map[string]bool{"admin": true}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ocs/v2.php/cloud/users", s.admin(s.listUsers))
+	mux.HandleFunc("POST /ocs/v2.php/cloud/users", s.admin(s.createUser))
+	mux.HandleFunc("GET /ocs/v2.php/cloud/users/{id}", s.admin(s.withUser(s.getUser)))
+	mux.HandleFunc("PUT /ocs/v2.php/cloud/users/{id}", s.admin(s.withUser(s.editUser)))
+	mux.HandleFunc("DELETE /ocs/v2.php/cloud/users/{id}", s.admin(s.withUser(s.deleteUser)))
+	mux.HandleFunc("PUT /ocs/v2.php/cloud/users/{id}/enable", s.admin(s.withUser(s.setDisabled(false))))
+	mux.HandleFunc("PUT /ocs/v2.php/cloud/users/{id}/disable", s.admin(s.withUser(s.setDisabled(true))))
+	mux.HandleFunc("GET /ocs/v2.php/cloud/users/{id}/groups", s.admin(s.withUser(s.getUserGroups)))
+	mux.HandleFunc("POST /ocs/v2.php/cloud/users/{id}/groups", s.admin(s.withUser(s.addToGroup)))
+	mux.HandleFunc("DELETE /ocs/v2.php/cloud/users/{id}/groups", s.admin(s.withUser(s.removeFromGroup)))
+	mux.HandleFunc("POST /ocs/v2.php/cloud/groups", s.admin(s.createGroup))
+	mux.HandleFunc("GET /ocs/v2.php/core/getapppassword", s.getAppPassword)
+	mux.HandleFunc("DELETE /ocs/v2.php/core/apppassword", s.deleteAppPassword)
+
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// AddUser creates an account directly, e.g. one that exists before the test runs.
+func (s *Server) AddUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[u.ID]; !ok {
+		s.order = append(s.order, u.ID)
+	}
+	for _, group := range u.Groups {
+		s.groups[group] = true
+	}
+	s.users[u.ID] = &u
+}
+
+// User returns a copy of an account, false if it does not exist.
+func (s *Server) User(id string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, false
+	}
+	c := *u
+	c.Groups = slices.Clone(u.Groups)
+	c.AppPasswords = slices.Clone(u.AppPasswords)
+	return c, true
+}
+
+// SetUsedBytes sets how much storage an account uses.
+func (s *Server) SetUsedBytes(id string, used int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[id]; ok {
+		u.UsedBytes = used
+	}
+}
+
+// FailNext makes the next requests fail with the given HTTP statuses, one each, without an
+// OCS body, like a proxy in front of an unavailable Nextcloud would.
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// Requests returns how many requests the server received, including failed ones.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		var status int
+		if len(s.failures) > 0 {
+			status, s.failures = s.failures[0], s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		if status != 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		// net/http only parses the form of POST, PUT and PATCH, but the group endpoints take
+		// one on DELETE too.
+		if r.Method == http.MethodDelete && r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+			body, _ := io.ReadAll(r.Body)
+			form, _ := url.ParseQuery(string(body))
+			r.PostForm, r.Form = form, form
+		}
+		if r.Header.Get("OCS-APIRequest") != "true" {
+			writeOCS(w, http.StatusUnauthorized, 997, "CSRF check failed", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeOCS writes a v2 response. Data is an empty list on failure, like Nextcloud sends.
+func writeOCS(w http.ResponseWriter, httpStatus, statusCode int, message string, data interface{}) {
+	status := "ok"
+	if statusCode != 200 {
+		status = "failure"
+		data = []interface{}{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ocs": map[string]interface{}{
+			"meta": map[string]interface{}{"status": status, "statuscode": statusCode, "message": message},
+			"data": data,
+		},
+	})
+}
+
+func writeOK(w http.ResponseWriter, data interface{}) {
+	writeOCS(w, http.StatusOK, 200, "OK", data)
+}
+
+// fail answers with an endpoint specific status code, which v2 sends as HTTP 400.
+func fail(w http.ResponseWriter, statusCode int, message string) {
+	writeOCS(w, http.StatusBadRequest, statusCode, message, nil)
+}
+
+func notFound(w http.ResponseWriter, message string) {
+	writeOCS(w, http.StatusNotFound, 404, message, nil)
+}
+
+type userHandler func(w http.ResponseWriter, r *http.Request, u *User)
+
+// admin guards the provisioning API, which only admins may use. Handlers run with s.mu held.
+func (s *Server) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != AdminUser || password != AdminPassword {
+			writeOCS(w, http.StatusUnauthorized, 997, "Current user is not logged in", nil)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		next(w, r)
+	}
+}
+
+func (s *Server) withUser(next userHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, ok := s.users[r.PathValue("id")]
+		if !ok {
+			notFound(w, "User does not exist")
+			return
+		}
+		next(w, r, u)
+	}
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = len(s.order)
+	}
+	ids := []string{}
+	if offset < len(s.order) {
+		ids = s.order[offset:min(offset+limit, len(s.order))]
+	}
+	writeOK(w, map[string]interface{}{"users": ids})
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("userid")
+	if id == "" {
+		fail(w, 101, "Invalid input data")
+		return
+	}
+	if _, ok := s.users[id]; ok {
+		fail(w, 102, "User already exists")
+		return
+	}
+	// Without a password Nextcloud emails a link to set one, so it needs the address.
+	if r.FormValue("password") == "" && r.FormValue("email") == "" {
+		fail(w, 108, "To send a password link to the user an email address is required.")
+		return
+	}
+	quota, ok := parseQuota(r.FormValue("quota"))
+	if !ok {
+		fail(w, 101, "Invalid quota value")
+		return
+	}
+	u := &User{ID: id, Email: r.FormValue("email"), Password: r.FormValue("password"), QuotaBytes: quota}
+	for _, group := range r.Form["groups[]"] {
+		if !s.groups[group] {
+			fail(w, 104, "group "+group+" does not exist")
+			return
+		}
+		u.Groups = append(u.Groups, group)
+	}
+	s.users[id] = u
+	s.order = append(s.order, id)
+	writeOK(w, map[string]interface{}{"id": id})
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request, u *User) {
+	quota := map[string]interface{}{"used": u.UsedBytes}
+	if u.QuotaBytes == Unlimited {
+		quota["quota"] = "none"
+		quota["free"] = int64(1) << 40 // What the disk has left
+	} else {
+		quota["quota"] = u.QuotaBytes
+		quota["free"] = max(u.QuotaBytes-u.UsedBytes, 0)
+	}
+	writeOK(w, map[string]interface{}{
+		"id":      u.ID,
+		"email":   u.Email,
+		"enabled": !u.Disabled,
+		"quota":   quota,
+		"groups":  nonNil(u.Groups),
+	})
+}
+
+func (s *Server) editUser(w http.ResponseWriter, r *http.Request, u *User) {
+	switch r.FormValue("key") {
+	case "quota":
+		quota, ok := parseQuota(r.FormValue("value"))
+		if !ok {
+			fail(w, 102, "Invalid quota value")
+			return
+		}
+		u.QuotaBytes = quota
+	case "email":
+		u.Email = r.FormValue("value")
+	default:
+		fail(w, 103, "Unknown key")
+		return
+	}
+	writeOK(w, []interface{}{})
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request, u *User) {
+	delete(s.users, u.ID)
+	s.order = slices.DeleteFunc(s.order, func(id string) bool { return id == u.ID })
+	writeOK(w, []interface{}{})
+}
+
+func (s *Server) setDisabled(disabled bool) userHandler {
+	return func(w http.ResponseWriter, r *http.Request, u *User) {
+		u.Disabled = disabled
+		writeOK(w, []interface{}{})
+	}
+}
+
+func (s *Server) getUserGroups(w http.ResponseWriter, r *http.Request, u *User) {
+	writeOK(w, map[string]interface{}{"groups": nonNil(u.Groups)})
+}
+
+func (s *Server) addToGroup(w http.ResponseWriter, r *http.Request, u *User) {
+	group := r.FormValue("groupid")
+	if group == "" {
+		fail(w, 101, "Group not specified")
+		return
+	}
+	if !s.groups[group] {
+		fail(w, 102, "Group does not exist")
+		return
+	}
+	if !slices.Contains(u.Groups, group) {
+		u.Groups = append(u.Groups, group)
+	}
+	writeOK(w, []interface{}{})
+}
+
+func (s *Server) removeFromGroup(w http.ResponseWriter, r *http.Request, u *User) {
+	group := r.FormValue("groupid")
+	if group == "" {
+		fail(w, 101, "Group not specified")
+		return
+	}
+	if !s.groups[group] {
+		fail(w, 102, "Group does not exist")
+		return
+	}
+	u.Groups = slices.DeleteFunc(u.Groups, func(g string) bool { return g == group })
+	writeOK(w, []interface{}{})
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request) {
+	group := r.FormValue("groupid")
+	if group == "" {
+		fail(w, 101, "Invalid group name")
+		return
+	}
+	if s.groups[group] {
+		fail(w, 102, "Group already exists")
+		return
+	}
+	s.groups[group] = true
+	writeOK(w, []interface{}{})
+}
+
+// userAuth authenticates an enabled user with their password or one of their app passwords.
+func (s *Server) userAuth(r *http.Request) (*User, bool) {
+	id, password, ok := r.BasicAuth()
+	if !ok || password == "" {
+		return nil, false
+	}
+	u, exists := s.users[id]
+	if !exists || u.Disabled {
+		return nil, false
+	}
+	return u, u.Password == password || slices.Contains(u.AppPasswords, password)
+}
+
+func (s *Server) getAppPassword(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.userAuth(r)
+	if !ok {
+		writeOCS(w, http.StatusUnauthorized, 997, "Current user is not logged in", nil)
+		return
+	}
+	_, password, _ := r.BasicAuth()
+	if slices.Contains(u.AppPasswords, password) {
+		// Nextcloud refuses to derive an app password from another one.
+		writeOCS(w, http.StatusForbidden, 403, "You cannot request an new apppassword with an apppassword", nil)
+		return
+	}
+	appPassword := "app-" + strconv.Itoa(s.requests) + "-" + strings.ReplaceAll(u.ID, "@", "-")
+	u.AppPasswords = append(u.AppPasswords, appPassword)
+	writeOK(w, map[string]interface{}{"apppassword": appPassword})
+}
+
+func (s *Server) deleteAppPassword(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.userAuth(r)
+	_, password, _ := r.BasicAuth()
+	if !ok || !slices.Contains(u.AppPasswords, password) {
+		writeOCS(w, http.StatusForbidden, 403, "Only app passwords can be deleted", nil)
+		return
+	}
+	u.AppPasswords = slices.DeleteFunc(u.AppPasswords, func(p string) bool { return p == password })
+	writeOK(w, []interface{}{})
+}
+
+// parseQuota reads a quota like the client sends it ("5 GB"), a byte count or "none".
+func parseQuota(value string) (int64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "none" {
+		return Unlimited, true
+	}
+	units := map[string]int64{"B": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}
+	number, unit, _ := strings.Cut(value, " ")
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	if unit == "" {
+		return n, true
+	}
+	factor, ok := units[strings.ToUpper(unit)]
+	if !ok {
+		return 0, false
+	}
+	return n * factor, true
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
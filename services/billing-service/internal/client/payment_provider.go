@@ -0,0 +1,154 @@
+// services/billing-service/internal/client/payment_provider.go
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+//
+// Payment Provider
+//
+
+// Payment webhook event types.
+const (
+	PaymentEventSucceeded = "payment.succeeded"
+	PaymentEventFailed    = "payment.failed"
+)
+
+// CheckoutSession is a payment the user completes on the provider's hosted page.
+type CheckoutSession struct {
+	ID  string
+	URL string
+}
+
+// PaymentEvent is a payment status update the provider sends to our webhook.
+type PaymentEvent struct {
+	Type        string `json:"type"`
+	PaymentID   string `json:"payment_id"`
+	Reference   string `json:"reference"` // The reference passed to CreateCheckout
+	AmountMinor int64  `json:"amount_minor"`
+	Currency    string `json:"currency"`
+}
+
+// ErrInvalidSignature is returned for webhook calls that were not signed by the provider.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+type PaymentProvider interface {
+	// CreateCheckout starts a payment of amountMinor in currency. The reference comes back
+	// in the webhook events of the payment.
+	CreateCheckout(ctx context.Context, reference string, amountMinor int64, currency string) (*CheckoutSession, error)
+	// ParseWebhook verifies the signature of a webhook call and decodes its event.
+	ParseWebhook(payload []byte, signature string) (*PaymentEvent, error)
+	// Refund pays back amountMinor of a payment and returns the provider's refund ID.
+	// Retrying with the same reference does not refund twice.
+	Refund(ctx context.Context, paymentID string, amountMinor int64, currency, reference string) (string, error)
+}
+
+type hostedCheckoutProvider struct {
+	checkoutURL   string
+	apiURL        string
+	webhookSecret string
+	httpClient    *http.Client
+}
+
+// NewHostedCheckoutProvider returns a PaymentProvider for a hosted checkout page. Checkout
+// parameters are passed in the URL and signed, webhooks and API requests carry a hex
+// HMAC-SHA256 of their body, all with the shared webhookSecret.
+func NewHostedCheckoutProvider(checkoutURL, apiURL, webhookSecret string) PaymentProvider {
+	return &hostedCheckoutProvider{
+		checkoutURL:   checkoutURL,
+		apiURL:        apiURL,
+		webhookSecret: webhookSecret,
+		httpClient:    &http.Client{},
+	}
+}
+
+func (p *hostedCheckoutProvider) CreateCheckout(ctx context.Context, reference string, amountMinor int64, currency string) (*CheckoutSession, error) {
+	if p.checkoutURL == "" || p.webhookSecret == "" {
+		return nil, fmt.Errorf("payment provider is not configured")
+	}
+	endpoint, err := url.Parse(p.checkoutURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkout url: %w", err)
+	}
+
+	amount := strconv.FormatInt(amountMinor, 10)
+	query := endpoint.Query()
+	query.Set("reference", reference)
+	query.Set("amount_minor", amount)
+	query.Set("currency", currency)
+	query.Set("signature", p.sign([]byte(reference+"|"+amount+"|"+currency)))
+	endpoint.RawQuery = query.Encode()
+
+	return &CheckoutSession{ID: reference, URL: endpoint.String()}, nil
+}
+
+func (p *hostedCheckoutProvider) ParseWebhook(payload []byte, signature string) (*PaymentEvent, error) {
+	if p.webhookSecret == "" || !hmac.Equal([]byte(p.sign(payload)), []byte(signature)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event PaymentEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode payment event: %w", err)
+	}
+	return &event, nil
+}
+
+type refundRequest struct {
+	PaymentID   string `json:"payment_id"`
+	AmountMinor int64  `json:"amount_minor"`
+	Currency    string `json:"currency"`
+	Reference   string `json:"reference"`
+}
+
+func (p *hostedCheckoutProvider) Refund(ctx context.Context, paymentID string, amountMinor int64, currency, reference string) (string, error) {
+	if p.apiURL == "" || p.webhookSecret == "" {
+		return "", fmt.Errorf("payment provider is not configured")
+	}
+	body, err := json.Marshal(refundRequest{PaymentID: paymentID, AmountMinor: amountMinor, Currency: currency, Reference: reference})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode refund request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.apiURL+"/refunds", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create refund request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature", p.sign(body))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute refund request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("payment provider returned status %d for refund", resp.StatusCode)
+	}
+
+	var result struct {
+		RefundID string `json:"refund_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode refund response: %w", err)
+	}
+	return result.RefundID, nil
+}
+
+func (p *hostedCheckoutProvider) sign(data []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.webhookSecret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
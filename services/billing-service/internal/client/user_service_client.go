@@ -15,10 +15,15 @@ import (
 type UserDetails struct {
 	ID    int64  `json:"id"`
 	Email string `json:"email"`
+	// EmailVerified reports whether the user confirmed they own Email.
+	EmailVerified bool `json:"email_verified"`
 }
 
 type UserServiceClient interface {
 	GetUserDetails(ctx context.Context, userID int64) (*UserDetails, error)
+	// ListUserIDs returns up to limit user ids greater than afterID in ascending order, for
+	// paging through all users.
+	ListUserIDs(ctx context.Context, afterID int64, limit int) ([]int64, error)
 }
 
 type userServiceClient struct {
@@ -59,3 +64,31 @@ func (c *userServiceClient) GetUserDetails(ctx context.Context, userID int64) (*
 
 	return &details, nil
 }
+
+func (c *userServiceClient) ListUserIDs(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	endpoint := fmt.Sprintf("%s/internal/v1/user-ids?afterId=%d&limit=%d", c.baseURL, afterID, limit)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user-service request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute user-service request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user-service returned non-200 status: %d", resp.StatusCode)
+	}
+
+	var page struct {
+		UserIDs []int64 `json:"user_ids"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode user ids response: %w", err)
+	}
+
+	return page.UserIDs, nil
+}
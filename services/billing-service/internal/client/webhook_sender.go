@@ -0,0 +1,94 @@
+// services/billing-service/internal/client/webhook_sender.go
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//
+// Webhook Sender
+//
+
+// maxWebhookResponseBody is how much of an endpoint's response is kept for the delivery log.
+const maxWebhookResponseBody = 1024
+
+// WebhookResponse is what an endpoint answered to a delivery.
+type WebhookResponse struct {
+	Status int
+	Body   string // Truncated to maxWebhookResponseBody bytes
+}
+
+// WebhookSender POSTs events to webhook endpoints.
+type WebhookSender interface {
+	// Send posts body to url, signed with secret. Any response is returned, whatever its status;
+	// the error is only set if none was received.
+	Send(ctx context.Context, url, secret, eventID, eventType string, body []byte) (*WebhookResponse, error)
+}
+
+type httpWebhookSender struct {
+	httpClient *http.Client
+}
+
+// NewHTTPWebhookSender returns a WebhookSender that gives every request `timeout` to complete.
+// Redirects are not followed, so an endpoint that moved fails until it is updated.
+//
+// Requests carry the headers
+//
+//	X-Webhook-Id:        the event ID, the same for every redelivery of the event
+//	X-Webhook-Event:     the event type
+//	X-Webhook-Timestamp: Unix seconds at which the request was signed
+//	X-Webhook-Signature: "v1=" and the hex HMAC-SHA256 of "<timestamp>.<body>" with the secret
+//
+// Receivers should compare the signature in constant time and reject old timestamps.
+func NewHTTPWebhookSender(timeout time.Duration) WebhookSender {
+	return &httpWebhookSender{
+		httpClient: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (s *httpWebhookSender) Send(ctx context.Context, url, secret, eventID, eventType string, body []byte) (*WebhookResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "jcloud-billing-webhooks/1")
+	req.Header.Set("X-Webhook-Id", eventID)
+	req.Header.Set("X-Webhook-Event", eventType)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "v1="+SignWebhook(secret, timestamp, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// A body that cannot be read completely does not change the outcome of the delivery.
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	return &WebhookResponse{Status: resp.StatusCode, Body: string(respBody)}, nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 that webhook requests carry in X-Webhook-Signature.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -4,6 +4,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
@@ -14,6 +15,9 @@ type Config struct {
 	Postgres  PostgresConfig
 	JWT       JWTConfig
 	Nextcloud NextcloudConfig
+	Billing   BillingConfig
+	Payment   PaymentConfig
+	Webhook   WebhookConfig
 }
 
 type PostgresConfig struct {
@@ -32,6 +36,81 @@ type NextcloudConfig struct {
 	ApiURL      string `env:"NC_API_URL" env-required:"true"`
 	ApiUser     string `env:"NC_API_USER" env-required:"true"`
 	ApiPassword string `env:"NC_API_PASSWORD" env-required:"true"`
+	// Timeout bounds a single OCS request.
+	Timeout time.Duration `env:"NC_TIMEOUT" env-default:"10s"`
+	// MaxRetries is how often an OCS request is repeated after a network error or a 5xx response;
+	// -1 disables retries.
+	MaxRetries int `env:"NC_MAX_RETRIES" env-default:"3"`
+	// RetryDelay is the wait before the first retry of an OCS request, doubled and jittered
+	// for every further one.
+	RetryDelay time.Duration `env:"NC_RETRY_DELAY" env-default:"200ms"`
+	// LogRequests logs every OCS request, with secrets redacted.
+	LogRequests bool `env:"NC_LOG_REQUESTS" env-default:"false"`
+	// SyncInterval is how often the outbox of pending Nextcloud syncs is delivered.
+	SyncInterval time.Duration `env:"NC_SYNC_INTERVAL" env-default:"15s"`
+	// SyncRetryDelay is the wait after the first failed attempt of a sync; it doubles with
+	// every further failure.
+	SyncRetryDelay time.Duration `env:"NC_SYNC_RETRY_DELAY" env-default:"30s"`
+	// SyncMaxAttempts is how often a sync is tried before it is dead-lettered.
+	SyncMaxAttempts int `env:"NC_SYNC_MAX_ATTEMPTS" env-default:"10"`
+	// ReconcileInterval is how often Nextcloud quotas are compared with what users are entitled to.
+	ReconcileInterval time.Duration `env:"NC_RECONCILE_INTERVAL" env-default:"24h"`
+	// ReconcileFix corrects drifted quotas found by the periodic reconciliation; otherwise
+	// they are only reported.
+	ReconcileFix bool `env:"NC_RECONCILE_FIX" env-default:"false"`
+	// UsageCacheTTL is how long storage usage read from Nextcloud is served before it is fetched again.
+	UsageCacheTTL time.Duration `env:"NC_USAGE_CACHE_TTL" env-default:"5m"`
+	// UsageInterval is how often the usage of every subscriber is refreshed to send quota warnings.
+	UsageInterval time.Duration `env:"NC_USAGE_INTERVAL" env-default:"1h"`
+}
+
+type BillingConfig struct {
+	// RenewalInterval is how often subscriptions with an ended period are processed.
+	RenewalInterval time.Duration `env:"RENEWAL_INTERVAL" env-default:"1h"`
+	// DefaultCurrency is charged when a user's locale currency is not offered. Every paid plan
+	// must have a price in it.
+	DefaultCurrency string `env:"BILLING_DEFAULT_CURRENCY" env-default:"USD"`
+	// SellerCountry is where the company is registered for tax (ISO 3166-1 alpha-2).
+	// Customers without a billing profile are taxed as consumers in this country.
+	SellerCountry string `env:"BILLING_SELLER_COUNTRY" env-default:"DE"`
+	// OverLimitPolicy is what happens when a plan change leaves a user with more data, in
+	// Nextcloud and videos together, than the new plan's storage: READ_ONLY allows it and pauses
+	// their uploads, BLOCK_DOWNGRADE refuses it. There is no policy that hides the oldest
+	// content: video-service cannot hide a video, only store it, and Nextcloud files can only
+	// be made unreachable by moving or deleting them, which billing must not do to user data.
+	OverLimitPolicy string `env:"BILLING_OVER_LIMIT_POLICY" env-default:"READ_ONLY"`
+	// OverLimitGrace is how long over-limit users have to free up space or upgrade before
+	// support follows up.
+	OverLimitGrace time.Duration `env:"BILLING_OVER_LIMIT_GRACE" env-default:"336h"`
+	// OverLimitInterval is how often grace period reminders and expiries are processed.
+	OverLimitInterval time.Duration `env:"BILLING_OVER_LIMIT_INTERVAL" env-default:"1h"`
+	// RepairInterval is how often users without a subscription are looked for and put on the
+	// default plan.
+	RepairInterval time.Duration `env:"BILLING_REPAIR_INTERVAL" env-default:"1h"`
+	// IdempotencyTTL is how long the response to an internal write with an Idempotency-Key is
+	// kept for retries.
+	IdempotencyTTL time.Duration `env:"BILLING_IDEMPOTENCY_TTL" env-default:"24h"`
+}
+
+// PaymentConfig connects the payment provider used for balance top-ups and refunds.
+// Both are unavailable while it is not configured.
+type PaymentConfig struct {
+	CheckoutURL   string `env:"PAYMENT_CHECKOUT_URL"`
+	ApiURL        string `env:"PAYMENT_API_URL"` // Used for refunds
+	WebhookSecret string `env:"PAYMENT_WEBHOOK_SECRET"`
+}
+
+// WebhookConfig controls the delivery of outgoing webhooks to the endpoints admins register.
+type WebhookConfig struct {
+	// Timeout bounds a single delivery request.
+	Timeout time.Duration `env:"WEBHOOK_TIMEOUT" env-default:"10s"`
+	// Interval is how often due deliveries are sent.
+	Interval time.Duration `env:"WEBHOOK_INTERVAL" env-default:"10s"`
+	// RetryDelay is the wait after the first failed attempt of a delivery; it doubles with
+	// every further failure.
+	RetryDelay time.Duration `env:"WEBHOOK_RETRY_DELAY" env-default:"1m"`
+	// MaxAttempts is how often a delivery is tried before it is dead-lettered.
+	MaxAttempts int `env:"WEBHOOK_MAX_ATTEMPTS" env-default:"10"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,52 @@
+// internal/domain/addon.go
+package domain
+
+import (
+	"jcloud-project/libs/go-common/entitlements"
+	"time"
+)
+
+// AddOn is a product bought on top of a plan, e.g. "+100 GB storage".
+// Its PermissionDeltas are added to the permissions of whatever plan the user is on.
+type AddOn struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Prices is the monthly price of one unit, one entry per currency it can be bought in.
+	Prices []Money `json:"prices"`
+	// PermissionDeltas is what one unit adds, e.g. {"storage_quota_gb": 100}. Deltas are
+	// fixed once the add-on is created.
+	PermissionDeltas entitlements.Deltas `json:"permission_deltas"`
+	IsActive         bool                `json:"is_active"` // Inactive add-ons cannot be bought, existing ones keep working
+	CreatedAt        time.Time           `json:"created_at"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+}
+
+// PriceIn returns the monthly price of one unit in currency, ok is false if it is not sold in it.
+func (a *AddOn) PriceIn(currency string) (Money, bool) {
+	for _, price := range a.Prices {
+		if price.Currency == currency {
+			return price, true
+		}
+	}
+	return Money{}, false
+}
+
+// UserAddOn is an add-on a user has bought. Buying the same add-on again stacks its Quantity.
+type UserAddOn struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	AddOn     AddOn     `json:"add_on"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MergePermissions returns the plan's permissions with the deltas of the add-ons added on top.
+func MergePermissions(base entitlements.Entitlements, addOns []UserAddOn) entitlements.Entitlements {
+	merged := base
+	for _, ua := range addOns {
+		merged = merged.Add(ua.AddOn.PermissionDeltas, ua.Quantity)
+	}
+	return merged
+}
@@ -1,34 +1,223 @@
 // internal/domain/billing.go
 package domain
 
-import "time"
+import (
+	"jcloud-project/libs/go-common/entitlements"
+	"time"
+)
 
 // SubscriptionPlan is the template for a subscription (e.g., "Free", "Pro")
 type SubscriptionPlan struct {
-	ID    int64   `json:"id"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"` // Monthly price
-	// Permissions holds all features and limits for this plan.
-	// Using map[string]interface{} for flexibility, stored as JSONB in Postgres.
-	Permissions map[string]interface{} `json:"permissions"`
-	IsActive    bool                   `json:"is_active"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Prices is the monthly price list, one entry per currency the plan can be bought in.
+	// An empty list means the plan is free.
+	Prices []Money `json:"prices"`
+	// MeteredPrices bills usage beyond the included amounts at the end of each period.
+	MeteredPrices []MeteredPrice `json:"metered_prices"`
+	// Permissions holds all features and limits for this plan, stored as JSONB in Postgres.
+	// Prices, MeteredPrices and Permissions always mirror the plan's current version.
+	Permissions entitlements.Entitlements `json:"permissions"`
+	// TrialDays is the length of the free trial offered for this plan, 0 means no trial.
+	TrialDays int `json:"trial_days"`
+	// SortOrder defines the position of the plan in public plan listings.
+	SortOrder int `json:"sort_order"`
+	// CurrentVersionID is the version new subscriptions are created on.
+	CurrentVersionID int64     `json:"current_version_id"`
+	IsActive         bool      `json:"is_active"` // Archived plans are inactive and cannot be subscribed to
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// IsFree reports whether the plan costs nothing in every currency.
+func (p *SubscriptionPlan) IsFree() bool {
+	for _, price := range p.Prices {
+		if !price.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// PriceIn returns the monthly price of the plan in currency. Free plans cost zero in any
+// currency; for paid plans ok is false when the plan has no price in that currency.
+func (p *SubscriptionPlan) PriceIn(currency string) (price Money, ok bool) {
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			return price, true
+		}
+	}
+	return NewMoney(0, currency), p.IsFree()
+}
+
+// PlanVersion is an immutable snapshot of a plan's price and permissions.
+// Subscriptions are pinned to a version, so editing a plan never affects existing subscribers.
+type PlanVersion struct {
+	ID            int64                     `json:"id"`
+	PlanID        int64                     `json:"plan_id"`
+	Version       int                       `json:"version"`
+	Prices        []Money                   `json:"prices"`
+	MeteredPrices []MeteredPrice            `json:"metered_prices"`
+	Permissions   entitlements.Entitlements `json:"permissions"`
+	CreatedAt     time.Time                 `json:"created_at"`
+}
+
+// PlanVersionReport is a PlanVersion with the number of subscriptions pinned to it.
+type PlanVersionReport struct {
+	PlanVersion
+	Subscribers int `json:"subscribers"`
+}
+
+// PlanVersionMigration moves every subscription of one plan version to another at EffectiveAt.
+type PlanVersionMigration struct {
+	ID            int64      `json:"id"`
+	PlanID        int64      `json:"plan_id"`
+	FromVersionID int64      `json:"from_version_id"`
+	ToVersionID   int64      `json:"to_version_id"`
+	EffectiveAt   time.Time  `json:"effective_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Subscription statuses.
+const (
+	SubscriptionStatusActive   = "ACTIVE"
+	SubscriptionStatusTrialing = "TRIALING"
+	SubscriptionStatusCanceled = "CANCELED"
+	SubscriptionStatusPastDue  = "PAST_DUE"
+)
+
 // UserSubscription is an instance of a user subscribed to a specific plan.
 type UserSubscription struct {
-	ID       int64     `json:"id"`
-	UserID   int64     `json:"user_id"`
-	PlanID   int64     `json:"plan_id"`
-	Status   string    `json:"status"` // e.g., "ACTIVE", "CANCELED", "PAST_DUE"
-	StartsAt time.Time `json:"starts_at"`
-	EndsAt   time.Time `json:"ends_at"`
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+	PlanID int64 `json:"plan_id"`
+	// PlanVersionID pins the price and permissions the subscriber pays for and gets.
+	PlanVersionID int64     `json:"plan_version_id"`
+	Status        string    `json:"status"` // e.g., "ACTIVE", "CANCELED", "PAST_DUE"
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	// Currency is the ISO 4217 currency the subscription is billed in.
+	Currency string `json:"currency"`
+	// PendingPlanID is the plan the subscription switches to when the current period ends
+	// (used for downgrades, which never take effect mid-cycle).
+	PendingPlanID *int64 `json:"pending_plan_id,omitempty"`
+	// CancelAtPeriodEnd means the subscription stays active until EndsAt and is then canceled
+	// instead of renewed.
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end"`
+	CanceledAt        *time.Time `json:"canceled_at,omitempty"` // When the user asked to cancel
+	CancelReason      *string    `json:"cancel_reason,omitempty"`
+	CancelFeedback    *string    `json:"cancel_feedback,omitempty"`
+	// TrialEndsAt is set once the subscription has been a trial and keeps the original trial end.
+	TrialEndsAt         *time.Time `json:"trial_ends_at,omitempty"`
+	TrialReminderSentAt *time.Time `json:"-"`
+	// UsageBilledUntil is where the invoiced metered usage ends. Renewals bill the usage up to
+	// the end of the period, immediate plan changes up to the change.
+	UsageBilledUntil time.Time `json:"-"`
+}
+
+// TrialRedemption records that a user started a trial, so that neither the account nor its
+// email can start another one.
+type TrialRedemption struct {
+	UserID int64
+	Email  string // Normalized, see the billing service
+	PlanID int64
 }
 
 // UserSubscriptionDetails is a DTO for returning a user's subscription info.
 type UserSubscriptionDetails struct {
-	PlanName string    `json:"plan_name"`
-	Status   string    `json:"status"`
-	EndsAt   time.Time `json:"ends_at"`
+	PlanName          string     `json:"plan_name"`
+	Status            string     `json:"status"`
+	EndsAt            time.Time  `json:"ends_at"`
+	PendingPlanName   *string    `json:"pending_plan_name,omitempty"`
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end"`
+	TrialEndsAt       *time.Time `json:"trial_ends_at,omitempty"`
+}
+
+// Cancellation survey reasons a user can pick from.
+const (
+	CancelReasonTooExpensive    = "TOO_EXPENSIVE"
+	CancelReasonMissingFeatures = "MISSING_FEATURES"
+	CancelReasonSwitchedService = "SWITCHED_SERVICE"
+	CancelReasonNotUsing        = "NOT_USING"
+	CancelReasonTechnicalIssues = "TECHNICAL_ISSUES"
+	CancelReasonOther           = "OTHER"
+)
+
+// IsValidCancelReason reports whether reason is one of the known survey answers.
+func IsValidCancelReason(reason string) bool {
+	switch reason {
+	case CancelReasonTooExpensive, CancelReasonMissingFeatures, CancelReasonSwitchedService,
+		CancelReasonNotUsing, CancelReasonTechnicalIssues, CancelReasonOther:
+		return true
+	}
+	return false
+}
+
+// Plan change types returned by a proration preview.
+const (
+	ChangeTypeUpgrade   = "UPGRADE"   // Applied immediately, remainder of the period is charged
+	ChangeTypeDowngrade = "DOWNGRADE" // Scheduled for the end of the current period
+	ChangeTypeLateral   = "LATERAL"   // Same price, applied immediately at no cost
+)
+
+// ProrationPreview describes the monetary effect of switching a subscription to another plan.
+type ProrationPreview struct {
+	CurrentPlanID int64     `json:"current_plan_id"`
+	NewPlanID     int64     `json:"new_plan_id"`
+	ChangeType    string    `json:"change_type"`
+	Credit        Money     `json:"credit"`        // Unused portion of the current plan
+	Charge        Money     `json:"charge"`        // Remainder of the current period on the new plan
+	Discount      Money     `json:"discount"`      // Coupon discount on this change
+	Subtotal      Money     `json:"subtotal"`      // Charge minus credit and discount, never negative
+	Tax           *TaxLine  `json:"tax,omitempty"` // nil when no tax applies to the customer
+	AmountDue     Money     `json:"amount_due"`    // Subtotal plus exclusive tax
+	CouponCode    string    `json:"coupon_code,omitempty"`
+	EffectiveAt   time.Time `json:"effective_at"`
+	PeriodEndsAt  time.Time `json:"period_ends_at"`
+	// StorageWarning is set when the user stores more than the new plan allows.
+	StorageWarning *StorageWarning `json:"storage_warning,omitempty"`
+}
+
+// Invoice statuses.
+const (
+	InvoiceStatusOpen = "OPEN"
+	InvoiceStatusPaid = "PAID"
+	InvoiceStatusVoid = "VOID"
+)
+
+// Invoice line kinds.
+const (
+	InvoiceLineSubscription    = "SUBSCRIPTION"
+	InvoiceLineProrationCredit = "PRORATION_CREDIT"
+	InvoiceLineProrationCharge = "PRORATION_CHARGE"
+	InvoiceLineDiscount        = "DISCOUNT"
+	InvoiceLineAddOn           = "ADD_ON"
+	InvoiceLineUsage           = "USAGE" // Metered overage of the period that just ended
+	InvoiceLineTax             = "TAX"   // Only for exclusive tax, inclusive tax is part of the other lines
+)
+
+// Invoice records an amount billed to a user for a subscription.
+type Invoice struct {
+	ID             int64    `json:"id"`
+	UserID         int64    `json:"user_id"`
+	SubscriptionID int64    `json:"subscription_id"`
+	Status         string   `json:"status"`
+	Subtotal       Money    `json:"subtotal"` // Sum of all lines except TAX
+	Tax            *TaxLine `json:"tax,omitempty"`
+	Total          Money    `json:"total"`
+	// PaidFromBalance is the part of the total paid from the user's prepaid balance.
+	PaidFromBalance Money         `json:"paid_from_balance"`
+	Lines           []InvoiceLine `json:"lines"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+// InvoiceLine is a single billed or credited item of an invoice.
+type InvoiceLine struct {
+	ID          int64  `json:"id"`
+	InvoiceID   int64  `json:"invoice_id"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Amount      Money  `json:"amount"`              // Negative for credits and discounts
+	CouponID    *int64 `json:"coupon_id,omitempty"` // Set on DISCOUNT lines
 }
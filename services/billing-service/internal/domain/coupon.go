@@ -0,0 +1,61 @@
+// internal/domain/coupon.go
+package domain
+
+import "time"
+
+// Coupon discount types.
+const (
+	DiscountTypePercent = "PERCENT"
+	DiscountTypeFixed   = "FIXED"
+)
+
+// Coupon durations: how many invoices a redeemed coupon is applied to.
+const (
+	CouponDurationOnce      = "ONCE"      // Only the first invoice
+	CouponDurationRepeating = "REPEATING" // The first DurationMonths invoices
+	CouponDurationForever   = "FOREVER"   // Every invoice while the subscription lasts
+)
+
+// Coupon is a discount code that users can redeem when changing plans.
+type Coupon struct {
+	ID             int64      `json:"id"`
+	Code           string     `json:"code"`
+	DiscountType   string     `json:"discount_type"`
+	PercentOff     float64    `json:"percent_off,omitempty"` // For PERCENT coupons, 0 < x <= 100
+	AmountOff      *Money     `json:"amount_off,omitempty"`  // For FIXED coupons, only applies in its currency
+	Duration       string     `json:"duration"`
+	DurationMonths *int       `json:"duration_months,omitempty"` // Only for REPEATING coupons
+	MaxRedemptions *int       `json:"max_redemptions,omitempty"` // nil means unlimited
+	TimesRedeemed  int        `json:"times_redeemed"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	// PlanIDs restricts the coupon to these plans, an empty list means every plan.
+	PlanIDs   []int64   `json:"plan_ids"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AppliesToPlan reports whether the coupon can be used with the given plan.
+func (c *Coupon) AppliesToPlan(planID int64) bool {
+	if len(c.PlanIDs) == 0 {
+		return true
+	}
+	for _, id := range c.PlanIDs {
+		if id == planID {
+			return true
+		}
+	}
+	return false
+}
+
+// CouponRedemption links a coupon to the subscription it was redeemed for.
+type CouponRedemption struct {
+	ID             int64 `json:"id"`
+	CouponID       int64 `json:"coupon_id"`
+	UserID         int64 `json:"user_id"`
+	SubscriptionID int64 `json:"subscription_id"`
+	// PeriodsRemaining is the number of invoices the discount still applies to, nil means forever.
+	PeriodsRemaining *int      `json:"periods_remaining,omitempty"`
+	IsActive         bool      `json:"is_active"`
+	RedeemedAt       time.Time `json:"redeemed_at"`
+}
@@ -0,0 +1,42 @@
+// internal/domain/credit_note.go
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Where the money of a refund goes.
+const (
+	RefundToWallet   = "WALLET"   // Credited to the user's prepaid balance
+	RefundToProvider = "PROVIDER" // Paid back through the payment provider
+)
+
+// CreditNote gives back part or all of what was paid for an invoice. The invoice itself is
+// never changed, its credit notes are what corrects it.
+type CreditNote struct {
+	ID        int64  `json:"id"`
+	InvoiceID int64  `json:"invoice_id"`
+	UserID    int64  `json:"user_id"`
+	Amount    Money  `json:"amount"` // Refunded amount, including Tax
+	Tax       Money  `json:"tax"`    // The share of the invoice's tax in Amount
+	Reason    string `json:"reason"`
+	RefundTo  string `json:"refund_to"`
+	// TopUpID is the provider payment a PROVIDER refund was paid back to.
+	TopUpID             *int64    `json:"top_up_id,omitempty"`
+	ProviderRefundID    *string   `json:"provider_refund_id,omitempty"`
+	LedgerTransactionID int64     `json:"ledger_transaction_id"`
+	IssuedBy            int64     `json:"issued_by"` // The admin who issued the refund
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// LedgerTransaction books the refund: the money leaves the REFUNDS account towards the
+// user's balance or back to the payment provider.
+func (n *CreditNote) LedgerTransaction() *LedgerTransaction {
+	to := LedgerAccountWallet
+	if n.RefundTo == RefundToProvider {
+		to = LedgerAccountPaymentProvider
+	}
+	return NewTransfer(n.UserID, LedgerTransactionRefund, fmt.Sprintf("Refund of invoice %d", n.InvoiceID),
+		fmt.Sprintf("credit_note:%d", n.ID), LedgerAccountRefunds, to, n.Amount)
+}
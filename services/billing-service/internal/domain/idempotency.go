@@ -0,0 +1,17 @@
+// internal/domain/idempotency.go
+package domain
+
+import "time"
+
+// IdempotencyRecord is a request made with an idempotency key and, once it completed, its
+// response, which retries with the same key receive instead of running the request again.
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	// StatusCode is nil while the first request with the key is in progress.
+	StatusCode   *int
+	ContentType  string
+	ResponseBody []byte
+	CreatedAt    time.Time
+}
@@ -0,0 +1,233 @@
+// internal/domain/money.go
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// currencyExponents lists the supported ISO 4217 currencies with the number of digits
+// of their minor unit (2 for cents, 0 for yen).
+var currencyExponents = map[string]int{
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"CHF": 2,
+	"PLN": 2,
+	"RUB": 2,
+	"KZT": 2,
+	"JPY": 0,
+}
+
+// IsSupportedCurrency reports whether code is an ISO 4217 currency billing-service can charge in.
+func IsSupportedCurrency(code string) bool {
+	_, ok := currencyExponents[code]
+	return ok
+}
+
+// Money is an exact amount in the minor units (cents, kopecks, ...) of an ISO 4217 currency.
+// Amounts are never represented as floating point, not even in JSON.
+type Money struct {
+	Amount   int64  // Minor units
+	Currency string // ISO 4217 code
+}
+
+// NewMoney creates an amount of minor units in the given currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "9.99" in the given currency.
+// More fractional digits than the currency has are rejected rather than rounded.
+func ParseMoney(value, currency string) (Money, error) {
+	exp, ok := currencyExponents[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("unsupported currency '%s'", currency)
+	}
+
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, frac, _ := strings.Cut(value, ".")
+	if whole == "" || len(frac) > exp || strings.ContainsAny(whole+frac, "+-") {
+		return Money{}, fmt.Errorf("invalid %s amount '%s'", currency, value)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid %s amount '%s'", currency, value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Decimal formats the amount in major units, e.g. "9.99".
+func (m Money) Decimal() string {
+	exp := currencyExponents[m.Currency]
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// Add returns m + o. Both amounts must be in the same currency.
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}
+}
+
+// Sub returns m - o. Both amounts must be in the same currency.
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Cmp compares two amounts of the same currency and returns -1, 0 or +1.
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Min returns the smaller of two amounts of the same currency.
+func (m Money) Min(o Money) Money {
+	if m.Cmp(o) <= 0 {
+		return m
+	}
+	return o
+}
+
+// Prorate returns m * part / whole, rounded half away from zero to the minor unit.
+func (m Money) Prorate(part, whole int64) Money {
+	if whole == 0 {
+		return Money{Currency: m.Currency}
+	}
+	n := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(part))
+	d := big.NewInt(whole)
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	// Round half away from zero: compare 2*|r| with |d|
+	if new(big.Int).Abs(new(big.Int).Lsh(r, 1)).Cmp(new(big.Int).Abs(d)) >= 0 {
+		if (n.Sign() < 0) != (d.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Money{Amount: q.Int64(), Currency: m.Currency}
+}
+
+// MulBasisPoints returns m * bp / 10000 (1 bp = 0.01%), rounded half away from zero.
+func (m Money) MulBasisPoints(bp int64) Money {
+	return m.Prorate(bp, 10000)
+}
+
+func (m Money) mustMatch(o Money) {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("money: currency mismatch %s vs %s", m.Currency, o.Currency))
+	}
+}
+
+type moneyJSON struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as a decimal string: {"amount": "9.99", "currency": "EUR"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the amount as a decimal string or a JSON number; either way it is
+// parsed from its text, never through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	parsed, err := ParseMoney(raw.Amount.String(), strings.ToUpper(raw.Currency))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// regionCurrencies maps ISO 3166 regions of a locale to the currency they are billed in.
+var regionCurrencies = map[string]string{
+	"US": "USD",
+	"GB": "GBP",
+	"CH": "CHF",
+	"PL": "PLN",
+	"RU": "RUB",
+	"KZ": "KZT",
+	"JP": "JPY",
+	"DE": "EUR", "FR": "EUR", "ES": "EUR", "IT": "EUR", "NL": "EUR", "AT": "EUR",
+	"BE": "EUR", "IE": "EUR", "FI": "EUR", "PT": "EUR", "GR": "EUR",
+}
+
+// languageCurrencies is the fallback for locales without a region, e.g. "de".
+var languageCurrencies = map[string]string{
+	"de": "EUR",
+	"fr": "EUR",
+	"es": "EUR",
+	"it": "EUR",
+	"nl": "EUR",
+	"pl": "PLN",
+	"ru": "RUB",
+	"kk": "KZT",
+	"ja": "JPY",
+}
+
+// CurrencyForLocale derives a currency from a locale or an Accept-Language header value
+// ("de-DE,de;q=0.9,en;q=0.8"). It returns "" when the locale is unknown.
+func CurrencyForLocale(locale string) string {
+	for _, tag := range strings.Split(locale, ",") {
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), ";")
+		lang, region, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+		if c, ok := regionCurrencies[strings.ToUpper(region)]; ok {
+			return c
+		}
+		if c, ok := languageCurrencies[strings.ToLower(lang)]; ok {
+			return c
+		}
+	}
+	return ""
+}
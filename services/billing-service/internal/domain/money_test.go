@@ -0,0 +1,151 @@
+// internal/domain/money_test.go
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneyProrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      Money
+		part, whole int64
+		want        int64
+	}{
+		{"exact", NewMoney(1000, "EUR"), 1, 4, 250},
+		{"rounds down below half", NewMoney(1000, "EUR"), 1, 3, 333},
+		{"rounds up above half", NewMoney(2000, "EUR"), 1, 3, 667},
+		{"rounds half away from zero", NewMoney(5, "EUR"), 1, 2, 3},
+		{"negative rounds half away from zero", NewMoney(-5, "EUR"), 1, 2, -3},
+		{"whole amount", NewMoney(999, "USD"), 30, 30, 999},
+		{"nothing", NewMoney(999, "USD"), 0, 30, 0},
+		{"zero whole", NewMoney(999, "USD"), 5, 0, 0},
+		{"no overflow on large products", NewMoney(1_000_000_000_00, "USD"), 2_592_000_000_000_000, 2_592_000_000_000_000, 1_000_000_000_00},
+		{"zero-decimal currency", NewMoney(1000, "JPY"), 1, 3, 333},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.amount.Prorate(tt.part, tt.whole)
+			if got.Amount != tt.want || got.Currency != tt.amount.Currency {
+				t.Errorf("%v.Prorate(%d, %d) = %v, want %d %s", tt.amount, tt.part, tt.whole, got, tt.want, tt.amount.Currency)
+			}
+		})
+	}
+}
+
+func TestMoneyMulBasisPoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Money
+		bp     int64
+		want   int64
+	}{
+		{"19 percent", NewMoney(1000, "EUR"), 1900, 190},
+		{"rounds up", NewMoney(999, "EUR"), 1950, 195},    // 194.805
+		{"rounds half away", NewMoney(50, "EUR"), 100, 1}, // 0.5
+		{"below half", NewMoney(49, "EUR"), 100, 0},       // 0.49
+		{"hundred percent", NewMoney(1234, "USD"), 10000, 1234},
+		{"zero rate", NewMoney(1234, "USD"), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.amount.MulBasisPoints(tt.bp); got.Amount != tt.want {
+				t.Errorf("%v.MulBasisPoints(%d) = %v, want %d", tt.amount, tt.bp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyMin(t *testing.T) {
+	tests := []struct {
+		a, b, want Money
+	}{
+		{NewMoney(100, "EUR"), NewMoney(200, "EUR"), NewMoney(100, "EUR")},
+		{NewMoney(200, "EUR"), NewMoney(100, "EUR"), NewMoney(100, "EUR")},
+		{NewMoney(100, "EUR"), NewMoney(100, "EUR"), NewMoney(100, "EUR")},
+		{NewMoney(-100, "EUR"), NewMoney(0, "EUR"), NewMoney(-100, "EUR")},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Min(tt.b); got != tt.want {
+			t.Errorf("%v.Min(%v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyCurrencyMismatch(t *testing.T) {
+	eur, usd := NewMoney(100, "EUR"), NewMoney(100, "USD")
+	tests := []struct {
+		name string
+		op   func()
+	}{
+		{"Add", func() { eur.Add(usd) }},
+		{"Sub", func() { eur.Sub(usd) }},
+		{"Cmp", func() { eur.Cmp(usd) }},
+		{"Min", func() { eur.Min(usd) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s of EUR and USD did not panic", tt.name)
+				}
+			}()
+			tt.op()
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		money Money
+		json  string
+	}{
+		{NewMoney(499, "USD"), `{"amount":"4.99","currency":"USD"}`},
+		{NewMoney(5, "EUR"), `{"amount":"0.05","currency":"EUR"}`},
+		{NewMoney(-1250, "EUR"), `{"amount":"-12.50","currency":"EUR"}`},
+		{NewMoney(1500, "JPY"), `{"amount":"1500","currency":"JPY"}`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.money)
+		if err != nil {
+			t.Fatalf("Marshal(%v): %v", tt.money, err)
+		}
+		if string(data) != tt.json {
+			t.Errorf("Marshal(%v) = %s, want %s", tt.money, data, tt.json)
+		}
+
+		var got Money
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if got != tt.money {
+			t.Errorf("Unmarshal(%s) = %v, want %v", data, got, tt.money)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json    string
+		want    Money
+		wantErr bool
+	}{
+		{json: `{"amount":9.99,"currency":"eur"}`, want: NewMoney(999, "EUR")},
+		{json: `{"amount":"0.1","currency":"USD"}`, want: NewMoney(10, "USD")},
+		{json: `{"amount":"0.105","currency":"USD"}`, wantErr: true},
+		{json: `{"amount":"1.5","currency":"JPY"}`, wantErr: true},
+		{json: `{"amount":"1.00","currency":"XXX"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.json), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) error = %v, wantErr %v", tt.json, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.json, got, tt.want)
+		}
+	}
+}
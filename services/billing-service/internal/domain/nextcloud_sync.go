@@ -0,0 +1,40 @@
+// internal/domain/nextcloud_sync.go
+package domain
+
+import "time"
+
+// Kinds of Nextcloud syncs.
+const (
+	// NextcloudSyncQuota sets the user's quota to the storage of their plan and add-ons and
+	// creates their account first if they have none, e.g. right after registration.
+	NextcloudSyncQuota = "QUOTA"
+	// NextcloudSyncGroups keeps the user in the Nextcloud groups of their plan and removes them
+	// from groups of other plans.
+	NextcloudSyncGroups = "GROUPS"
+)
+
+// Nextcloud sync statuses.
+const (
+	NextcloudSyncPending   = "PENDING"
+	NextcloudSyncDelivered = "DELIVERED"
+	NextcloudSyncDead      = "DEAD" // Gave up after too many attempts, waits for an admin to replay it
+)
+
+// NextcloudSync is an outbox message asking to bring a user's Nextcloud account in line with
+// their subscription. It carries no data: the current state is read when it is delivered, so
+// a user has at most one pending sync of each kind no matter how often they change.
+type NextcloudSync struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	Kind          string     `json:"kind"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	// Generation grows every time the sync is requested again while pending. A delivery only
+	// completes the generation it started with, later requests are delivered again.
+	Generation int64 `json:"-"`
+}
@@ -0,0 +1,66 @@
+// internal/domain/over_limit.go
+package domain
+
+import "time"
+
+// Over-limit policies, i.e. what happens when a plan change leaves a user with more data than
+// the new plan's storage, counting Nextcloud and videos together. Content is never hidden or
+// deleted automatically, see config.BillingConfig.OverLimitPolicy. Support follows up on
+// expired cases instead.
+const (
+	// OverLimitPolicyReadOnly allows the change. The user keeps their data but cannot upload
+	// until they are within their storage again, and is reminded before the grace period ends.
+	OverLimitPolicyReadOnly = "READ_ONLY"
+	// OverLimitPolicyBlockDowngrade refuses plan changes that would leave the user over their
+	// storage. Cancellations are never refused, and scheduled downgrades that take effect while
+	// the user is over it still open a case.
+	OverLimitPolicyBlockDowngrade = "BLOCK_DOWNGRADE"
+)
+
+// IsOverLimitPolicy reports whether p is a known over-limit policy.
+func IsOverLimitPolicy(p string) bool {
+	return p == OverLimitPolicyReadOnly || p == OverLimitPolicyBlockDowngrade
+}
+
+// Over-limit case statuses.
+const (
+	OverLimitOpen = "OPEN"
+	// OverLimitExpired cases passed their grace period without the user freeing up space.
+	// The user stays read-only.
+	OverLimitExpired  = "EXPIRED"
+	OverLimitResolved = "RESOLVED"
+)
+
+// OverLimitCase is a period in which a user stored more than their storage quota. The user is
+// read-only until the case is resolved, which happens as soon as their usage is within the
+// quota again.
+type OverLimitCase struct {
+	ID             int64      `json:"id"`
+	UserID         int64      `json:"user_id"`
+	Status         string     `json:"status"`
+	UsedBytes      int64      `json:"used_bytes"` // When the case was opened
+	QuotaGB        int        `json:"quota_gb"`
+	GraceEndsAt    time.Time  `json:"grace_ends_at"`
+	ReminderSentAt *time.Time `json:"reminder_sent_at,omitempty"`
+	OpenedAt       time.Time  `json:"opened_at"`
+	ExpiredAt      *time.Time `json:"expired_at,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+// StorageWarning tells a user previewing a plan change that they store more than the new plan
+// allows, and what happens if they change anyway.
+type StorageWarning struct {
+	UsedBytes   int64  `json:"used_bytes"`
+	QuotaGB     int    `json:"quota_gb"`
+	OverByBytes int64  `json:"over_by_bytes"`
+	Policy      string `json:"policy"`
+	// Blocked is set when the policy refuses the change.
+	Blocked   bool   `json:"blocked"`
+	GraceDays int    `json:"grace_days,omitempty"`
+	Message   string `json:"message"`
+}
+
+// QuotaBytes converts a storage quota in GB to bytes. Nextcloud reads "100 GB" with binary units.
+func QuotaBytes(quotaGB int) int64 {
+	return int64(quotaGB) << 30
+}
@@ -0,0 +1,34 @@
+// internal/domain/quota_reconciliation.go
+package domain
+
+import "time"
+
+// QuotaReconciliation is the report of one comparison of the quotas set in Nextcloud with the
+// quotas users are entitled to.
+type QuotaReconciliation struct {
+	ID     int64 `json:"id"`
+	DryRun bool  `json:"dry_run"` // Discrepancies were only reported, not corrected
+	// Checked counts the Nextcloud accounts of subscribers that were compared.
+	Checked   int `json:"checked"`
+	InSync    int `json:"in_sync"`
+	Drifted   int `json:"drifted"`
+	Corrected int `json:"corrected"` // Drifted accounts a quota sync was queued for
+	// Unmanaged counts Nextcloud accounts without a subscription, e.g. admin accounts.
+	Unmanaged int `json:"unmanaged"`
+	// Missing counts subscribers without a Nextcloud account.
+	Missing       int                `json:"missing"`
+	Failed        int                `json:"failed"` // Users that could not be checked
+	Discrepancies []QuotaDiscrepancy `json:"discrepancies"`
+	StartedAt     time.Time          `json:"started_at"`
+	FinishedAt    time.Time          `json:"finished_at"`
+}
+
+// QuotaDiscrepancy is a Nextcloud account whose quota differs from the user's entitlement.
+type QuotaDiscrepancy struct {
+	UserID          int64  `json:"user_id"`
+	Username        string `json:"username"`
+	ExpectedGB      int    `json:"expected_gb"`
+	ActualBytes     int64  `json:"actual_bytes"`
+	ActualUnlimited bool   `json:"actual_unlimited"`
+	Corrected       bool   `json:"corrected"`
+}
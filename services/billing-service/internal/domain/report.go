@@ -0,0 +1,63 @@
+// internal/domain/report.go
+package domain
+
+import "time"
+
+// SubscriptionSnapshot is a live subscription as its history recorded it at some point in time.
+type SubscriptionSnapshot struct {
+	SubscriptionID int64
+	UserID         int64
+	PlanID         int64
+	PlanName       string
+	Status         string
+	// MRR is the monthly price of the plan version in the subscription's currency, less the
+	// coupon discount in effect, plus the user's add-ons. It is zero for subscriptions that do
+	// not pay, e.g. while trialing.
+	MRR Money
+}
+
+// MRRReport is the recurring revenue of one currency in one period and how it moved. Amounts
+// are not converted between currencies, so every currency has its own rows. Coupon discounts
+// are deducted for as long as they apply. Add-ons count at the prices they had at the time.
+//
+// EndingMRR = StartingMRR + NewMRR + ExpansionMRR - ContractionMRR - ChurnedMRR
+type MRRReport struct {
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"` // Exclusive
+	Currency       string    `json:"currency"`
+	StartingMRR    Money     `json:"starting_mrr"`
+	NewMRR         Money     `json:"new_mrr"`         // From users who paid nothing at the start
+	ExpansionMRR   Money     `json:"expansion_mrr"`   // From users who pay more than at the start
+	ContractionMRR Money     `json:"contraction_mrr"` // From users who pay less, but still pay
+	ChurnedMRR     Money     `json:"churned_mrr"`     // From users who paid at the start and no longer do
+	EndingMRR      Money     `json:"ending_mrr"`
+	EndingARR      Money     `json:"ending_arr"`
+	// Subscribers are the paying users at the end of the period.
+	Subscribers        int `json:"subscribers"`
+	NewSubscribers     int `json:"new_subscribers"`
+	ChurnedSubscribers int `json:"churned_subscribers"`
+}
+
+// PlanMixEntry is how many users are on a plan at a point in time and what they pay, per currency.
+type PlanMixEntry struct {
+	PlanID   int64  `json:"plan_id"`
+	PlanName string `json:"plan_name"`
+	Currency string `json:"currency"`
+	Active   int    `json:"active"`
+	Trialing int    `json:"trialing"`
+	MRR      Money  `json:"mrr"`
+}
+
+// TrialConversion counts the outcome of the trials of a plan that started in a date range.
+type TrialConversion struct {
+	PlanID   int64  `json:"plan_id"`
+	PlanName string `json:"plan_name"`
+	Started  int    `json:"started"`
+	// Converted trials were followed by a paid period.
+	Converted int `json:"converted"`
+	// NotConverted trials were canceled or ended on a free plan.
+	NotConverted int `json:"not_converted"`
+	InTrial      int `json:"in_trial"`
+	// ConversionRate is Converted out of the trials that ended, 0 while none has.
+	ConversionRate float64 `json:"conversion_rate"`
+}
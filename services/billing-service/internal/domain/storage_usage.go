@@ -0,0 +1,44 @@
+// internal/domain/storage_usage.go
+package domain
+
+import "time"
+
+// StorageWarningLevels are the shares of storage_quota_gb, in percent, at which users are
+// warned that they are running out of storage. Each is sent once per crossing.
+var StorageWarningLevels = []int{80, 100}
+
+// StorageUsage is a user's storage usage: their Nextcloud usage as last fetched and their
+// video library as video-service last reported it. Both count towards storage_quota_gb.
+type StorageUsage struct {
+	UserID int64 `json:"user_id"`
+	// UsedBytes and FreeBytes are what Nextcloud reports.
+	UsedBytes int64 `json:"used_bytes"`
+	FreeBytes int64 `json:"free_bytes"`
+	// VideoBytes is the size of the user's uploaded videos, 0 until video-service reported it.
+	VideoBytes int64 `json:"video_bytes"`
+	// QuotaGB is the storage the user is entitled to, which Nextcloud enforces.
+	QuotaGB int `json:"quota_gb"`
+	// UsedPercent is the share of QuotaGB taken by Nextcloud and videos together.
+	UsedPercent float64   `json:"used_percent"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	// WarningLevel is the highest of StorageWarningLevels the user was warned about, 0 if none.
+	WarningLevel int `json:"-"`
+	// OverLimit is the user's unresolved over-limit case, during which they cannot upload.
+	OverLimit *OverLimitCase `json:"over_limit,omitempty"`
+}
+
+// TotalBytes is what the user stores in Nextcloud and video-service together.
+func (u *StorageUsage) TotalBytes() int64 {
+	return u.UsedBytes + u.VideoBytes
+}
+
+// StorageWarningLevel returns the highest warning level usedPercent has reached, 0 if none.
+func StorageWarningLevel(usedPercent float64) int {
+	level := 0
+	for _, l := range StorageWarningLevels {
+		if usedPercent >= float64(l) {
+			level = l
+		}
+	}
+	return level
+}
@@ -0,0 +1,71 @@
+// internal/domain/subscription_history.go
+package domain
+
+import "time"
+
+// Subscription history events.
+const (
+	SubscriptionEventCreated               = "CREATED"
+	SubscriptionEventPlanChanged           = "PLAN_CHANGED"
+	SubscriptionEventChangeScheduled       = "CHANGE_SCHEDULED"
+	SubscriptionEventTrialStarted          = "TRIAL_STARTED"
+	SubscriptionEventCancellationScheduled = "CANCELLATION_SCHEDULED"
+	SubscriptionEventResumed               = "RESUMED"
+	SubscriptionEventCanceled              = "CANCELED"
+	SubscriptionEventRenewed               = "RENEWED"
+	SubscriptionEventPeriodStarted         = "PERIOD_STARTED" // A billing period started outside of a renewal, e.g. for add-ons
+	SubscriptionEventVersionMigrated       = "VERSION_MIGRATED"
+	SubscriptionEventPastDue               = "PAST_DUE"     // A renewal was not covered by the balance
+	SubscriptionEventRenewalPaid           = "RENEWAL_PAID" // The open invoices of a past-due subscription were settled
+)
+
+// Who caused a subscription change.
+const (
+	ActorUser   = "USER"
+	ActorAdmin  = "ADMIN"
+	ActorSystem = "SYSTEM" // Renewals, migrations and other background work
+)
+
+// SubscriptionChange describes why a subscription is written. It is recorded in the
+// subscription's history together with the state after the write.
+type SubscriptionChange struct {
+	Event     string
+	Reason    string
+	ActorType string
+	ActorID   *int64 // Unset for ActorSystem
+}
+
+// ChangeByUser is a change the subscriber made themselves.
+func ChangeByUser(event string, userID int64, reason string) SubscriptionChange {
+	return SubscriptionChange{Event: event, Reason: reason, ActorType: ActorUser, ActorID: &userID}
+}
+
+// ChangeByAdmin is a change an admin made to someone's subscription.
+func ChangeByAdmin(event string, adminID int64, reason string) SubscriptionChange {
+	return SubscriptionChange{Event: event, Reason: reason, ActorType: ActorAdmin, ActorID: &adminID}
+}
+
+// ChangeBySystem is a change made by billing-service itself.
+func ChangeBySystem(event, reason string) SubscriptionChange {
+	return SubscriptionChange{Event: event, Reason: reason, ActorType: ActorSystem}
+}
+
+// SubscriptionHistoryEntry is an immutable snapshot of a subscription right after a change.
+type SubscriptionHistoryEntry struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int64     `json:"subscription_id"`
+	UserID         int64     `json:"user_id"`
+	Event          string    `json:"event"`
+	PlanID         int64     `json:"plan_id"`
+	PlanName       string    `json:"plan_name"`
+	PlanVersionID  int64     `json:"plan_version_id"`
+	Status         string    `json:"status"`
+	StartsAt       time.Time `json:"starts_at"`
+	EndsAt         time.Time `json:"ends_at"`
+	Currency       string    `json:"currency"`
+	PendingPlanID  *int64    `json:"pending_plan_id,omitempty"`
+	Reason         *string   `json:"reason,omitempty"`
+	ActorType      string    `json:"actor_type"`
+	ActorID        *int64    `json:"actor_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
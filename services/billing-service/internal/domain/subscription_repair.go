@@ -0,0 +1,16 @@
+// internal/domain/subscription_repair.go
+package domain
+
+import "time"
+
+// SubscriptionRepair is the report of one search for users without a live subscription, e.g.
+// because assigning the default plan at registration failed.
+type SubscriptionRepair struct {
+	Checked int `json:"checked"` // Users known to user-service
+	Missing int `json:"missing"`
+	// Assigned are the users who were given the default plan.
+	Assigned   []int64   `json:"assigned"`
+	Failed     int       `json:"failed"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
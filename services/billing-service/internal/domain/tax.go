@@ -0,0 +1,65 @@
+// internal/domain/tax.go
+package domain
+
+import "time"
+
+// TaxRate is the sales tax (VAT, GST, ...) charged to customers in a country or one of its regions.
+type TaxRate struct {
+	ID      int64  `json:"id"`
+	Country string `json:"country"`          // ISO 3166-1 alpha-2
+	Region  string `json:"region,omitempty"` // ISO 3166-2 subdivision, empty for the whole country
+	Name    string `json:"name"`             // Shown on invoices, e.g. "VAT"
+	RateBps int64  `json:"rate_bps"`         // In basis points: 1900 is 19%
+	// Inclusive means plan prices already contain the tax, so it is carved out of the amount
+	// instead of being added on top.
+	Inclusive bool      `json:"inclusive"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BillingProfile holds the tax-relevant details of a customer.
+type BillingProfile struct {
+	UserID      int64     `json:"user_id"`
+	Country     string    `json:"country"`
+	Region      string    `json:"region,omitempty"`
+	IsBusiness  bool      `json:"is_business"`
+	CompanyName *string   `json:"company_name,omitempty"`
+	TaxID       *string   `json:"tax_id,omitempty"` // VAT identification number of a business
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TaxLine is the tax applied to an invoice or a checkout preview.
+type TaxLine struct {
+	Name    string `json:"name"`
+	Country string `json:"country"`
+	Region  string `json:"region,omitempty"`
+	RateBps int64  `json:"rate_bps"`
+	// Inclusive taxes are already part of the charged amount, exclusive ones are added to it.
+	Inclusive bool `json:"inclusive"`
+	// ReverseCharge means no tax is charged: the business customer accounts for it instead.
+	ReverseCharge bool    `json:"reverse_charge"`
+	CustomerTaxID *string `json:"customer_tax_id,omitempty"` // Required on reverse-charge invoices
+	Taxable       Money   `json:"taxable"`                   // Net amount the tax is calculated on
+	Amount        Money   `json:"amount"`
+}
+
+// euCountries are the EU member states, within which B2B supplies are reverse-charged.
+var euCountries = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true, "DK": true, "EE": true, "ES": true,
+	"FI": true, "FR": true, "GR": true, "HR": true, "HU": true, "IE": true, "IT": true, "LT": true, "LU": true,
+	"LV": true, "MT": true, "NL": true, "PL": true, "PT": true, "RO": true, "SE": true, "SI": true, "SK": true,
+}
+
+// IsEUCountry reports whether the ISO 3166-1 alpha-2 code is an EU member state.
+func IsEUCountry(country string) bool {
+	return euCountries[country]
+}
+
+// VATPrefix returns the prefix of EU VAT numbers issued in country. It matches the country code
+// except for Greece, which uses "EL".
+func VATPrefix(country string) string {
+	if country == "GR" {
+		return "EL"
+	}
+	return country
+}
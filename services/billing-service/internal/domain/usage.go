@@ -0,0 +1,140 @@
+// internal/domain/usage.go
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Usage metrics reported by video-service. Only storage has a producer so far: video-service
+// neither transcodes nor serves videos yet.
+const (
+	UsageMetricStorageGBHours   = "STORAGE_GB_HOURS"
+	UsageMetricTranscodeMinutes = "TRANSCODE_MINUTES"
+	UsageMetricEgressBytes      = "EGRESS_BYTES"
+)
+
+// IsValidUsageMetric reports whether metric is one of the metered usage metrics.
+func IsValidUsageMetric(metric string) bool {
+	switch metric {
+	case UsageMetricStorageGBHours, UsageMetricTranscodeMinutes, UsageMetricEgressBytes:
+		return true
+	}
+	return false
+}
+
+// quantityScale is the number of Quantity units in one unit of a metric.
+const quantityScale = 1000
+
+// Quantity is an exact amount of usage in thousandths of the metric's unit, so that e.g.
+// 0.25 GB-hours can be summed up without floating point errors.
+type Quantity int64
+
+// NewQuantity converts whole units of a metric into a Quantity.
+func NewQuantity(units int64) Quantity {
+	return Quantity(units * quantityScale)
+}
+
+// ParseQuantity parses a decimal string with at most three fractional digits, e.g. "12.5".
+func ParseQuantity(value string) (Quantity, error) {
+	value = strings.TrimSpace(value)
+	whole, frac, _ := strings.Cut(value, ".")
+	if whole == "" || len(frac) > 3 || strings.ContainsAny(whole+frac, "+-") {
+		return 0, fmt.Errorf("invalid quantity '%s'", value)
+	}
+	frac += strings.Repeat("0", 3-len(frac))
+
+	q, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity '%s'", value)
+	}
+	return Quantity(q), nil
+}
+
+// String formats the quantity in units of its metric, e.g. "12.5".
+func (q Quantity) String() string {
+	s := strconv.FormatInt(int64(q)/quantityScale, 10)
+	if frac := int64(q) % quantityScale; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%03d", frac), "0")
+	}
+	return s
+}
+
+// MarshalJSON encodes the quantity as a decimal string.
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.String())
+}
+
+// UnmarshalJSON accepts a decimal string or a JSON number, parsed from its text.
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	var raw json.Number
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := ParseQuantity(raw.String())
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// UsageEvent is a single usage measurement reported by another service.
+type UsageEvent struct {
+	ID int64 `json:"id"`
+	// EventID is the producer's unique ID of the event, reporting it again has no effect.
+	EventID    string    `json:"event_id"`
+	UserID     int64     `json:"user_id"`
+	Metric     string    `json:"metric"`
+	Quantity   Quantity  `json:"quantity"`
+	OccurredAt time.Time `json:"occurred_at"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// UsageRecording is the outcome of recording a batch of usage events.
+type UsageRecording struct {
+	Recorded   int `json:"recorded"`
+	Duplicates int `json:"duplicates"` // Recorded before, e.g. by an earlier attempt
+	// Late are the IDs of events that occurred before the user's current billing period, which
+	// was invoiced already. They are not recorded and should not be sent again.
+	Late []string `json:"late"`
+}
+
+// MeteredPrice charges the usage of a metric beyond what a plan includes per billing period.
+type MeteredPrice struct {
+	Metric   string   `json:"metric"`
+	Included Quantity `json:"included"`  // Usage per period that costs nothing
+	UnitSize Quantity `json:"unit_size"` // UnitPrices are per this much usage, e.g. 1073741824 egress bytes
+	// UnitPrices is the price of UnitSize usage, one entry per currency the plan is sold in.
+	UnitPrices []Money `json:"unit_prices"`
+}
+
+// UnitPriceIn returns the unit price in currency, ok is false if the metric is not sold in it.
+func (m *MeteredPrice) UnitPriceIn(currency string) (Money, bool) {
+	for _, price := range m.UnitPrices {
+		if price.Currency == currency {
+			return price, true
+		}
+	}
+	return Money{}, false
+}
+
+// Overage returns the part of `used` that exceeds the included usage.
+func (m *MeteredPrice) Overage(used Quantity) Quantity {
+	if used <= m.Included {
+		return 0
+	}
+	return used - m.Included
+}
+
+// UsageSummary is the usage of one metric within a billing period.
+type UsageSummary struct {
+	Metric   string   `json:"metric"`
+	Quantity Quantity `json:"quantity"`
+	Included Quantity `json:"included"`
+	Overage  Quantity `json:"overage"`
+	Charge   *Money   `json:"charge,omitempty"` // nil for metrics the plan does not bill
+}
@@ -0,0 +1,114 @@
+// internal/domain/wallet.go
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ledger accounts. Every transaction moves money between accounts and its entries add up to
+// zero, so money is never created or lost. WALLET entries belong to the transaction's user,
+// the other accounts are the company's.
+const (
+	LedgerAccountWallet          = "WALLET"           // The user's prepaid balance
+	LedgerAccountPaymentProvider = "PAYMENT_PROVIDER" // Money received through the payment provider
+	LedgerAccountReceivables     = "RECEIVABLES"      // Invoices settled from a balance
+	LedgerAccountRefunds         = "REFUNDS"          // Refunds credited to a balance
+)
+
+// Ledger transaction kinds.
+const (
+	LedgerTransactionTopUp          = "TOP_UP"
+	LedgerTransactionInvoicePayment = "INVOICE_PAYMENT"
+	LedgerTransactionRefund         = "REFUND"
+)
+
+// LedgerTransaction is an immutable, balanced set of ledger entries.
+type LedgerTransaction struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	// Reference identifies what caused the transaction, e.g. "invoice:42". It is unique, so
+	// the same event can never be booked twice.
+	Reference string        `json:"reference"`
+	Entries   []LedgerEntry `json:"entries"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// LedgerEntry credits (positive amount) or debits (negative amount) one account.
+type LedgerEntry struct {
+	ID            int64  `json:"id"`
+	TransactionID int64  `json:"transaction_id"`
+	Account       string `json:"account"`
+	Amount        Money  `json:"amount"`
+}
+
+// NewTransfer returns a transaction that moves `amount` from one account to another.
+func NewTransfer(userID int64, kind, description, reference, from, to string, amount Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		UserID:      userID,
+		Kind:        kind,
+		Description: description,
+		Reference:   reference,
+		Entries: []LedgerEntry{
+			{Account: from, Amount: amount.Neg()},
+			{Account: to, Amount: amount},
+		},
+	}
+}
+
+// Validate checks that the transaction is balanced: at least two non-zero entries in a
+// single currency that add up to zero.
+func (t *LedgerTransaction) Validate() error {
+	if len(t.Entries) < 2 {
+		return fmt.Errorf("a ledger transaction needs at least two entries")
+	}
+	if t.Reference == "" {
+		return fmt.Errorf("a ledger transaction needs a reference")
+	}
+	sum := NewMoney(0, t.Entries[0].Amount.Currency)
+	for _, e := range t.Entries {
+		if e.Amount.Currency != sum.Currency {
+			return fmt.Errorf("ledger entries must all be in %s", sum.Currency)
+		}
+		if e.Amount.IsZero() {
+			return fmt.Errorf("ledger entries must not be zero")
+		}
+		sum = sum.Add(e.Amount)
+	}
+	if !sum.IsZero() {
+		return fmt.Errorf("ledger entries do not balance, they add up to %s", sum)
+	}
+	return nil
+}
+
+// WalletTransaction is a change of a user's balance, as shown to the user.
+type WalletTransaction struct {
+	TransactionID int64     `json:"transaction_id"`
+	Kind          string    `json:"kind"`
+	Description   string    `json:"description"`
+	Amount        Money     `json:"amount"` // Positive for money added to the balance
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Top-up statuses.
+const (
+	TopUpStatusPending   = "PENDING"
+	TopUpStatusSucceeded = "SUCCEEDED"
+	TopUpStatusFailed    = "FAILED"
+)
+
+// WalletTopUp is a payment through the payment provider that adds to a user's balance once
+// the provider confirms it.
+type WalletTopUp struct {
+	ID                int64      `json:"id"`
+	UserID            int64      `json:"user_id"`
+	Amount            Money      `json:"amount"`
+	Refunded          Money      `json:"refunded"` // Paid back through the provider by credit notes
+	Status            string     `json:"status"`
+	ProviderPaymentID *string    `json:"provider_payment_id,omitempty"`
+	CheckoutURL       string     `json:"checkout_url"` // Where the user completes the payment
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,138 @@
+// internal/domain/webhook.go
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types.
+const (
+	WebhookEventSubscriptionCreated  = "subscription.created"
+	WebhookEventSubscriptionChanged  = "subscription.changed" // Any change other than creation and cancellation
+	WebhookEventSubscriptionCanceled = "subscription.canceled"
+	WebhookEventInvoicePaid          = "invoice.paid"
+	WebhookEventPaymentFailed        = "payment.failed"
+)
+
+// WebhookEventTypes are all event types an endpoint can subscribe to.
+var WebhookEventTypes = []string{
+	WebhookEventSubscriptionCreated,
+	WebhookEventSubscriptionChanged,
+	WebhookEventSubscriptionCanceled,
+	WebhookEventInvoicePaid,
+	WebhookEventPaymentFailed,
+}
+
+// IsWebhookEventType reports whether t is one of WebhookEventTypes.
+func IsWebhookEventType(t string) bool {
+	for _, known := range WebhookEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionWebhookEventType returns the webhook event type of a subscription history event.
+func SubscriptionWebhookEventType(event string) string {
+	switch event {
+	case SubscriptionEventCreated:
+		return WebhookEventSubscriptionCreated
+	case SubscriptionEventCanceled:
+		return WebhookEventSubscriptionCanceled
+	default:
+		return WebhookEventSubscriptionChanged
+	}
+}
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryDead      = "DEAD" // Gave up after too many attempts, waits for an admin to redeliver it
+)
+
+// WebhookEndpoint is a URL an external system registered to receive billing events.
+type WebhookEndpoint struct {
+	ID          int64  `json:"id"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	// Secret signs every delivery. It is only returned when the endpoint is created or the
+	// secret is rotated.
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	IsActive   bool      `json:"is_active"` // Deliveries to inactive endpoints wait until it is active again
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookEvent is something that happened in billing, sent to every endpoint subscribed to its type.
+type WebhookEvent struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	UserID    *int64          `json:"user_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// WebhookDelivery is the sending of one event to one endpoint. Redelivering it sends the
+// same event ID again, so receivers can drop duplicates.
+type WebhookDelivery struct {
+	ID                 int64                    `json:"id"`
+	EventID            int64                    `json:"event_id"`
+	EventType          string                   `json:"event_type"`
+	EndpointID         int64                    `json:"endpoint_id"`
+	URL                string                   `json:"url"`
+	Status             string                   `json:"status"`
+	Attempts           int                      `json:"attempts"`
+	NextAttemptAt      time.Time                `json:"next_attempt_at"`
+	LastError          *string                  `json:"last_error,omitempty"`
+	LastResponseStatus *int                     `json:"last_response_status,omitempty"`
+	CreatedAt          time.Time                `json:"created_at"`
+	UpdatedAt          time.Time                `json:"updated_at"`
+	DeliveredAt        *time.Time               `json:"delivered_at,omitempty"`
+	Event              *WebhookEvent            `json:"event,omitempty"` // Only loaded for a single delivery
+	Log                []WebhookDeliveryAttempt `json:"log,omitempty"`   // Only loaded for a single delivery
+	Secret             string                   `json:"-"`               // Only loaded for sending
+}
+
+// WebhookDeliveryAttempt is the log entry of a single try to send a delivery.
+type WebhookDeliveryAttempt struct {
+	ID             int64     `json:"id"`
+	DeliveryID     int64     `json:"delivery_id"`
+	ResponseStatus *int      `json:"response_status,omitempty"` // Unset if no response was received
+	ResponseBody   *string   `json:"response_body,omitempty"`   // Truncated
+	Error          *string   `json:"error,omitempty"`
+	DurationMs     int       `json:"duration_ms"`
+	AttemptedAt    time.Time `json:"attempted_at"`
+}
+
+// SubscriptionWebhookData is the data of subscription.* events: the subscription right after
+// the change and what caused it.
+type SubscriptionWebhookData struct {
+	SubscriptionID    int64      `json:"subscription_id"`
+	UserID            int64      `json:"user_id"`
+	PlanID            int64      `json:"plan_id"`
+	PlanName          string     `json:"plan_name"`
+	PlanVersionID     int64      `json:"plan_version_id"`
+	Status            string     `json:"status"`
+	Currency          string     `json:"currency"`
+	StartsAt          time.Time  `json:"starts_at"`
+	EndsAt            time.Time  `json:"ends_at"`
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end"`
+	TrialEndsAt       *time.Time `json:"trial_ends_at,omitempty"`
+	PendingPlanID     *int64     `json:"pending_plan_id,omitempty"`
+	// Event is the subscription history event of the change, e.g. "PLAN_CHANGED".
+	Event     string `json:"event"`
+	Reason    string `json:"reason,omitempty"`
+	ActorType string `json:"actor_type"`
+}
+
+// PaymentWebhookData is the data of payment.* events.
+type PaymentWebhookData struct {
+	UserID            int64   `json:"user_id"`
+	TopUpID           int64   `json:"top_up_id"`
+	ProviderPaymentID *string `json:"provider_payment_id,omitempty"`
+	Amount            Money   `json:"amount"`
+}
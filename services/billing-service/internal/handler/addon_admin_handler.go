@@ -0,0 +1,100 @@
+// services/billing-service/internal/handler/addon_admin_handler.go
+package handler
+
+import (
+	"encoding/json"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/service"
+	"jcloud-project/libs/go-common/entitlements"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AddOnAdminHandler struct {
+	service service.AddOnService
+}
+
+func NewAddOnAdminHandler(s service.AddOnService) *AddOnAdminHandler {
+	return &AddOnAdminHandler{service: s}
+}
+
+func (h *AddOnAdminHandler) GetAllAddOns(c echo.Context) error {
+	addOns, err := h.service.GetAllAddOns(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, addOns)
+}
+
+type createAddOnRequest struct {
+	Name             string          `json:"name"`
+	Description      string          `json:"description"`
+	Prices           []domain.Money  `json:"prices"`
+	PermissionDeltas json.RawMessage `json:"permissionDeltas"`
+}
+
+func (h *AddOnAdminHandler) CreateAddOn(c echo.Context) error {
+	var req createAddOnRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+	deltas, err := entitlements.ParseDeltas(req.PermissionDeltas)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	addOn, err := h.service.CreateAddOn(c.Request().Context(), &domain.AddOn{
+		Name:             req.Name,
+		Description:      req.Description,
+		Prices:           req.Prices,
+		PermissionDeltas: deltas,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, addOn)
+}
+
+type patchAddOnRequest struct {
+	Name        *string         `json:"name,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Prices      *[]domain.Money `json:"prices,omitempty"`
+	IsActive    *bool           `json:"isActive,omitempty"`
+}
+
+func (h *AddOnAdminHandler) PatchAddOn(c echo.Context) error {
+	addOnID, err := strconv.ParseInt(c.Param("addOnId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid add-on id"})
+	}
+
+	var req patchAddOnRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	addOn, err := h.service.PatchAddOn(c.Request().Context(), addOnID, service.AddOnPatch{
+		Name:        req.Name,
+		Description: req.Description,
+		Prices:      req.Prices,
+		IsActive:    req.IsActive,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, addOn)
+}
+
+func (h *AddOnAdminHandler) ArchiveAddOn(c echo.Context) error {
+	addOnID, err := strconv.ParseInt(c.Param("addOnId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid add-on id"})
+	}
+
+	if err := h.service.ArchiveAddOn(c.Request().Context(), addOnID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,27 @@
+// services/billing-service/internal/handler/admin_middleware.go
+package handler
+
+import (
+	commontypes "jcloud-project/libs/go-common/types/jwt"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+func AdminMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userToken, ok := c.Get("user").(*jwt.Token)
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid token"})
+		}
+		claims, ok := userToken.Claims.(*commontypes.JwtCustomClaims)
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, echo.Map{"error": "invalid token claims"})
+		}
+		if claims.Role != "ADMIN" {
+			return c.JSON(http.StatusForbidden, echo.Map{"error": "access forbidden: administrator role required"})
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,118 @@
+// services/billing-service/internal/handler/coupon_handler.go
+package handler
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type CouponHandler struct {
+	service service.CouponService
+}
+
+func NewCouponHandler(s service.CouponService) *CouponHandler {
+	return &CouponHandler{service: s}
+}
+
+func (h *CouponHandler) GetAllCoupons(c echo.Context) error {
+	coupons, err := h.service.GetAllCoupons(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, coupons)
+}
+
+func (h *CouponHandler) GetCoupon(c echo.Context) error {
+	couponID, err := strconv.ParseInt(c.Param("couponId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid coupon id"})
+	}
+
+	coupon, err := h.service.GetCoupon(c.Request().Context(), couponID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, coupon)
+}
+
+type createCouponRequest struct {
+	Code           string        `json:"code"`
+	DiscountType   string        `json:"discountType"`
+	PercentOff     float64       `json:"percentOff"`
+	AmountOff      *domain.Money `json:"amountOff"`
+	Duration       string        `json:"duration"`
+	DurationMonths *int          `json:"durationMonths"`
+	MaxRedemptions *int          `json:"maxRedemptions"`
+	ExpiresAt      *time.Time    `json:"expiresAt"`
+	PlanIDs        []int64       `json:"planIds"`
+}
+
+func (h *CouponHandler) CreateCoupon(c echo.Context) error {
+	var req createCouponRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	coupon, err := h.service.CreateCoupon(c.Request().Context(), &domain.Coupon{
+		Code:           req.Code,
+		DiscountType:   req.DiscountType,
+		PercentOff:     req.PercentOff,
+		AmountOff:      req.AmountOff,
+		Duration:       req.Duration,
+		DurationMonths: req.DurationMonths,
+		MaxRedemptions: req.MaxRedemptions,
+		ExpiresAt:      req.ExpiresAt,
+		PlanIDs:        req.PlanIDs,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, coupon)
+}
+
+type patchCouponRequest struct {
+	MaxRedemptions *int       `json:"maxRedemptions,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	PlanIDs        *[]int64   `json:"planIds,omitempty"`
+	IsActive       *bool      `json:"isActive,omitempty"`
+}
+
+func (h *CouponHandler) PatchCoupon(c echo.Context) error {
+	couponID, err := strconv.ParseInt(c.Param("couponId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid coupon id"})
+	}
+
+	var req patchCouponRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	coupon, err := h.service.PatchCoupon(c.Request().Context(), couponID, service.CouponPatch{
+		MaxRedemptions: req.MaxRedemptions,
+		ExpiresAt:      req.ExpiresAt,
+		PlanIDs:        req.PlanIDs,
+		IsActive:       req.IsActive,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, coupon)
+}
+
+func (h *CouponHandler) DeactivateCoupon(c echo.Context) error {
+	couponID, err := strconv.ParseInt(c.Param("couponId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid coupon id"})
+	}
+
+	if err := h.service.DeactivateCoupon(c.Request().Context(), couponID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
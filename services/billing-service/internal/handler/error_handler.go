@@ -28,6 +28,9 @@ func CustomHTTPErrorHandler(err error, c echo.Context) {
 	case errors.Is(err, ierr.ErrConflict):
 		httpCode = http.StatusConflict
 		errMsg = err.Error()
+	case errors.Is(err, ierr.ErrInvalidInput):
+		httpCode = http.StatusBadRequest
+		errMsg = err.Error()
 	default:
 		httpCode = http.StatusInternalServerError
 		errMsg = "internal server error"
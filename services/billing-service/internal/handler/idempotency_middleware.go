@@ -0,0 +1,79 @@
+// services/billing-service/internal/handler/idempotency_middleware.go
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"jcloud-project/billing-service/internal/service"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// IdempotencyKeyHeader carries the caller's key for a write it may retry.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks responses stored for an earlier request with the same key.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotencyMiddleware runs a request carrying an Idempotency-Key header once per key.
+// Retries with the same key and body get the stored response. Requests without the header
+// are passed through. Failed requests, i.e. errors and 5xx responses, are not stored, so that
+// their retry runs again.
+func IdempotencyMiddleware(s service.IdempotencyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request body"})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+			hash := sha256.Sum256(body)
+			scope := c.Request().Method + " " + c.Request().URL.Path
+			ctx := c.Request().Context()
+
+			stored, err := s.Begin(ctx, scope, key, hex.EncodeToString(hash[:]))
+			if err != nil {
+				return err
+			}
+			if stored != nil {
+				c.Response().Header().Set(idempotentReplayedHeader, "true")
+				return c.Blob(*stored.StatusCode, stored.ContentType, stored.ResponseBody)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			err = next(c)
+			if err != nil || c.Response().Status >= http.StatusInternalServerError {
+				if releaseErr := s.Release(ctx, scope, key); releaseErr != nil {
+					log.Printf("Failed to release idempotency key '%s' of %s: %v", key, scope, releaseErr)
+				}
+				return err
+			}
+			if err := s.Complete(ctx, scope, key, c.Response().Status, c.Response().Header().Get(echo.HeaderContentType), recorder.body.Bytes()); err != nil {
+				log.Printf("Failed to store the response for idempotency key '%s' of %s: %v", key, scope, err)
+			}
+			return nil
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body written through it.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
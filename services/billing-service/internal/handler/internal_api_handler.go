@@ -2,9 +2,11 @@
 package handler
 
 import (
+	"jcloud-project/billing-service/internal/domain"
 	"jcloud-project/billing-service/internal/service"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -36,16 +38,58 @@ type createSubscriptionRequest struct {
 	PlanName string `json:"planName"`
 }
 
+// CreateSubscription puts a new user on a plan. It is safe to retry: a user who already has a
+// subscription keeps it and the request succeeds with 200.
 func (h *InternalApiHandler) CreateSubscription(c echo.Context) error {
 	var req createSubscriptionRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
 	}
 
-	err := h.service.CreateInitialSubscription(c.Request().Context(), req.UserID, req.PlanName)
+	created, err := h.service.CreateInitialSubscription(c.Request().Context(), req.UserID, req.PlanName)
 	if err != nil {
 		return err
 	}
+	if !created {
+		return c.JSON(http.StatusOK, echo.Map{"message": "user already has a subscription"})
+	}
 
 	return c.JSON(http.StatusCreated, echo.Map{"message": "subscription created successfully"})
 }
+
+type recordUsageRequest struct {
+	Events []struct {
+		EventID    string          `json:"eventId"`
+		UserID     int64           `json:"userId"`
+		Metric     string          `json:"metric"`
+		Quantity   domain.Quantity `json:"quantity"`
+		OccurredAt time.Time       `json:"occurredAt"`
+	} `json:"events"`
+}
+
+// RecordUsage ingests a batch of usage events. Re-sending events is safe, they are
+// deduplicated by eventId. Events of already invoiced periods are listed as late and dropped.
+func (h *InternalApiHandler) RecordUsage(c echo.Context) error {
+	var req recordUsageRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	events := make([]domain.UsageEvent, 0, len(req.Events))
+	for _, e := range req.Events {
+		events = append(events, domain.UsageEvent{
+			EventID:    e.EventID,
+			UserID:     e.UserID,
+			Metric:     e.Metric,
+			Quantity:   e.Quantity,
+			OccurredAt: e.OccurredAt,
+		})
+	}
+
+	result, err := h.service.RecordUsage(c.Request().Context(), events)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, result)
+}
@@ -0,0 +1,54 @@
+// services/billing-service/internal/handler/nextcloud_sync_admin_handler.go
+package handler
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type NextcloudSyncAdminHandler struct {
+	service service.NextcloudSyncService
+}
+
+func NewNextcloudSyncAdminHandler(s service.NextcloudSyncService) *NextcloudSyncAdminHandler {
+	return &NextcloudSyncAdminHandler{service: s}
+}
+
+// GetSyncs lists outbox syncs by status, dead-lettered ones by default.
+func (h *NextcloudSyncAdminHandler) GetSyncs(c echo.Context) error {
+	status := strings.ToUpper(c.QueryParam("status"))
+	if status == "" {
+		status = domain.NextcloudSyncDead
+	}
+	limit := 0
+	if raw := c.QueryParam("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid limit"})
+		}
+	}
+
+	syncs, err := h.service.GetSyncs(c.Request().Context(), status, limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, syncs)
+}
+
+func (h *NextcloudSyncAdminHandler) ReplaySync(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("syncId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid sync id"})
+	}
+
+	sync, err := h.service.ReplaySync(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, sync)
+}
@@ -0,0 +1,42 @@
+// services/billing-service/internal/handler/over_limit_admin_handler.go
+package handler
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type OverLimitAdminHandler struct {
+	service service.OverLimitService
+}
+
+func NewOverLimitAdminHandler(s service.OverLimitService) *OverLimitAdminHandler {
+	return &OverLimitAdminHandler{service: s}
+}
+
+// GetCases lists over-limit cases by status, expired ones by default, which support follows
+// up on.
+func (h *OverLimitAdminHandler) GetCases(c echo.Context) error {
+	status := strings.ToUpper(c.QueryParam("status"))
+	if status == "" {
+		status = domain.OverLimitExpired
+	}
+	limit := 0
+	if raw := c.QueryParam("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid limit"})
+		}
+	}
+
+	cases, err := h.service.GetCases(c.Request().Context(), status, limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, cases)
+}
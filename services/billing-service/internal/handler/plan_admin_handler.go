@@ -0,0 +1,176 @@
+// services/billing-service/internal/handler/plan_admin_handler.go
+package handler
+
+import (
+	"encoding/json"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/service"
+	"jcloud-project/libs/go-common/entitlements"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PlanAdminHandler struct {
+	service service.PlanService
+}
+
+func NewPlanAdminHandler(s service.PlanService) *PlanAdminHandler {
+	return &PlanAdminHandler{service: s}
+}
+
+func (h *PlanAdminHandler) GetAllPlans(c echo.Context) error {
+	plans, err := h.service.GetAllPlans(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, plans)
+}
+
+type createPlanRequest struct {
+	Name          string                `json:"name"`
+	Prices        []domain.Money        `json:"prices"`
+	MeteredPrices []domain.MeteredPrice `json:"meteredPrices"`
+	Permissions   json.RawMessage       `json:"permissions"`
+	TrialDays     int                   `json:"trialDays"`
+}
+
+func (h *PlanAdminHandler) CreatePlan(c echo.Context) error {
+	var req createPlanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+	permissions, err := entitlements.Parse(req.Permissions)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	plan, err := h.service.CreatePlan(c.Request().Context(), &domain.SubscriptionPlan{
+		Name:          req.Name,
+		Prices:        req.Prices,
+		MeteredPrices: req.MeteredPrices,
+		Permissions:   permissions,
+		TrialDays:     req.TrialDays,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, plan)
+}
+
+type patchPlanRequest struct {
+	Name          *string                `json:"name,omitempty"`
+	Prices        *[]domain.Money        `json:"prices,omitempty"`
+	MeteredPrices *[]domain.MeteredPrice `json:"meteredPrices,omitempty"`
+	Permissions   json.RawMessage        `json:"permissions,omitempty"`
+	TrialDays     *int                   `json:"trialDays,omitempty"`
+	IsActive      *bool                  `json:"isActive,omitempty"`
+}
+
+func (h *PlanAdminHandler) PatchPlan(c echo.Context) error {
+	planID, err := strconv.ParseInt(c.Param("planId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid plan id"})
+	}
+
+	var req patchPlanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+	var permissions *entitlements.Entitlements
+	if req.Permissions != nil {
+		parsed, err := entitlements.Parse(req.Permissions)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+		}
+		permissions = &parsed
+	}
+
+	plan, err := h.service.PatchPlan(c.Request().Context(), planID, service.PlanPatch{
+		Name:          req.Name,
+		Prices:        req.Prices,
+		MeteredPrices: req.MeteredPrices,
+		Permissions:   permissions,
+		TrialDays:     req.TrialDays,
+		IsActive:      req.IsActive,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, plan)
+}
+
+func (h *PlanAdminHandler) ArchivePlan(c echo.Context) error {
+	planID, err := strconv.ParseInt(c.Param("planId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid plan id"})
+	}
+
+	if err := h.service.ArchivePlan(c.Request().Context(), planID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type reorderPlansRequest struct {
+	PlanIDs []int64 `json:"planIds"`
+}
+
+func (h *PlanAdminHandler) ReorderPlans(c echo.Context) error {
+	var req reorderPlansRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	if err := h.service.ReorderPlans(c.Request().Context(), req.PlanIDs); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *PlanAdminHandler) GetPlanVersions(c echo.Context) error {
+	planID, err := strconv.ParseInt(c.Param("planId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid plan id"})
+	}
+
+	versions, err := h.service.GetPlanVersions(c.Request().Context(), planID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, versions)
+}
+
+type scheduleVersionMigrationRequest struct {
+	EffectiveAt time.Time `json:"effectiveAt"`
+}
+
+// ScheduleVersionMigration moves subscribers of the given version to the plan's current version.
+func (h *PlanAdminHandler) ScheduleVersionMigration(c echo.Context) error {
+	planID, err := strconv.ParseInt(c.Param("planId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid plan id"})
+	}
+	versionID, err := strconv.ParseInt(c.Param("versionId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid version id"})
+	}
+
+	var req scheduleVersionMigrationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	migration, err := h.service.ScheduleVersionMigration(c.Request().Context(), planID, versionID, req.EffectiveAt)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, migration)
+}
+
+// GetEntitlementsSchema returns the JSON Schema that plan permissions are validated against.
+func (h *PlanAdminHandler) GetEntitlementsSchema(c echo.Context) error {
+	return c.JSON(http.StatusOK, entitlements.Schema())
+}
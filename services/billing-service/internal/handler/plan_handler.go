@@ -23,3 +23,11 @@ func (h *PlanHandler) GetAllPlans(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, plans)
 }
+
+func (h *PlanHandler) GetAvailableAddOns(c echo.Context) error {
+	addOns, err := h.service.GetAvailableAddOns(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, addOns)
+}
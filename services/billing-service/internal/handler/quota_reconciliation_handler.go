@@ -0,0 +1,53 @@
+// services/billing-service/internal/handler/quota_reconciliation_handler.go
+package handler
+
+import (
+	"jcloud-project/billing-service/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type QuotaReconciliationHandler struct {
+	service service.QuotaReconciliationService
+}
+
+func NewQuotaReconciliationHandler(s service.QuotaReconciliationService) *QuotaReconciliationHandler {
+	return &QuotaReconciliationHandler{service: s}
+}
+
+func (h *QuotaReconciliationHandler) GetReconciliations(c echo.Context) error {
+	limit := 0
+	if raw := c.QueryParam("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid limit"})
+		}
+	}
+
+	recs, err := h.service.GetReconciliations(c.Request().Context(), limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, recs)
+}
+
+type reconcileRequest struct {
+	// DryRun only reports discrepancies. It defaults to true, corrections must be asked for.
+	DryRun *bool `json:"dryRun"`
+}
+
+func (h *QuotaReconciliationHandler) Reconcile(c echo.Context) error {
+	var req reconcileRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+	dryRun := req.DryRun == nil || *req.DryRun
+
+	rec, err := h.service.Reconcile(c.Request().Context(), dryRun)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, rec)
+}
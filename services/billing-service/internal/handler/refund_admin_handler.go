@@ -0,0 +1,70 @@
+// services/billing-service/internal/handler/refund_admin_handler.go
+package handler
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/service"
+	commontypes "jcloud-project/libs/go-common/types/jwt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type RefundAdminHandler struct {
+	service service.BillingService
+}
+
+func NewRefundAdminHandler(s service.BillingService) *RefundAdminHandler {
+	return &RefundAdminHandler{service: s}
+}
+
+func (h *RefundAdminHandler) GetCreditNotes(c echo.Context) error {
+	invoiceID, err := strconv.ParseInt(c.Param("invoiceId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid invoice id"})
+	}
+
+	notes, err := h.service.GetCreditNotes(c.Request().Context(), invoiceID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, notes)
+}
+
+type refundInvoiceRequest struct {
+	Amount    *domain.Money `json:"amount"` // Omit to refund everything not refunded yet
+	Reason    string        `json:"reason"`
+	RefundTo  string        `json:"refundTo"`
+	Downgrade bool          `json:"downgrade"`
+}
+
+func (h *RefundAdminHandler) RefundInvoice(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	invoiceID, err := strconv.ParseInt(c.Param("invoiceId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid invoice id"})
+	}
+
+	var req refundInvoiceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+	if req.RefundTo == "" {
+		req.RefundTo = domain.RefundToWallet
+	}
+
+	note, err := h.service.RefundInvoice(c.Request().Context(), claims.UserID, invoiceID, service.RefundRequest{
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+		RefundTo:  req.RefundTo,
+		Downgrade: req.Downgrade,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, note)
+}
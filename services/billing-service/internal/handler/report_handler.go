@@ -0,0 +1,136 @@
+// services/billing-service/internal/handler/report_handler.go
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"jcloud-project/billing-service/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ReportHandler serves the admin revenue reports. Every report is JSON by default and CSV
+// with ?format=csv.
+type ReportHandler struct {
+	service service.ReportService
+}
+
+func NewReportHandler(s service.ReportService) *ReportHandler {
+	return &ReportHandler{service: s}
+}
+
+// GetMRR reports MRR, ARR and their movements per month from ?from to ?to (YYYY-MM-DD, both
+// inclusive).
+func (h *ReportHandler) GetMRR(c echo.Context) error {
+	from, to, err := reportRange(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	reports, err := h.service.GetMRRReport(c.Request().Context(), from, to)
+	if err != nil {
+		return err
+	}
+	if !wantsCSV(c) {
+		return c.JSON(http.StatusOK, reports)
+	}
+
+	rows := [][]string{{"period_start", "period_end", "currency", "starting_mrr", "new_mrr", "expansion_mrr",
+		"contraction_mrr", "churned_mrr", "ending_mrr", "ending_arr", "subscribers", "new_subscribers", "churned_subscribers"}}
+	for _, r := range reports {
+		rows = append(rows, []string{
+			r.PeriodStart.Format(time.DateOnly), r.PeriodEnd.Format(time.DateOnly), r.Currency,
+			r.StartingMRR.Decimal(), r.NewMRR.Decimal(), r.ExpansionMRR.Decimal(), r.ContractionMRR.Decimal(),
+			r.ChurnedMRR.Decimal(), r.EndingMRR.Decimal(), r.EndingARR.Decimal(),
+			strconv.Itoa(r.Subscribers), strconv.Itoa(r.NewSubscribers), strconv.Itoa(r.ChurnedSubscribers),
+		})
+	}
+	return writeCSV(c, fmt.Sprintf("mrr_%s_%s.csv", c.QueryParam("from"), c.QueryParam("to")), rows)
+}
+
+// GetPlanMix reports the users on each plan at the end of ?at (YYYY-MM-DD), or now.
+func (h *ReportHandler) GetPlanMix(c echo.Context) error {
+	at := time.Now()
+	if raw := c.QueryParam("at"); raw != "" {
+		day, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid date, expected YYYY-MM-DD"})
+		}
+		at = day.AddDate(0, 0, 1)
+	}
+
+	mix, err := h.service.GetPlanMix(c.Request().Context(), at)
+	if err != nil {
+		return err
+	}
+	if !wantsCSV(c) {
+		return c.JSON(http.StatusOK, mix)
+	}
+
+	rows := [][]string{{"plan_id", "plan_name", "currency", "active", "trialing", "mrr"}}
+	for _, e := range mix {
+		rows = append(rows, []string{
+			strconv.FormatInt(e.PlanID, 10), e.PlanName, e.Currency,
+			strconv.Itoa(e.Active), strconv.Itoa(e.Trialing), e.MRR.Decimal(),
+		})
+	}
+	return writeCSV(c, fmt.Sprintf("plan_mix_%s.csv", at.AddDate(0, 0, -1).Format(time.DateOnly)), rows)
+}
+
+// GetTrialConversions reports the outcome of the trials started from ?from to ?to
+// (YYYY-MM-DD, both inclusive) per plan.
+func (h *ReportHandler) GetTrialConversions(c echo.Context) error {
+	from, to, err := reportRange(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
+
+	conversions, err := h.service.GetTrialConversions(c.Request().Context(), from, to)
+	if err != nil {
+		return err
+	}
+	if !wantsCSV(c) {
+		return c.JSON(http.StatusOK, conversions)
+	}
+
+	rows := [][]string{{"plan_id", "plan_name", "started", "converted", "not_converted", "in_trial", "conversion_rate"}}
+	for _, t := range conversions {
+		rows = append(rows, []string{
+			strconv.FormatInt(t.PlanID, 10), t.PlanName, strconv.Itoa(t.Started), strconv.Itoa(t.Converted),
+			strconv.Itoa(t.NotConverted), strconv.Itoa(t.InTrial), strconv.FormatFloat(t.ConversionRate, 'f', 4, 64),
+		})
+	}
+	return writeCSV(c, fmt.Sprintf("trial_conversions_%s_%s.csv", c.QueryParam("from"), c.QueryParam("to")), rows)
+}
+
+// reportRange parses the required ?from and ?to days into [from, to) in UTC.
+func reportRange(c echo.Context) (time.Time, time.Time, error) {
+	from, err := time.Parse(time.DateOnly, c.QueryParam("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from, expected YYYY-MM-DD")
+	}
+	to, err := time.Parse(time.DateOnly, c.QueryParam("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to, expected YYYY-MM-DD")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+func wantsCSV(c echo.Context) bool {
+	return c.QueryParam("format") == "csv"
+}
+
+func writeCSV(c echo.Context, filename string, rows [][]string) error {
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// services/billing-service/internal/handler/storage_usage_handler.go
+package handler
+
+import (
+	"jcloud-project/billing-service/internal/service"
+	commontypes "jcloud-project/libs/go-common/types/jwt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type StorageUsageHandler struct {
+	service service.StorageUsageService
+}
+
+func NewStorageUsageHandler(s service.StorageUsageService) *StorageUsageHandler {
+	return &StorageUsageHandler{service: s}
+}
+
+func (h *StorageUsageHandler) GetStorageUsage(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	usage, err := h.service.GetStorageUsage(c.Request().Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, usage)
+}
+
+type recordVideoUsageRequest struct {
+	UsedBytes  int64     `json:"usedBytes"`
+	MeasuredAt time.Time `json:"measuredAt"`
+}
+
+// RecordVideoUsage receives the size of a user's video library from video-service. Re-sending
+// a report is safe, older measurements than the stored one are ignored.
+func (h *StorageUsageHandler) RecordVideoUsage(c echo.Context) error {
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+	var req recordVideoUsageRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	if err := h.service.RecordVideoUsage(c.Request().Context(), userID, req.UsedBytes, req.MeasuredAt); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
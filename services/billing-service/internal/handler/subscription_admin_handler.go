@@ -0,0 +1,41 @@
+// services/billing-service/internal/handler/subscription_admin_handler.go
+package handler
+
+import (
+	"jcloud-project/billing-service/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SubscriptionAdminHandler struct {
+	service service.BillingService
+}
+
+func NewSubscriptionAdminHandler(s service.BillingService) *SubscriptionAdminHandler {
+	return &SubscriptionAdminHandler{service: s}
+}
+
+func (h *SubscriptionAdminHandler) GetSubscriptionHistory(c echo.Context) error {
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	history, err := h.service.GetSubscriptionHistory(c.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, history)
+}
+
+// RepairSubscriptions puts every user without a live subscription on the default plan right
+// away instead of waiting for the repair worker.
+func (h *SubscriptionAdminHandler) RepairSubscriptions(c echo.Context) error {
+	repair, err := h.service.RepairMissingSubscriptions(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, repair)
+}
@@ -5,6 +5,7 @@ import (
 	"jcloud-project/billing-service/internal/service"
 	commontypes "jcloud-project/libs/go-common/types/jwt"
 	"net/http"
+	"strconv"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
@@ -30,8 +31,31 @@ func (h *SubscriptionHandler) GetUserSubscription(c echo.Context) error {
 	return c.JSON(http.StatusOK, subscription)
 }
 
+func (h *SubscriptionHandler) GetSubscriptionHistory(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	history, err := h.service.GetSubscriptionHistory(c.Request().Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
 type changeSubscriptionRequest struct {
-	PlanID int64 `json:"planId"`
+	PlanID     int64  `json:"planId"`
+	CouponCode string `json:"couponCode"`
+	// Currency is optional, by default it is derived from the Accept-Language header.
+	Currency string `json:"currency"`
+}
+
+// currencyPreference combines an explicitly requested currency with the client's locale.
+func currencyPreference(c echo.Context, currency string) service.CurrencyPreference {
+	return service.CurrencyPreference{
+		Currency: currency,
+		Locale:   c.Request().Header.Get("Accept-Language"),
+	}
 }
 
 func (h *SubscriptionHandler) ChangeSubscription(c echo.Context) error {
@@ -43,10 +67,166 @@ func (h *SubscriptionHandler) ChangeSubscription(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
 	}
 
-	err := h.service.ChangeSubscription(c.Request().Context(), claims.UserID, req.PlanID)
+	proration, err := h.service.ChangeSubscription(c.Request().Context(), claims.UserID, req.PlanID, service.PlanChangeOptions{
+		CouponCode:         req.CouponCode,
+		CurrencyPreference: currencyPreference(c, req.Currency),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":   "subscription updated successfully",
+		"proration": proration,
+	})
+}
+
+// PreviewSubscriptionChange returns the prorated amounts of a plan change without applying it.
+func (h *SubscriptionHandler) PreviewSubscriptionChange(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	planID, err := strconv.ParseInt(c.QueryParam("planId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid plan id"})
+	}
+
+	preview, err := h.service.PreviewSubscriptionChange(c.Request().Context(), claims.UserID, planID, service.PlanChangeOptions{
+		CouponCode:         c.QueryParam("couponCode"),
+		CurrencyPreference: currencyPreference(c, c.QueryParam("currency")),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, preview)
+}
+
+type cancelSubscriptionRequest struct {
+	// AtPeriodEnd defaults to true: the user keeps what they paid for until the period ends.
+	AtPeriodEnd *bool  `json:"atPeriodEnd"`
+	Reason      string `json:"reason"`
+	Feedback    string `json:"feedback"`
+}
+
+func (h *SubscriptionHandler) CancelSubscription(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	var req cancelSubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+	atPeriodEnd := req.AtPeriodEnd == nil || *req.AtPeriodEnd
+
+	subscription, err := h.service.CancelSubscription(c.Request().Context(), claims.UserID, atPeriodEnd, req.Reason, req.Feedback)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+func (h *SubscriptionHandler) ResumeSubscription(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	subscription, err := h.service.ResumeSubscription(c.Request().Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+type startTrialRequest struct {
+	PlanID int64 `json:"planId"`
+	// AutoConvert defaults to true: the trial continues as a paid subscription when it ends.
+	AutoConvert *bool  `json:"autoConvert"`
+	Currency    string `json:"currency"`
+}
+
+func (h *SubscriptionHandler) StartTrial(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	var req startTrialRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+	autoConvert := req.AutoConvert == nil || *req.AutoConvert
+
+	subscription, err := h.service.StartTrial(c.Request().Context(), claims.UserID, req.PlanID, autoConvert, currencyPreference(c, req.Currency))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+// GetCurrentUsage returns the metered usage of the current billing period so far.
+func (h *SubscriptionHandler) GetCurrentUsage(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	usage, err := h.service.GetCurrentUsage(c.Request().Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, usage)
+}
+
+func (h *SubscriptionHandler) GetUserAddOns(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	addOns, err := h.service.GetUserAddOns(c.Request().Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, addOns)
+}
+
+type addAddOnRequest struct {
+	AddOnID  int64 `json:"addOnId"`
+	Quantity int   `json:"quantity"` // Defaults to 1
+}
+
+func (h *SubscriptionHandler) AddAddOn(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	var req addAddOnRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	addOns, err := h.service.AddAddOn(c.Request().Context(), claims.UserID, req.AddOnID, req.Quantity)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, addOns)
+}
+
+func (h *SubscriptionHandler) RemoveAddOn(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	addOnID, err := strconv.ParseInt(c.Param("addOnId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid add-on id"})
+	}
+
+	addOns, err := h.service.RemoveAddOn(c.Request().Context(), claims.UserID, addOnID)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusOK, echo.Map{"message": "subscription updated successfully"})
+	return c.JSON(http.StatusOK, addOns)
 }
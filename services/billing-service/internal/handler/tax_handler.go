@@ -0,0 +1,111 @@
+// services/billing-service/internal/handler/tax_handler.go
+package handler
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/service"
+	commontypes "jcloud-project/libs/go-common/types/jwt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type TaxHandler struct {
+	service service.TaxService
+}
+
+func NewTaxHandler(s service.TaxService) *TaxHandler {
+	return &TaxHandler{service: s}
+}
+
+func (h *TaxHandler) GetBillingProfile(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	profile, err := h.service.GetBillingProfile(c.Request().Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, profile)
+}
+
+type billingProfileRequest struct {
+	Country     string  `json:"country"`
+	Region      string  `json:"region"`
+	IsBusiness  bool    `json:"isBusiness"`
+	CompanyName *string `json:"companyName"`
+	TaxID       *string `json:"taxId"`
+}
+
+func (h *TaxHandler) SaveBillingProfile(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	var req billingProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	profile, err := h.service.SaveBillingProfile(c.Request().Context(), &domain.BillingProfile{
+		UserID:      claims.UserID,
+		Country:     req.Country,
+		Region:      req.Region,
+		IsBusiness:  req.IsBusiness,
+		CompanyName: req.CompanyName,
+		TaxID:       req.TaxID,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, profile)
+}
+
+func (h *TaxHandler) GetTaxRates(c echo.Context) error {
+	rates, err := h.service.GetTaxRates(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, rates)
+}
+
+type saveTaxRateRequest struct {
+	Country   string `json:"country"`
+	Region    string `json:"region"`
+	Name      string `json:"name"`
+	RateBps   int64  `json:"rateBps"`
+	Inclusive bool   `json:"inclusive"`
+}
+
+// SaveTaxRate creates or replaces the rate of a country or region.
+func (h *TaxHandler) SaveTaxRate(c echo.Context) error {
+	var req saveTaxRateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	rate, err := h.service.SaveTaxRate(c.Request().Context(), &domain.TaxRate{
+		Country:   req.Country,
+		Region:    req.Region,
+		Name:      req.Name,
+		RateBps:   req.RateBps,
+		Inclusive: req.Inclusive,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, rate)
+}
+
+func (h *TaxHandler) DeleteTaxRate(c echo.Context) error {
+	rateID, err := strconv.ParseInt(c.Param("taxRateId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid tax rate id"})
+	}
+
+	if err := h.service.DeleteTaxRate(c.Request().Context(), rateID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
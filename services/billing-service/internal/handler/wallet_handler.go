@@ -0,0 +1,117 @@
+// services/billing-service/internal/handler/wallet_handler.go
+package handler
+
+import (
+	"io"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/service"
+	commontypes "jcloud-project/libs/go-common/types/jwt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// paymentSignatureHeader carries the payment provider's signature of a webhook body.
+const paymentSignatureHeader = "X-Payment-Signature"
+
+type WalletHandler struct {
+	service service.WalletService
+}
+
+func NewWalletHandler(s service.WalletService) *WalletHandler {
+	return &WalletHandler{service: s}
+}
+
+func (h *WalletHandler) GetWallet(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	balances, err := h.service.GetBalances(c.Request().Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"balances": balances})
+}
+
+func (h *WalletHandler) GetTransactions(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	limit := 0
+	if raw := c.QueryParam("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid limit"})
+		}
+	}
+
+	transactions, err := h.service.GetTransactions(c.Request().Context(), claims.UserID, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, transactions)
+}
+
+type topUpRequest struct {
+	Amount domain.Money `json:"amount"`
+}
+
+func (h *WalletHandler) StartTopUp(c echo.Context) error {
+	userToken := c.Get("user").(*jwt.Token)
+	claims := userToken.Claims.(*commontypes.JwtCustomClaims)
+
+	var req topUpRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	topUp, err := h.service.StartTopUp(c.Request().Context(), claims.UserID, req.Amount)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, topUp)
+}
+
+// HandlePaymentWebhook receives payment status updates from the payment provider. The raw
+// body is needed to verify the signature, so it is not bound.
+func (h *WalletHandler) HandlePaymentWebhook(c echo.Context) error {
+	payload, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request body"})
+	}
+
+	if err := h.service.HandlePaymentWebhook(c.Request().Context(), payload, c.Request().Header.Get(paymentSignatureHeader)); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type refundToBalanceRequest struct {
+	Amount      domain.Money `json:"amount"`
+	Description string       `json:"description"`
+	// Reference identifies the refund, e.g. a support ticket; the same reference is booked only once.
+	Reference string `json:"reference"`
+}
+
+func (h *WalletHandler) RefundToBalance(c echo.Context) error {
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid user id"})
+	}
+
+	var req refundToBalanceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	refund, err := h.service.RefundToBalance(c.Request().Context(), userID, req.Amount, req.Description, req.Reference)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, refund)
+}
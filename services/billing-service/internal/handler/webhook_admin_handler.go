@@ -0,0 +1,160 @@
+// services/billing-service/internal/handler/webhook_admin_handler.go
+package handler
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type WebhookAdminHandler struct {
+	service service.WebhookService
+}
+
+func NewWebhookAdminHandler(s service.WebhookService) *WebhookAdminHandler {
+	return &WebhookAdminHandler{service: s}
+}
+
+func (h *WebhookAdminHandler) GetEventTypes(c echo.Context) error {
+	return c.JSON(http.StatusOK, domain.WebhookEventTypes)
+}
+
+func (h *WebhookAdminHandler) GetEndpoints(c echo.Context) error {
+	endpoints, err := h.service.GetEndpoints(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, endpoints)
+}
+
+type createWebhookEndpointRequest struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	EventTypes  []string `json:"eventTypes"`
+}
+
+// CreateEndpoint registers an endpoint. The response holds its signing secret, which is not
+// shown again.
+func (h *WebhookAdminHandler) CreateEndpoint(c echo.Context) error {
+	var req createWebhookEndpointRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	endpoint, err := h.service.CreateEndpoint(c.Request().Context(), &domain.WebhookEndpoint{
+		URL:         req.URL,
+		Description: req.Description,
+		EventTypes:  req.EventTypes,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, endpoint)
+}
+
+type patchWebhookEndpointRequest struct {
+	URL         *string   `json:"url,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	EventTypes  *[]string `json:"eventTypes,omitempty"`
+	IsActive    *bool     `json:"isActive,omitempty"`
+}
+
+func (h *WebhookAdminHandler) PatchEndpoint(c echo.Context) error {
+	endpointID, err := strconv.ParseInt(c.Param("endpointId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid endpoint id"})
+	}
+
+	var req patchWebhookEndpointRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	endpoint, err := h.service.PatchEndpoint(c.Request().Context(), endpointID, service.WebhookEndpointPatch{
+		URL:         req.URL,
+		Description: req.Description,
+		EventTypes:  req.EventTypes,
+		IsActive:    req.IsActive,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, endpoint)
+}
+
+func (h *WebhookAdminHandler) DeleteEndpoint(c echo.Context) error {
+	endpointID, err := strconv.ParseInt(c.Param("endpointId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid endpoint id"})
+	}
+
+	if err := h.service.DeleteEndpoint(c.Request().Context(), endpointID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *WebhookAdminHandler) RotateSecret(c echo.Context) error {
+	endpointID, err := strconv.ParseInt(c.Param("endpointId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid endpoint id"})
+	}
+
+	endpoint, err := h.service.RotateSecret(c.Request().Context(), endpointID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, endpoint)
+}
+
+// GetDeliveries lists the newest deliveries to an endpoint, optionally filtered by status.
+func (h *WebhookAdminHandler) GetDeliveries(c echo.Context) error {
+	endpointID, err := strconv.ParseInt(c.Param("endpointId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid endpoint id"})
+	}
+	status := strings.ToUpper(c.QueryParam("status"))
+	limit := 0
+	if raw := c.QueryParam("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid limit"})
+		}
+	}
+
+	deliveries, err := h.service.GetDeliveries(c.Request().Context(), endpointID, status, limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDelivery returns a delivery with its event and the log of its attempts.
+func (h *WebhookAdminHandler) GetDelivery(c echo.Context) error {
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid delivery id"})
+	}
+
+	delivery, err := h.service.GetDelivery(c.Request().Context(), deliveryID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, delivery)
+}
+
+func (h *WebhookAdminHandler) Redeliver(c echo.Context) error {
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid delivery id"})
+	}
+
+	delivery, err := h.service.Redeliver(c.Request().Context(), deliveryID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, delivery)
+}
@@ -0,0 +1,218 @@
+// services/billing-service/internal/repository/addon_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type addOnPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAddOnPostgresRepository(db *pgxpool.Pool) AddOnRepository {
+	return &addOnPostgresRepository{db: db}
+}
+
+const addOnColumns = `a.id, a.name, a.description, a.prices, a.permission_deltas, a.is_active, a.created_at, a.updated_at`
+
+func scanAddOn(row pgx.Row, a *domain.AddOn) error {
+	return row.Scan(&a.ID, &a.Name, &a.Description, &a.Prices, &a.PermissionDeltas, &a.IsActive, &a.CreatedAt, &a.UpdatedAt)
+}
+
+func (r *addOnPostgresRepository) Create(ctx context.Context, a *domain.AddOn) error {
+	query := `
+		INSERT INTO add_ons (name, description, prices, permission_deltas, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+	err := r.db.QueryRow(ctx, query, a.Name, a.Description, a.Prices, a.PermissionDeltas, a.IsActive).
+		Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "unique constraint") {
+			return ierr.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// Update saves everything but the permission deltas, which never change.
+func (r *addOnPostgresRepository) Update(ctx context.Context, a *domain.AddOn) error {
+	query := `
+		UPDATE add_ons SET name = $1, description = $2, prices = $3, is_active = $4, updated_at = NOW()
+		WHERE id = $5
+		RETURNING updated_at`
+	err := r.db.QueryRow(ctx, query, a.Name, a.Description, a.Prices, a.IsActive, a.ID).Scan(&a.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ierr.ErrNotFound
+		}
+		if strings.Contains(err.Error(), "unique constraint") {
+			return ierr.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *addOnPostgresRepository) FindByID(ctx context.Context, id int64) (*domain.AddOn, error) {
+	query := `SELECT ` + addOnColumns + ` FROM add_ons a WHERE a.id = $1`
+	var a domain.AddOn
+	if err := scanAddOn(r.db.QueryRow(ctx, query, id), &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *addOnPostgresRepository) FindAll(ctx context.Context) ([]domain.AddOn, error) {
+	return r.findAddOns(ctx, `SELECT `+addOnColumns+` FROM add_ons a ORDER BY a.id`)
+}
+
+func (r *addOnPostgresRepository) FindAllActive(ctx context.Context) ([]domain.AddOn, error) {
+	return r.findAddOns(ctx, `SELECT `+addOnColumns+` FROM add_ons a WHERE a.is_active ORDER BY a.id`)
+}
+
+func (r *addOnPostgresRepository) findAddOns(ctx context.Context, query string) ([]domain.AddOn, error) {
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addOns []domain.AddOn
+	for rows.Next() {
+		var a domain.AddOn
+		if err := scanAddOn(rows, &a); err != nil {
+			return nil, err
+		}
+		addOns = append(addOns, a)
+	}
+	return addOns, rows.Err()
+}
+
+func (r *addOnPostgresRepository) FindByUserID(ctx context.Context, userID int64) ([]domain.UserAddOn, error) {
+	query := `
+		SELECT ua.id, ua.user_id, ua.quantity, ua.created_at, ua.updated_at, ` + addOnColumns + `
+		FROM user_add_ons ua
+		JOIN add_ons a ON a.id = ua.add_on_id
+		WHERE ua.user_id = $1
+		ORDER BY a.id`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addOns []domain.UserAddOn
+	for rows.Next() {
+		var ua domain.UserAddOn
+		a := &ua.AddOn
+		err := rows.Scan(&ua.ID, &ua.UserID, &ua.Quantity, &ua.CreatedAt, &ua.UpdatedAt,
+			&a.ID, &a.Name, &a.Description, &a.Prices, &a.PermissionDeltas, &a.IsActive, &a.CreatedAt, &a.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		addOns = append(addOns, ua)
+	}
+	return addOns, rows.Err()
+}
+
+// AddToUser gives the user `quantity` more units of the add-on, stacking on units they already have.
+func (r *addOnPostgresRepository) AddToUser(ctx context.Context, userID, addOnID int64, quantity int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO user_add_ons (user_id, add_on_id, quantity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, add_on_id) DO UPDATE
+		SET quantity = user_add_ons.quantity + EXCLUDED.quantity, updated_at = NOW()
+		RETURNING quantity`
+	var total int
+	if err := tx.QueryRow(ctx, query, userID, addOnID, quantity).Scan(&total); err != nil {
+		return err
+	}
+	if err := recordAddOnHistory(ctx, tx, userID, addOnID, total); err != nil {
+		return err
+	}
+	if err := enqueueNextcloudSync(ctx, tx, userID, domain.NextcloudSyncQuota); err != nil {
+		return err
+	}
+	if err := publishEntitlementsChanged(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *addOnPostgresRepository) RemoveFromUser(ctx context.Context, userID, addOnID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM user_add_ons WHERE user_id = $1 AND add_on_id = $2`, userID, addOnID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ierr.ErrNotFound
+	}
+	if err := recordAddOnHistory(ctx, tx, userID, addOnID, 0); err != nil {
+		return err
+	}
+	if err := enqueueNextcloudSync(ctx, tx, userID, domain.NextcloudSyncQuota); err != nil {
+		return err
+	}
+	if err := publishEntitlementsChanged(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *addOnPostgresRepository) RemoveAllFromUser(ctx context.Context, userID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		WITH removed AS (
+			DELETE FROM user_add_ons WHERE user_id = $1 RETURNING user_id, add_on_id
+		)
+		INSERT INTO user_add_on_history (user_id, add_on_id, quantity, prices)
+		SELECT r.user_id, r.add_on_id, 0, a.prices FROM removed r JOIN add_ons a ON a.id = r.add_on_id`
+	if _, err := tx.Exec(ctx, query, userID); err != nil {
+		return err
+	}
+	if err := enqueueNextcloudSync(ctx, tx, userID, domain.NextcloudSyncQuota); err != nil {
+		return err
+	}
+	if err := publishEntitlementsChanged(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// recordAddOnHistory records within tx that the user now holds `quantity` units of the add-on,
+// at its current prices.
+func recordAddOnHistory(ctx context.Context, tx pgx.Tx, userID, addOnID int64, quantity int) error {
+	query := `
+		INSERT INTO user_add_on_history (user_id, add_on_id, quantity, prices)
+		SELECT $1, id, $3, prices FROM add_ons WHERE id = $2`
+	_, err := tx.Exec(ctx, query, userID, addOnID, quantity)
+	return err
+}
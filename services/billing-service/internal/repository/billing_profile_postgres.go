@@ -0,0 +1,47 @@
+// services/billing-service/internal/repository/billing_profile_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type billingProfilePostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBillingProfilePostgresRepository(db *pgxpool.Pool) BillingProfileRepository {
+	return &billingProfilePostgresRepository{db: db}
+}
+
+func (r *billingProfilePostgresRepository) Save(ctx context.Context, p *domain.BillingProfile) error {
+	query := `
+		INSERT INTO billing_profiles (user_id, country, region, is_business, company_name, tax_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE
+		SET country = EXCLUDED.country, region = EXCLUDED.region, is_business = EXCLUDED.is_business,
+			company_name = EXCLUDED.company_name, tax_id = EXCLUDED.tax_id, updated_at = NOW()
+		RETURNING updated_at`
+	return r.db.QueryRow(ctx, query, p.UserID, p.Country, p.Region, p.IsBusiness, p.CompanyName, p.TaxID).Scan(&p.UpdatedAt)
+}
+
+func (r *billingProfilePostgresRepository) FindByUserID(ctx context.Context, userID int64) (*domain.BillingProfile, error) {
+	query := `
+		SELECT user_id, country, region, is_business, company_name, tax_id, updated_at
+		FROM billing_profiles WHERE user_id = $1`
+	var p domain.BillingProfile
+	err := r.db.QueryRow(ctx, query, userID).
+		Scan(&p.UserID, &p.Country, &p.Region, &p.IsBusiness, &p.CompanyName, &p.TaxID, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
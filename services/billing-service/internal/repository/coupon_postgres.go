@@ -0,0 +1,192 @@
+// services/billing-service/internal/repository/coupon_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type couponPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCouponPostgresRepository(db *pgxpool.Pool) CouponRepository {
+	return &couponPostgresRepository{db: db}
+}
+
+const couponColumns = `id, code, discount_type, percent_off, amount_off_minor, amount_off_currency, duration, duration_months,
+	max_redemptions, times_redeemed, expires_at, plan_ids, is_active, created_at, updated_at`
+
+func scanCoupon(row pgx.Row, c *domain.Coupon) error {
+	var amountOff *int64
+	var amountOffCurrency *string
+	err := row.Scan(&c.ID, &c.Code, &c.DiscountType, &c.PercentOff, &amountOff, &amountOffCurrency, &c.Duration, &c.DurationMonths,
+		&c.MaxRedemptions, &c.TimesRedeemed, &c.ExpiresAt, &c.PlanIDs, &c.IsActive, &c.CreatedAt, &c.UpdatedAt)
+	if err == nil && amountOff != nil && amountOffCurrency != nil {
+		m := domain.NewMoney(*amountOff, *amountOffCurrency)
+		c.AmountOff = &m
+	}
+	return err
+}
+
+// amountOffArgs splits a fixed discount into its nullable amount and currency columns.
+func amountOffArgs(c *domain.Coupon) (*int64, *string) {
+	if c.AmountOff == nil {
+		return nil, nil
+	}
+	return &c.AmountOff.Amount, &c.AmountOff.Currency
+}
+
+func (r *couponPostgresRepository) Create(ctx context.Context, c *domain.Coupon) error {
+	query := `
+		INSERT INTO coupons (code, discount_type, percent_off, amount_off_minor, amount_off_currency, duration, duration_months,
+			max_redemptions, expires_at, plan_ids, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, times_redeemed, created_at, updated_at`
+	amountOff, amountOffCurrency := amountOffArgs(c)
+	err := r.db.QueryRow(ctx, query, c.Code, c.DiscountType, c.PercentOff, amountOff, amountOffCurrency, c.Duration, c.DurationMonths,
+		c.MaxRedemptions, c.ExpiresAt, c.PlanIDs, c.IsActive).Scan(&c.ID, &c.TimesRedeemed, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "unique constraint") {
+			return ierr.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *couponPostgresRepository) Update(ctx context.Context, c *domain.Coupon) error {
+	query := `
+		UPDATE coupons
+		SET discount_type = $1, percent_off = $2, amount_off_minor = $3, amount_off_currency = $4, duration = $5,
+			duration_months = $6, max_redemptions = $7, expires_at = $8, plan_ids = $9, is_active = $10, updated_at = NOW()
+		WHERE id = $11
+		RETURNING updated_at`
+	amountOff, amountOffCurrency := amountOffArgs(c)
+	err := r.db.QueryRow(ctx, query, c.DiscountType, c.PercentOff, amountOff, amountOffCurrency, c.Duration, c.DurationMonths,
+		c.MaxRedemptions, c.ExpiresAt, c.PlanIDs, c.IsActive, c.ID).Scan(&c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ierr.ErrNotFound
+	}
+	return err
+}
+
+func (r *couponPostgresRepository) FindByID(ctx context.Context, id int64) (*domain.Coupon, error) {
+	query := `SELECT ` + couponColumns + ` FROM coupons WHERE id = $1`
+	var c domain.Coupon
+	if err := scanCoupon(r.db.QueryRow(ctx, query, id), &c); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *couponPostgresRepository) FindByCode(ctx context.Context, code string) (*domain.Coupon, error) {
+	query := `SELECT ` + couponColumns + ` FROM coupons WHERE code = $1`
+	var c domain.Coupon
+	if err := scanCoupon(r.db.QueryRow(ctx, query, code), &c); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *couponPostgresRepository) FindAll(ctx context.Context) ([]domain.Coupon, error) {
+	query := `SELECT ` + couponColumns + ` FROM coupons ORDER BY id ASC`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var coupons []domain.Coupon
+	for rows.Next() {
+		var c domain.Coupon
+		if err := scanCoupon(rows, &c); err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, c)
+	}
+	return coupons, rows.Err()
+}
+
+// redeemCoupon records the redemption within tx, counts it against the coupon's limit and
+// replaces any discount the subscription had before. Returns ierr.ErrConflict when the limit
+// is reached or the user already redeemed this coupon.
+func redeemCoupon(ctx context.Context, tx pgx.Tx, redemption *domain.CouponRedemption) error {
+	// Counting the redemption with a guarded UPDATE keeps the limit exact under concurrency.
+	tag, err := tx.Exec(ctx, `
+		UPDATE coupons SET times_redeemed = times_redeemed + 1, updated_at = NOW()
+		WHERE id = $1 AND is_active AND (max_redemptions IS NULL OR times_redeemed < max_redemptions)`,
+		redemption.CouponID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ierr.ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE coupon_redemptions SET is_active = false, ended_at = NOW() WHERE subscription_id = $1 AND is_active`,
+		redemption.SubscriptionID); err != nil {
+		return err
+	}
+
+	// A redemption used up by the plan change itself discounts the period it pays for.
+	query := `
+		INSERT INTO coupon_redemptions (coupon_id, user_id, subscription_id, periods_remaining, is_active, ended_at)
+		SELECT $1, $2, $3, $4, $5, CASE WHEN $5 THEN NULL ELSE ends_at END
+		FROM user_subscriptions WHERE id = $3
+		RETURNING id, redeemed_at`
+	err = tx.QueryRow(ctx, query, redemption.CouponID, redemption.UserID, redemption.SubscriptionID, redemption.PeriodsRemaining,
+		redemption.IsActive).Scan(&redemption.ID, &redemption.RedeemedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ierr.ErrNotFound
+		}
+		// (coupon_id, user_id) is unique: every user can redeem a coupon only once
+		if strings.Contains(err.Error(), "unique constraint") {
+			return ierr.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *couponPostgresRepository) FindActiveRedemption(ctx context.Context, subscriptionID int64) (*domain.CouponRedemption, error) {
+	query := `
+		SELECT id, coupon_id, user_id, subscription_id, periods_remaining, is_active, redeemed_at
+		FROM coupon_redemptions WHERE subscription_id = $1 AND is_active`
+	var cr domain.CouponRedemption
+	err := r.db.QueryRow(ctx, query, subscriptionID).
+		Scan(&cr.ID, &cr.CouponID, &cr.UserID, &cr.SubscriptionID, &cr.PeriodsRemaining, &cr.IsActive, &cr.RedeemedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &cr, nil
+}
+
+// consumeRedemptionPeriod uses up one invoice of a time-limited redemption within tx.
+func consumeRedemptionPeriod(ctx context.Context, tx pgx.Tx, redemptionID int64) error {
+	// The last invoice discounts the period the subscription was just renewed for.
+	query := `
+		UPDATE coupon_redemptions r
+		SET periods_remaining = r.periods_remaining - 1, is_active = r.periods_remaining > 1,
+			ended_at = CASE WHEN r.periods_remaining > 1 THEN NULL ELSE s.ends_at END
+		FROM user_subscriptions s
+		WHERE r.id = $1 AND r.periods_remaining IS NOT NULL AND s.id = r.subscription_id`
+	_, err := tx.Exec(ctx, query, redemptionID)
+	return err
+}
@@ -0,0 +1,106 @@
+// services/billing-service/internal/repository/credit_note_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type creditNotePostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCreditNotePostgresRepository(db *pgxpool.Pool) CreditNoteRepository {
+	return &creditNotePostgresRepository{db: db}
+}
+
+// Create locks the invoice so that concurrent refunds of it cannot together exceed what was paid.
+func (r *creditNotePostgresRepository) Create(ctx context.Context, n *domain.CreditNote) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var paid, credited int64
+	err = tx.QueryRow(ctx, `SELECT paid_from_balance_minor FROM invoices WHERE id = $1 FOR UPDATE`, n.InvoiceID).Scan(&paid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ierr.ErrNotFound
+		}
+		return err
+	}
+	err = tx.QueryRow(ctx, `SELECT COALESCE(SUM(amount_minor), 0)::BIGINT FROM credit_notes WHERE invoice_id = $1`, n.InvoiceID).
+		Scan(&credited)
+	if err != nil {
+		return err
+	}
+	if credited+n.Amount.Amount > paid {
+		return ierr.ErrConflict
+	}
+
+	if n.TopUpID != nil {
+		tag, err := tx.Exec(ctx, `
+			UPDATE wallet_top_ups SET refunded_minor = refunded_minor + $1
+			WHERE id = $2 AND amount_minor - refunded_minor >= $1`,
+			n.Amount.Amount, *n.TopUpID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ierr.ErrConflict
+		}
+	}
+
+	// The ledger transaction references the credit note, so its ID is reserved up front.
+	if err := tx.QueryRow(ctx, `SELECT nextval('credit_notes_id_seq')`).Scan(&n.ID); err != nil {
+		return err
+	}
+	refund := n.LedgerTransaction()
+	if err := insertLedgerTransaction(ctx, tx, refund); err != nil {
+		return err
+	}
+	n.LedgerTransactionID = refund.ID
+
+	query := `
+		INSERT INTO credit_notes (id, invoice_id, user_id, amount_minor, tax_minor, currency, reason, refund_to, top_up_id,
+			provider_refund_id, ledger_transaction_id, issued_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING created_at`
+	err = tx.QueryRow(ctx, query, n.ID, n.InvoiceID, n.UserID, n.Amount.Amount, n.Tax.Amount, n.Amount.Currency, n.Reason,
+		n.RefundTo, n.TopUpID, n.ProviderRefundID, n.LedgerTransactionID, n.IssuedBy).Scan(&n.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *creditNotePostgresRepository) FindByInvoiceID(ctx context.Context, invoiceID int64) ([]domain.CreditNote, error) {
+	query := `
+		SELECT id, invoice_id, user_id, amount_minor, tax_minor, currency, reason, refund_to, top_up_id, provider_refund_id,
+			ledger_transaction_id, issued_by, created_at
+		FROM credit_notes WHERE invoice_id = $1 ORDER BY id`
+	rows, err := r.db.Query(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []domain.CreditNote{}
+	for rows.Next() {
+		var n domain.CreditNote
+		err := rows.Scan(&n.ID, &n.InvoiceID, &n.UserID, &n.Amount.Amount, &n.Tax.Amount, &n.Amount.Currency, &n.Reason,
+			&n.RefundTo, &n.TopUpID, &n.ProviderRefundID, &n.LedgerTransactionID, &n.IssuedBy, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		n.Tax.Currency = n.Amount.Currency
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
@@ -0,0 +1,22 @@
+// services/billing-service/internal/repository/entitlements_postgres.go
+package repository
+
+import (
+	"context"
+	"jcloud-project/libs/go-common/entitlements"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// publishEntitlementsChanged tells the other services' entitlement caches that the user's
+// permissions changed. Postgres delivers the notification when tx commits, and drops it if
+// tx is rolled back.
+func publishEntitlementsChanged(ctx context.Context, tx pgx.Tx, userID int64) error {
+	return notifyEntitlements(ctx, tx, strconv.FormatInt(userID, 10))
+}
+
+func notifyEntitlements(ctx context.Context, tx pgx.Tx, payload string) error {
+	_, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, entitlements.Channel, payload)
+	return err
+}
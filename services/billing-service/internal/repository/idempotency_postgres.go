@@ -0,0 +1,69 @@
+// services/billing-service/internal/repository/idempotency_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type idempotencyPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdempotencyPostgresRepository(db *pgxpool.Pool) IdempotencyRepository {
+	return &idempotencyPostgresRepository{db: db}
+}
+
+func (r *idempotencyPostgresRepository) Reserve(ctx context.Context, scope, key, requestHash string, expiredBefore, abandonedBefore time.Time) (*domain.IdempotencyRecord, error) {
+	query := `
+		INSERT INTO idempotency_keys (scope, key, request_hash) VALUES ($1, $2, $3)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL, response_body = NULL, created_at = NOW()
+		WHERE idempotency_keys.created_at < $4 OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < $5)
+		RETURNING key`
+	var reserved string
+	err := r.db.QueryRow(ctx, query, scope, key, requestHash, expiredBefore, abandonedBefore).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	// The key is taken, return what it was used for.
+	rec := domain.IdempotencyRecord{Scope: scope, Key: key}
+	var contentType *string
+	err = r.db.QueryRow(ctx, `
+		SELECT request_hash, status_code, content_type, response_body, created_at
+		FROM idempotency_keys WHERE scope = $1 AND key = $2`, scope, key).
+		Scan(&rec.RequestHash, &rec.StatusCode, &contentType, &rec.ResponseBody, &rec.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Released in between, report it as still in progress and let the caller retry.
+		rec.RequestHash = requestHash
+		return &rec, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if contentType != nil {
+		rec.ContentType = *contentType
+	}
+	return &rec, nil
+}
+
+func (r *idempotencyPostgresRepository) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE idempotency_keys SET status_code = $3, content_type = $4, response_body = $5
+		WHERE scope = $1 AND key = $2`, scope, key, statusCode, contentType, body)
+	return err
+}
+
+func (r *idempotencyPostgresRepository) Release(ctx context.Context, scope, key string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status_code IS NULL`, scope, key)
+	return err
+}
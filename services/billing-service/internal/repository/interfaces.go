@@ -8,14 +8,259 @@ import (
 )
 
 type PlanRepository interface {
+	Create(ctx context.Context, plan *domain.SubscriptionPlan) error
+	Update(ctx context.Context, plan *domain.SubscriptionPlan, newVersion bool) error
 	FindByID(ctx context.Context, id int64) (*domain.SubscriptionPlan, error)
 	FindByName(ctx context.Context, name string) (*domain.SubscriptionPlan, error)
 	FindAllActive(ctx context.Context) ([]domain.SubscriptionPlan, error)
+	FindAll(ctx context.Context) ([]domain.SubscriptionPlan, error)
+	Reorder(ctx context.Context, planIDs []int64) error
+	FindVersionByID(ctx context.Context, id int64) (*domain.PlanVersion, error)
+	FindVersionReports(ctx context.Context, planID int64) ([]domain.PlanVersionReport, error)
+	CreateVersionMigration(ctx context.Context, migration *domain.PlanVersionMigration) error
+	FindDueVersionMigrations(ctx context.Context, now time.Time) ([]domain.PlanVersionMigration, error)
+	CompleteVersionMigration(ctx context.Context, id int64) error
+	// FindNextcloudGroups returns every Nextcloud group any version of any plan grants.
+	FindNextcloudGroups(ctx context.Context) ([]string, error)
 }
 
 type SubscriptionRepository interface {
-	Create(ctx context.Context, userID, planID int64) error
-	Update(ctx context.Context, userID, newPlanID int64, newEndDate time.Time) error
+	// Create and Update record the change in the subscription history, request Nextcloud quota
+	// and group syncs, publish an entitlement change and emit a subscription webhook event in
+	// the same transaction. Update skips all of them for bookkeeping writes, which pass a nil change.
+	// Create fails with ierr.ErrConflict if the user already has a live subscription.
+	Create(ctx context.Context, userID, planID int64, currency string, change domain.SubscriptionChange) error
+	Update(ctx context.Context, sub *domain.UserSubscription, change *domain.SubscriptionChange) error
+	// UpdateWith is Update with further writes in the same transaction, so that a change is
+	// never stored without what it was billed with.
+	UpdateWith(ctx context.Context, sub *domain.UserSubscription, change domain.SubscriptionChange, writes SubscriptionWrites) error
+	// Replace updates an ended subscription and creates one on planID for the same user and
+	// currency in one transaction, so the user is never left without a live subscription.
+	// final, if set, is the invoice for the ended subscription's last usage and is stored with it.
+	Replace(ctx context.Context, ended *domain.UserSubscription, change domain.SubscriptionChange, final *domain.Invoice, planID int64, created domain.SubscriptionChange) error
+	// ReplaceUnpaid is Replace for a past-due subscription that was never paid. Its open
+	// invoices are voided in the same transaction, what the balance paid of them is refunded.
+	ReplaceUnpaid(ctx context.Context, ended *domain.UserSubscription, change domain.SubscriptionChange, final *domain.Invoice, planID int64, created domain.SubscriptionChange) error
+	// FindByUserID returns the user's live subscription, which is active, trialing or past due.
+	FindByUserID(ctx context.Context, userID int64) (*domain.UserSubscription, error)
+	// FindByUserIDs returns the live subscriptions of those of userIDs that have one.
+	FindByUserIDs(ctx context.Context, userIDs []int64) ([]domain.UserSubscription, error)
+	FindDueForRenewal(ctx context.Context, now time.Time) ([]domain.UserSubscription, error)
+	// FindPastDueSince returns the past-due subscriptions whose unpaid period started before `before`.
+	FindPastDueSince(ctx context.Context, before time.Time) ([]domain.UserSubscription, error)
+	FindTrialsEndingBefore(ctx context.Context, before time.Time) ([]domain.UserSubscription, error)
 	FindDetailsByUserID(ctx context.Context, userID int64) (*domain.UserSubscriptionDetails, error)
 	FindPermissionsByUserID(ctx context.Context, userID int64) (*domain.SubscriptionPlan, error)
+	FindUserIDsByPlanVersion(ctx context.Context, planVersionID int64) ([]int64, error)
+	// FindLiveUserIDs returns the users with a live subscription.
+	FindLiveUserIDs(ctx context.Context) ([]int64, error)
+	// FindUserIDsWithoutLiveSubscription returns those of userIDs without a live subscription.
+	FindUserIDsWithoutLiveSubscription(ctx context.Context, userIDs []int64) ([]int64, error)
+	// MigratePlanVersion re-pins every live subscription from one plan version to another,
+	// requests Nextcloud syncs and emits webhook events for them, publishes an entitlement
+	// change for all users and returns the affected user IDs.
+	MigratePlanVersion(ctx context.Context, fromVersionID, toVersionID int64) ([]int64, error)
+	// FindHistoryByUserID returns the history of all of the user's subscriptions, newest first.
+	FindHistoryByUserID(ctx context.Context, userID int64) ([]domain.SubscriptionHistoryEntry, error)
+}
+
+// SubscriptionWrites are the rows SubscriptionRepository.UpdateWith stores with a subscription.
+type SubscriptionWrites struct {
+	// Invoice is issued for the change, paid from the balance like InvoiceRepository.Create does.
+	Invoice *domain.Invoice
+	// Redemption is a coupon redeemed with the change. It counts against the coupon's limit
+	// and replaces any discount the subscription had before; UpdateWith fails with
+	// ierr.ErrConflict when the limit is reached or the user already redeemed the coupon.
+	Redemption *domain.CouponRedemption
+	// ConsumedRedemptionID is a time-limited redemption the invoice used up one period of.
+	ConsumedRedemptionID *int64
+	// PastDueIfUnpaid makes the subscription past due when the balance does not cover Invoice.
+	// The invoice stays open until a top-up settles it.
+	PastDueIfUnpaid bool
+	// Trial is the trial started with the change. UpdateWith fails with ierr.ErrConflict when
+	// the user or the email already had one.
+	Trial *domain.TrialRedemption
+}
+
+type InvoiceRepository interface {
+	// Create stores the invoice and pays as much of it as the user's balance in its currency
+	// covers, booking the payment to the ledger in the same transaction. Invoices the balance
+	// covers in full are stored as paid.
+	Create(ctx context.Context, invoice *domain.Invoice) error
+	FindByID(ctx context.Context, id int64) (*domain.Invoice, error)
+}
+
+type TrialRepository interface {
+	// Exists reports whether a trial was already started by this user or with this email.
+	Exists(ctx context.Context, userID int64, email string) (bool, error)
+	// Trials are recorded with SubscriptionRepository.UpdateWith.
+}
+
+type CouponRepository interface {
+	Create(ctx context.Context, coupon *domain.Coupon) error
+	Update(ctx context.Context, coupon *domain.Coupon) error
+	FindByID(ctx context.Context, id int64) (*domain.Coupon, error)
+	FindByCode(ctx context.Context, code string) (*domain.Coupon, error)
+	FindAll(ctx context.Context) ([]domain.Coupon, error)
+	// Coupons are redeemed and their periods used up with SubscriptionRepository.UpdateWith.
+	FindActiveRedemption(ctx context.Context, subscriptionID int64) (*domain.CouponRedemption, error)
+}
+
+type TaxRateRepository interface {
+	// Save creates or replaces the rate of the rate's country and region.
+	Save(ctx context.Context, rate *domain.TaxRate) error
+	Delete(ctx context.Context, id int64) error
+	// FindForLocation returns the rate of the region, or the country-wide rate if there is none.
+	FindForLocation(ctx context.Context, country, region string) (*domain.TaxRate, error)
+	FindAll(ctx context.Context) ([]domain.TaxRate, error)
+}
+
+type BillingProfileRepository interface {
+	Save(ctx context.Context, profile *domain.BillingProfile) error
+	FindByUserID(ctx context.Context, userID int64) (*domain.BillingProfile, error)
+}
+
+type UsageRepository interface {
+	// Record stores usage events, skipping already recorded event IDs, and returns how many were new.
+	Record(ctx context.Context, events []domain.UsageEvent) (int, error)
+	SumByMetric(ctx context.Context, userID int64, from, to time.Time) (map[string]domain.Quantity, error)
+}
+
+type AddOnRepository interface {
+	Create(ctx context.Context, addOn *domain.AddOn) error
+	Update(ctx context.Context, addOn *domain.AddOn) error
+	FindByID(ctx context.Context, id int64) (*domain.AddOn, error)
+	FindAll(ctx context.Context) ([]domain.AddOn, error)
+	FindAllActive(ctx context.Context) ([]domain.AddOn, error)
+	FindByUserID(ctx context.Context, userID int64) ([]domain.UserAddOn, error)
+	// AddToUser, RemoveFromUser and RemoveAllFromUser request a quota sync and publish an
+	// entitlement change in the same transaction.
+	AddToUser(ctx context.Context, userID, addOnID int64, quantity int) error
+	RemoveFromUser(ctx context.Context, userID, addOnID int64) error
+	RemoveAllFromUser(ctx context.Context, userID int64) error
+}
+
+type WalletRepository interface {
+	// Post books a balanced ledger transaction. Returns ierr.ErrConflict if its reference was
+	// already booked or it would make a wallet balance negative.
+	Post(ctx context.Context, t *domain.LedgerTransaction) error
+	Balance(ctx context.Context, userID int64, currency string) (domain.Money, error)
+	// Balances returns the user's balance in every currency they ever had money in.
+	Balances(ctx context.Context, userID int64) ([]domain.Money, error)
+	// FindWalletTransactions returns the latest changes of the user's balance, newest first.
+	FindWalletTransactions(ctx context.Context, userID int64, limit int) ([]domain.WalletTransaction, error)
+	CreateTopUp(ctx context.Context, topUp *domain.WalletTopUp) error
+	FindTopUp(ctx context.Context, id int64) (*domain.WalletTopUp, error)
+	// CompleteTopUp marks a pending top-up as succeeded, books the credit and settles the open
+	// invoices the new balance covers, oldest first, in one transaction. It reports false
+	// without booking anything if the top-up was no longer pending and returns the IDs of the
+	// settled invoices.
+	CompleteTopUp(ctx context.Context, id int64, providerPaymentID string, credit *domain.LedgerTransaction) (bool, []int64, error)
+	FailTopUp(ctx context.Context, id int64, providerPaymentID string) error
+	// FindRefundableTopUp returns the user's latest provider payment that still has `amount`
+	// left to pay back.
+	FindRefundableTopUp(ctx context.Context, userID int64, amount domain.Money) (*domain.WalletTopUp, error)
+}
+
+type CreditNoteRepository interface {
+	// Create stores the credit note and books its refund. Returns ierr.ErrConflict if the
+	// invoice's credit notes would exceed what was paid for it, or the top-up it is paid back
+	// to has not enough left.
+	Create(ctx context.Context, note *domain.CreditNote) error
+	FindByInvoiceID(ctx context.Context, invoiceID int64) ([]domain.CreditNote, error)
+}
+
+type NextcloudOutboxRepository interface {
+	Enqueue(ctx context.Context, userID int64, kind string) error
+	// Claim leases up to `limit` due pending syncs for `lease`, skipping those leased by others.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.NextcloudSync, error)
+	// MarkDelivered completes a sync, unless it was requested again after it was claimed.
+	MarkDelivered(ctx context.Context, id, generation int64) error
+	// MarkFailed counts a failed attempt and schedules the next one, or gives up if dead is set.
+	MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time, dead bool) error
+	FindByStatus(ctx context.Context, status string, limit int) ([]domain.NextcloudSync, error)
+	// Replay makes a dead sync pending again. Returns ierr.ErrConflict if the user already has
+	// a pending sync of the same kind.
+	Replay(ctx context.Context, id int64) (*domain.NextcloudSync, error)
+}
+
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) error
+	UpdateEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) error
+	SetEndpointSecret(ctx context.Context, id int64, secret string) error
+	DeleteEndpoint(ctx context.Context, id int64) error
+	FindEndpointByID(ctx context.Context, id int64) (*domain.WebhookEndpoint, error)
+	FindAllEndpoints(ctx context.Context) ([]domain.WebhookEndpoint, error)
+	// ClaimDeliveries leases up to `limit` due pending deliveries for `lease`, skipping those
+	// leased by others. They come with their event and the endpoint's secret.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error)
+	// MarkDelivered logs a successful attempt and completes its delivery.
+	MarkDelivered(ctx context.Context, attempt *domain.WebhookDeliveryAttempt) error
+	// MarkFailed logs a failed attempt and schedules the next one, or gives up if dead is set.
+	MarkFailed(ctx context.Context, attempt *domain.WebhookDeliveryAttempt, nextAttemptAt time.Time, dead bool) error
+	FindDeliveries(ctx context.Context, endpointID int64, status string, limit int) ([]domain.WebhookDelivery, error)
+	FindDeliveryByID(ctx context.Context, id int64) (*domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, id int64) error
+}
+
+type StorageUsageRepository interface {
+	// FindByUserID returns the user's last fetched Nextcloud usage with their video bytes.
+	FindByUserID(ctx context.Context, userID int64) (*domain.StorageUsage, error)
+	// Save stores freshly fetched Nextcloud usage, keeping the warning level already reached.
+	Save(ctx context.Context, usage *domain.StorageUsage) error
+	// FindVideoBytes returns the user's last reported video bytes, 0 if none were reported.
+	FindVideoBytes(ctx context.Context, userID int64) (int64, error)
+	// SaveVideoBytes stores the user's video bytes measured at measuredAt. It reports false if
+	// a later measurement is stored already, so reports arriving out of order are ignored.
+	SaveVideoBytes(ctx context.Context, userID, usedBytes int64, measuredAt time.Time) (bool, error)
+	// SetWarningLevel moves the user's warning level from `from` to `to`. It reports false if
+	// the level was no longer `from`, i.e. another refresh already handled the crossing.
+	SetWarningLevel(ctx context.Context, userID int64, from, to int) (bool, error)
+}
+
+// OverLimitRepository keeps the cases of users who store more than their quota. Opening and
+// resolving a case change the user's entitlements, which is published in the same transaction.
+type OverLimitRepository interface {
+	// FindUnresolvedByUserID returns the user's open or expired case.
+	FindUnresolvedByUserID(ctx context.Context, userID int64) (*domain.OverLimitCase, error)
+	// Open records a new case. It reports false if the user already has an unresolved one.
+	Open(ctx context.Context, c *domain.OverLimitCase) (bool, error)
+	// Resolve closes the user's unresolved case and returns it.
+	Resolve(ctx context.Context, userID int64) (*domain.OverLimitCase, error)
+	// FindReminderDue returns the open cases whose grace period ends before `before` and whose
+	// user was not reminded yet.
+	FindReminderDue(ctx context.Context, before time.Time) ([]domain.OverLimitCase, error)
+	// MarkReminderSent reports false if the reminder of the case was already sent.
+	MarkReminderSent(ctx context.Context, id int64) (bool, error)
+	// ExpireDue marks the open cases whose grace period ended before `now` as expired and
+	// returns them.
+	ExpireDue(ctx context.Context, now time.Time) ([]domain.OverLimitCase, error)
+	// FindByStatus returns the newest cases, of every status if status is empty.
+	FindByStatus(ctx context.Context, status string, limit int) ([]domain.OverLimitCase, error)
+}
+
+// IdempotencyRepository stores the requests made with an idempotency key and their responses.
+type IdempotencyRepository interface {
+	// Reserve claims the key for a new request. The key is free if it was never used, if it
+	// was used before expiredBefore or if its request is still in progress since before
+	// abandonedBefore. It returns nil once claimed, and the existing record otherwise.
+	Reserve(ctx context.Context, scope, key, requestHash string, expiredBefore, abandonedBefore time.Time) (*domain.IdempotencyRecord, error)
+	// Complete stores the response of the request that reserved the key.
+	Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error
+	// Release frees a reserved key whose request failed, so that a retry runs it again.
+	Release(ctx context.Context, scope, key string) error
+}
+
+type QuotaReconciliationRepository interface {
+	Create(ctx context.Context, rec *domain.QuotaReconciliation) error
+	// FindLatest returns the most recent reports, newest first.
+	FindLatest(ctx context.Context, limit int) ([]domain.QuotaReconciliation, error)
+}
+
+// ReportRepository reads the subscription history for revenue reports.
+type ReportRepository interface {
+	// FindSnapshotsAt returns the subscriptions that were active or trialing just before `at`.
+	FindSnapshotsAt(ctx context.Context, at time.Time) ([]domain.SubscriptionSnapshot, error)
+	// FindTrialConversions counts the outcome of the trials started in [from, to) per plan.
+	FindTrialConversions(ctx context.Context, from, to time.Time) ([]domain.TrialConversion, error)
 }
@@ -0,0 +1,291 @@
+// services/billing-service/internal/repository/invoice_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type invoicePostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewInvoicePostgresRepository(db *pgxpool.Pool) InvoiceRepository {
+	return &invoicePostgresRepository{db: db}
+}
+
+// Create stores the invoice together with its lines in a single transaction.
+func (r *invoicePostgresRepository) Create(ctx context.Context, invoice *domain.Invoice) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertInvoice(ctx, tx, invoice); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// insertInvoice stores a taxed invoice with its lines within tx and pays as much of it as the
+// user's balance in its currency covers. The balance is read under the same lock that
+// serializes all debits of it, so the payment cannot fail. Invoices the balance covers in full
+// are stored as paid and emit invoice.paid.
+func insertInvoice(ctx context.Context, tx pgx.Tx, invoice *domain.Invoice) error {
+	currency := invoice.Total.Currency
+	invoice.PaidFromBalance = domain.NewMoney(0, currency)
+	if invoice.Total.IsPositive() {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, invoice.UserID); err != nil {
+			return err
+		}
+		var balance int64
+		if err := tx.QueryRow(ctx, walletBalanceQuery, invoice.UserID, currency).Scan(&balance); err != nil {
+			return err
+		}
+		if balance > 0 {
+			invoice.PaidFromBalance = domain.NewMoney(balance, currency).Min(invoice.Total)
+		}
+	}
+	if invoice.PaidFromBalance.Cmp(invoice.Total) >= 0 {
+		invoice.Status = domain.InvoiceStatusPaid
+	}
+
+	query := `
+		INSERT INTO invoices (user_id, subscription_id, status, subtotal_minor, total_minor, currency,
+			tax_name, tax_country, tax_region, tax_rate_bps, tax_inclusive, tax_reverse_charge, customer_tax_id,
+			tax_taxable_minor, tax_minor, paid_from_balance_minor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id, created_at`
+	args := []interface{}{invoice.UserID, invoice.SubscriptionID, invoice.Status,
+		invoice.Subtotal.Amount, invoice.Total.Amount, invoice.Total.Currency}
+	if t := invoice.Tax; t != nil {
+		args = append(args, t.Name, t.Country, t.Region, t.RateBps, t.Inclusive, t.ReverseCharge, t.CustomerTaxID,
+			t.Taxable.Amount, t.Amount.Amount)
+	} else {
+		args = append(args, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	}
+	args = append(args, invoice.PaidFromBalance.Amount)
+	if err := tx.QueryRow(ctx, query, args...).Scan(&invoice.ID, &invoice.CreatedAt); err != nil {
+		return err
+	}
+
+	lineQuery := `
+		INSERT INTO invoice_lines (invoice_id, kind, description, amount_minor, coupon_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		line.InvoiceID = invoice.ID
+		if err := tx.QueryRow(ctx, lineQuery, line.InvoiceID, line.Kind, line.Description, line.Amount.Amount, line.CouponID).Scan(&line.ID); err != nil {
+			return err
+		}
+	}
+
+	if invoice.PaidFromBalance.IsPositive() {
+		payment := domain.NewTransfer(invoice.UserID, domain.LedgerTransactionInvoicePayment,
+			fmt.Sprintf("Payment of invoice %d", invoice.ID), fmt.Sprintf("invoice:%d", invoice.ID),
+			domain.LedgerAccountWallet, domain.LedgerAccountReceivables, invoice.PaidFromBalance)
+		if err := insertLedgerTransaction(ctx, tx, payment); err != nil {
+			return err
+		}
+	}
+	if invoice.Status == domain.InvoiceStatusPaid {
+		if err := enqueueWebhookEvent(ctx, tx, domain.WebhookEventInvoicePaid, &invoice.UserID, invoice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settleOpenInvoices pays the user's open invoices in currency from their balance within tx,
+// oldest first, until the balance no longer covers the rest of the next one. Settled invoices
+// emit invoice.paid, and a past-due subscription with nothing left open becomes active again.
+func settleOpenInvoices(ctx context.Context, tx pgx.Tx, userID int64, currency string) ([]int64, error) {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, userID); err != nil {
+		return nil, err
+	}
+	var balance int64
+	if err := tx.QueryRow(ctx, walletBalanceQuery, userID, currency).Scan(&balance); err != nil {
+		return nil, err
+	}
+
+	type openInvoice struct{ id, due int64 }
+	rows, err := tx.Query(ctx, `
+		SELECT id, total_minor - paid_from_balance_minor FROM invoices
+		WHERE user_id = $1 AND currency = $2 AND status = 'OPEN'
+		ORDER BY created_at, id
+		FOR UPDATE`, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	var open []openInvoice
+	for rows.Next() {
+		var o openInvoice
+		if err := rows.Scan(&o.id, &o.due); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		open = append(open, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var settled []int64
+	for _, o := range open {
+		if o.due > balance {
+			break
+		}
+		payment := domain.NewTransfer(userID, domain.LedgerTransactionInvoicePayment,
+			fmt.Sprintf("Payment of invoice %d", o.id), fmt.Sprintf("invoice:%d:settlement", o.id),
+			domain.LedgerAccountWallet, domain.LedgerAccountReceivables, domain.NewMoney(o.due, currency))
+		if err := insertLedgerTransaction(ctx, tx, payment); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE invoices SET status = 'PAID', paid_from_balance_minor = total_minor WHERE id = $1`, o.id); err != nil {
+			return nil, err
+		}
+		invoice, err := findInvoice(ctx, tx, o.id)
+		if err != nil {
+			return nil, err
+		}
+		if err := enqueueWebhookEvent(ctx, tx, domain.WebhookEventInvoicePaid, &userID, invoice); err != nil {
+			return nil, err
+		}
+		balance -= o.due
+		settled = append(settled, o.id)
+	}
+
+	if len(settled) > 0 {
+		if err := reactivatePaidSubscription(ctx, tx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return settled, nil
+}
+
+// voidOpenInvoices voids the subscription's open invoices within tx. What the balance already
+// paid of them goes back to the wallet.
+func voidOpenInvoices(ctx context.Context, tx pgx.Tx, subscriptionID int64) error {
+	query := `
+		UPDATE invoices SET status = 'VOID'
+		WHERE subscription_id = $1 AND status = 'OPEN'
+		RETURNING id, user_id, paid_from_balance_minor, currency`
+	rows, err := tx.Query(ctx, query, subscriptionID)
+	if err != nil {
+		return err
+	}
+	var refunds []*domain.LedgerTransaction
+	for rows.Next() {
+		var id, userID, paid int64
+		var currency string
+		if err := rows.Scan(&id, &userID, &paid, &currency); err != nil {
+			rows.Close()
+			return err
+		}
+		if paid > 0 {
+			refunds = append(refunds, domain.NewTransfer(userID, domain.LedgerTransactionRefund,
+				fmt.Sprintf("Refund of voided invoice %d", id), fmt.Sprintf("invoice:%d:void", id),
+				domain.LedgerAccountReceivables, domain.LedgerAccountWallet, domain.NewMoney(paid, currency)))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, refund := range refunds {
+		if err := insertLedgerTransaction(ctx, tx, refund); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invoiceQuerier is what loading an invoice needs, the pool and transactions both provide it.
+type invoiceQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// FindByID returns the invoice with its lines.
+func (r *invoicePostgresRepository) FindByID(ctx context.Context, id int64) (*domain.Invoice, error) {
+	return findInvoice(ctx, r.db, id)
+}
+
+func findInvoice(ctx context.Context, q invoiceQuerier, id int64) (*domain.Invoice, error) {
+	query := `
+		SELECT id, user_id, subscription_id, status, subtotal_minor, total_minor, paid_from_balance_minor, currency,
+			tax_name, tax_country, tax_region, tax_rate_bps, tax_inclusive, tax_reverse_charge, customer_tax_id,
+			tax_taxable_minor, tax_minor, created_at
+		FROM invoices WHERE id = $1`
+	var inv domain.Invoice
+	var subtotal, total, paidFromBalance int64
+	var taxName, taxCountry, taxRegion, customerTaxID *string
+	var taxRate, taxTaxable, taxAmount *int64
+	var taxInclusive, taxReverseCharge *bool
+	err := q.QueryRow(ctx, query, id).Scan(&inv.ID, &inv.UserID, &inv.SubscriptionID, &inv.Status, &subtotal, &total,
+		&paidFromBalance, &inv.Total.Currency, &taxName, &taxCountry, &taxRegion, &taxRate, &taxInclusive, &taxReverseCharge,
+		&customerTaxID, &taxTaxable, &taxAmount, &inv.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	currency := inv.Total.Currency
+	inv.Subtotal = domain.NewMoney(subtotal, currency)
+	inv.Total = domain.NewMoney(total, currency)
+	inv.PaidFromBalance = domain.NewMoney(paidFromBalance, currency)
+	if taxName != nil {
+		inv.Tax = &domain.TaxLine{
+			Name:          *taxName,
+			Country:       deref(taxCountry),
+			Region:        deref(taxRegion),
+			RateBps:       derefInt(taxRate),
+			Inclusive:     taxInclusive != nil && *taxInclusive,
+			ReverseCharge: taxReverseCharge != nil && *taxReverseCharge,
+			CustomerTaxID: customerTaxID,
+			Taxable:       domain.NewMoney(derefInt(taxTaxable), currency),
+			Amount:        domain.NewMoney(derefInt(taxAmount), currency),
+		}
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT id, invoice_id, kind, description, amount_minor, coupon_id
+		FROM invoice_lines WHERE invoice_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		line := domain.InvoiceLine{Amount: domain.NewMoney(0, currency)}
+		if err := rows.Scan(&line.ID, &line.InvoiceID, &line.Kind, &line.Description, &line.Amount.Amount, &line.CouponID); err != nil {
+			return nil, err
+		}
+		inv.Lines = append(inv.Lines, line)
+	}
+	return &inv, rows.Err()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefInt(n *int64) int64 {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
@@ -0,0 +1,136 @@
+// services/billing-service/internal/repository/nextcloud_outbox_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type nextcloudOutboxPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNextcloudOutboxPostgresRepository(db *pgxpool.Pool) NextcloudOutboxRepository {
+	return &nextcloudOutboxPostgresRepository{db: db}
+}
+
+// enqueueNextcloudSyncQuery requests a sync, merging it into the user's pending one if there is.
+const enqueueNextcloudSyncQuery = `
+	INSERT INTO nextcloud_outbox (user_id, kind) VALUES ($1, $2)
+	ON CONFLICT (user_id, kind) WHERE status = 'PENDING' DO UPDATE
+	SET generation = nextcloud_outbox.generation + 1, next_attempt_at = NOW(), updated_at = NOW()`
+
+// subscriptionSyncKinds are the syncs a change of a user's subscription requires.
+var subscriptionSyncKinds = []string{domain.NextcloudSyncQuota, domain.NextcloudSyncGroups}
+
+// enqueueNextcloudSync requests syncs as part of tx, so they are only sent if the change they
+// reflect is committed.
+func enqueueNextcloudSync(ctx context.Context, tx pgx.Tx, userID int64, kinds ...string) error {
+	for _, kind := range kinds {
+		if _, err := tx.Exec(ctx, enqueueNextcloudSyncQuery, userID, kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *nextcloudOutboxPostgresRepository) Enqueue(ctx context.Context, userID int64, kind string) error {
+	_, err := r.db.Exec(ctx, enqueueNextcloudSyncQuery, userID, kind)
+	return err
+}
+
+const nextcloudSyncColumns = `id, user_id, kind, status, attempts, generation, next_attempt_at, last_error, created_at,
+	updated_at, delivered_at`
+
+func scanNextcloudSync(row pgx.Row, s *domain.NextcloudSync) error {
+	return row.Scan(&s.ID, &s.UserID, &s.Kind, &s.Status, &s.Attempts, &s.Generation, &s.NextAttemptAt, &s.LastError,
+		&s.CreatedAt, &s.UpdatedAt, &s.DeliveredAt)
+}
+
+func collectNextcloudSyncs(rows pgx.Rows, err error) ([]domain.NextcloudSync, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	syncs := []domain.NextcloudSync{}
+	for rows.Next() {
+		var s domain.NextcloudSync
+		if err := scanNextcloudSync(rows, &s); err != nil {
+			return nil, err
+		}
+		syncs = append(syncs, s)
+	}
+	return syncs, rows.Err()
+}
+
+// Claim leases up to `limit` due syncs by pushing their next attempt `lease` into the future.
+// Syncs claimed by another worker are skipped; if a worker dies, its syncs are due again
+// once the lease runs out.
+func (r *nextcloudOutboxPostgresRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.NextcloudSync, error) {
+	query := `
+		UPDATE nextcloud_outbox SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond', updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM nextcloud_outbox
+			WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + nextcloudSyncColumns
+	return collectNextcloudSyncs(r.db.Query(ctx, query, limit, lease.Milliseconds()))
+}
+
+// MarkDelivered completes the sync unless it was requested again since it was claimed; it
+// then stays pending and is delivered once more.
+func (r *nextcloudOutboxPostgresRepository) MarkDelivered(ctx context.Context, id, generation int64) error {
+	query := `
+		UPDATE nextcloud_outbox SET status = 'DELIVERED', delivered_at = NOW(), last_error = NULL, updated_at = NOW()
+		WHERE id = $1 AND generation = $2 AND status = 'PENDING'`
+	_, err := r.db.Exec(ctx, query, id, generation)
+	return err
+}
+
+func (r *nextcloudOutboxPostgresRepository) MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := domain.NextcloudSyncPending
+	if dead {
+		status = domain.NextcloudSyncDead
+	}
+	query := `
+		UPDATE nextcloud_outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3, status = $4, updated_at = NOW()
+		WHERE id = $1 AND status = 'PENDING'`
+	_, err := r.db.Exec(ctx, query, id, lastError, nextAttemptAt, status)
+	return err
+}
+
+func (r *nextcloudOutboxPostgresRepository) FindByStatus(ctx context.Context, status string, limit int) ([]domain.NextcloudSync, error) {
+	query := `SELECT ` + nextcloudSyncColumns + ` FROM nextcloud_outbox WHERE status = $1 ORDER BY updated_at DESC LIMIT $2`
+	return collectNextcloudSyncs(r.db.Query(ctx, query, status, limit))
+}
+
+// Replay makes a dead sync pending again with a fresh set of attempts.
+func (r *nextcloudOutboxPostgresRepository) Replay(ctx context.Context, id int64) (*domain.NextcloudSync, error) {
+	query := `
+		UPDATE nextcloud_outbox SET status = 'PENDING', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'DEAD'
+		RETURNING ` + nextcloudSyncColumns
+	var s domain.NextcloudSync
+	if err := scanNextcloudSync(r.db.QueryRow(ctx, query, id), &s); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		// The user already has a pending sync of this kind, which makes the replay pointless.
+		if strings.Contains(err.Error(), "unique constraint") {
+			return nil, ierr.ErrConflict
+		}
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,142 @@
+// services/billing-service/internal/repository/over_limit_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type overLimitPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOverLimitPostgresRepository(db *pgxpool.Pool) OverLimitRepository {
+	return &overLimitPostgresRepository{db: db}
+}
+
+const overLimitColumns = `id, user_id, status, used_bytes, quota_gb, grace_ends_at, reminder_sent_at, opened_at, expired_at, resolved_at`
+
+func scanOverLimitCase(row pgx.Row, c *domain.OverLimitCase) error {
+	return row.Scan(&c.ID, &c.UserID, &c.Status, &c.UsedBytes, &c.QuotaGB, &c.GraceEndsAt, &c.ReminderSentAt,
+		&c.OpenedAt, &c.ExpiredAt, &c.ResolvedAt)
+}
+
+func collectOverLimitCases(rows pgx.Rows, err error) ([]domain.OverLimitCase, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cases := []domain.OverLimitCase{}
+	for rows.Next() {
+		var c domain.OverLimitCase
+		if err := scanOverLimitCase(rows, &c); err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, rows.Err()
+}
+
+func (r *overLimitPostgresRepository) FindUnresolvedByUserID(ctx context.Context, userID int64) (*domain.OverLimitCase, error) {
+	query := `SELECT ` + overLimitColumns + ` FROM over_limit_cases WHERE user_id = $1 AND status <> $2`
+	var c domain.OverLimitCase
+	if err := scanOverLimitCase(r.db.QueryRow(ctx, query, userID, domain.OverLimitResolved), &c); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *overLimitPostgresRepository) Open(ctx context.Context, c *domain.OverLimitCase) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO over_limit_cases (user_id, status, used_bytes, quota_gb, grace_ends_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) WHERE status <> 'RESOLVED' DO NOTHING
+		RETURNING id, opened_at`
+	err = tx.QueryRow(ctx, query, c.UserID, domain.OverLimitOpen, c.UsedBytes, c.QuotaGB, c.GraceEndsAt).Scan(&c.ID, &c.OpenedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	c.Status = domain.OverLimitOpen
+
+	if err := publishEntitlementsChanged(ctx, tx, c.UserID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *overLimitPostgresRepository) Resolve(ctx context.Context, userID int64) (*domain.OverLimitCase, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE over_limit_cases SET status = $2, resolved_at = NOW()
+		WHERE user_id = $1 AND status <> $2
+		RETURNING ` + overLimitColumns
+	var c domain.OverLimitCase
+	if err := scanOverLimitCase(tx.QueryRow(ctx, query, userID, domain.OverLimitResolved), &c); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := publishEntitlementsChanged(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	return &c, tx.Commit(ctx)
+}
+
+func (r *overLimitPostgresRepository) FindReminderDue(ctx context.Context, before time.Time) ([]domain.OverLimitCase, error) {
+	query := `
+		SELECT ` + overLimitColumns + ` FROM over_limit_cases
+		WHERE status = $1 AND reminder_sent_at IS NULL AND grace_ends_at <= $2
+		ORDER BY grace_ends_at`
+	return collectOverLimitCases(r.db.Query(ctx, query, domain.OverLimitOpen, before))
+}
+
+func (r *overLimitPostgresRepository) MarkReminderSent(ctx context.Context, id int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE over_limit_cases SET reminder_sent_at = NOW() WHERE id = $1 AND reminder_sent_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *overLimitPostgresRepository) ExpireDue(ctx context.Context, now time.Time) ([]domain.OverLimitCase, error) {
+	query := `
+		UPDATE over_limit_cases SET status = $1, expired_at = $3
+		WHERE status = $2 AND grace_ends_at <= $3
+		RETURNING ` + overLimitColumns
+	return collectOverLimitCases(r.db.Query(ctx, query, domain.OverLimitExpired, domain.OverLimitOpen, now))
+}
+
+func (r *overLimitPostgresRepository) FindByStatus(ctx context.Context, status string, limit int) ([]domain.OverLimitCase, error) {
+	query := `
+		SELECT ` + overLimitColumns + ` FROM over_limit_cases
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY opened_at DESC, id DESC
+		LIMIT $2`
+	return collectOverLimitCases(r.db.Query(ctx, query, status, limit))
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"jcloud-project/billing-service/internal/domain"
 	"jcloud-project/libs/go-common/ierr"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -19,11 +21,212 @@ func NewPlanPostgresRepository(db *pgxpool.Pool) PlanRepository {
 	return &planPostgresRepository{db: db}
 }
 
+// pricesOf aggregates the price list of a plan version into a JSON array of planPrice.
+func pricesOf(versionColumn string) string {
+	return `COALESCE((SELECT json_agg(json_build_object('amount_minor', pp.amount_minor, 'currency', pp.currency) ORDER BY pp.currency)
+		FROM plan_prices pp WHERE pp.plan_version_id = ` + versionColumn + `), '[]')`
+}
+
+var planColumns = `p.id, p.name, ` + pricesOf("p.current_version_id") + `,
+	(SELECT v.metered_prices FROM plan_versions v WHERE v.id = p.current_version_id), p.permissions, p.trial_days, p.sort_order,
+	p.current_version_id, p.is_active, p.created_at, p.updated_at`
+
+// planPrice is a row of plan_prices as aggregated by pricesOf.
+type planPrice struct {
+	AmountMinor int64  `json:"amount_minor"`
+	Currency    string `json:"currency"`
+}
+
+func toMoney(prices []planPrice) []domain.Money {
+	list := make([]domain.Money, 0, len(prices))
+	for _, p := range prices {
+		list = append(list, domain.NewMoney(p.AmountMinor, p.Currency))
+	}
+	return list
+}
+
+func scanPlan(row pgx.Row, p *domain.SubscriptionPlan) error {
+	var prices []planPrice
+	err := row.Scan(&p.ID, &p.Name, &prices, &p.MeteredPrices, &p.Permissions, &p.TrialDays, &p.SortOrder, &p.CurrentVersionID,
+		&p.IsActive, &p.CreatedAt, &p.UpdatedAt)
+	p.Prices = toMoney(prices)
+	return err
+}
+
+// Create stores the plan together with its first version.
+func (r *planPostgresRepository) Create(ctx context.Context, p *domain.SubscriptionPlan) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO subscription_plans (name, permissions, trial_days, sort_order, is_active)
+		VALUES ($1, $2, $3, COALESCE((SELECT MAX(sort_order) + 1 FROM subscription_plans), 0), $4)
+		RETURNING id, sort_order, created_at, updated_at`
+	err = tx.QueryRow(ctx, query, p.Name, p.Permissions, p.TrialDays, p.IsActive).
+		Scan(&p.ID, &p.SortOrder, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "unique constraint") {
+			return ierr.ErrConflict
+		}
+		return err
+	}
+
+	if err := insertPlanVersion(ctx, tx, p); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Update saves the plan. With newVersion set, prices and permissions are stored as a new
+// version that becomes the plan's current one; older versions are never modified.
+func (r *planPostgresRepository) Update(ctx context.Context, p *domain.SubscriptionPlan, newVersion bool) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE subscription_plans
+		SET name = $1, trial_days = $2, is_active = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING updated_at`
+	err = tx.QueryRow(ctx, query, p.Name, p.TrialDays, p.IsActive, p.ID).Scan(&p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ierr.ErrNotFound
+		}
+		if strings.Contains(err.Error(), "unique constraint") {
+			return ierr.ErrConflict
+		}
+		return err
+	}
+
+	if newVersion {
+		if err := insertPlanVersion(ctx, tx, p); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// insertPlanVersion snapshots the plan's prices, metered prices and permissions as its next version.
+func insertPlanVersion(ctx context.Context, tx pgx.Tx, p *domain.SubscriptionPlan) error {
+	query := `
+		INSERT INTO plan_versions (plan_id, version, permissions, metered_prices)
+		VALUES ($1, COALESCE((SELECT MAX(version) + 1 FROM plan_versions WHERE plan_id = $1), 1), $2, $3)
+		RETURNING id`
+	if p.MeteredPrices == nil {
+		p.MeteredPrices = []domain.MeteredPrice{}
+	}
+	if err := tx.QueryRow(ctx, query, p.ID, p.Permissions, p.MeteredPrices).Scan(&p.CurrentVersionID); err != nil {
+		return err
+	}
+
+	for _, price := range p.Prices {
+		_, err := tx.Exec(ctx, `INSERT INTO plan_prices (plan_version_id, currency, amount_minor) VALUES ($1, $2, $3)`,
+			p.CurrentVersionID, price.Currency, price.Amount)
+		if err != nil {
+			if strings.Contains(err.Error(), "unique constraint") {
+				return ierr.ErrConflict
+			}
+			return err
+		}
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE subscription_plans SET permissions = $1, current_version_id = $2, updated_at = NOW()
+		WHERE id = $3`, p.Permissions, p.CurrentVersionID, p.ID)
+	return err
+}
+
+func (r *planPostgresRepository) FindVersionByID(ctx context.Context, id int64) (*domain.PlanVersion, error) {
+	query := `SELECT v.id, v.plan_id, v.version, ` + pricesOf("v.id") + `, v.metered_prices, v.permissions, v.created_at
+		FROM plan_versions v WHERE v.id = $1`
+	var v domain.PlanVersion
+	var prices []planPrice
+	err := r.db.QueryRow(ctx, query, id).Scan(&v.ID, &v.PlanID, &v.Version, &prices, &v.MeteredPrices, &v.Permissions, &v.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	v.Prices = toMoney(prices)
+	return &v, nil
+}
+
+func (r *planPostgresRepository) FindVersionReports(ctx context.Context, planID int64) ([]domain.PlanVersionReport, error) {
+	query := `
+		SELECT v.id, v.plan_id, v.version, ` + pricesOf("v.id") + `, v.metered_prices, v.permissions, v.created_at,
+			COUNT(s.id) FILTER (WHERE s.status IN ('ACTIVE', 'TRIALING', 'PAST_DUE'))
+		FROM plan_versions v
+		LEFT JOIN user_subscriptions s ON s.plan_version_id = v.id
+		WHERE v.plan_id = $1
+		GROUP BY v.id
+		ORDER BY v.version ASC`
+	rows, err := r.db.Query(ctx, query, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []domain.PlanVersionReport
+	for rows.Next() {
+		var v domain.PlanVersionReport
+		var prices []planPrice
+		if err := rows.Scan(&v.ID, &v.PlanID, &v.Version, &prices, &v.MeteredPrices, &v.Permissions, &v.CreatedAt, &v.Subscribers); err != nil {
+			return nil, err
+		}
+		v.Prices = toMoney(prices)
+		reports = append(reports, v)
+	}
+	return reports, rows.Err()
+}
+
+func (r *planPostgresRepository) CreateVersionMigration(ctx context.Context, m *domain.PlanVersionMigration) error {
+	query := `
+		INSERT INTO plan_version_migrations (plan_id, from_version_id, to_version_id, effective_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	return r.db.QueryRow(ctx, query, m.PlanID, m.FromVersionID, m.ToVersionID, m.EffectiveAt).Scan(&m.ID, &m.CreatedAt)
+}
+
+func (r *planPostgresRepository) FindDueVersionMigrations(ctx context.Context, now time.Time) ([]domain.PlanVersionMigration, error) {
+	query := `
+		SELECT id, plan_id, from_version_id, to_version_id, effective_at, completed_at, created_at
+		FROM plan_version_migrations
+		WHERE completed_at IS NULL AND effective_at <= $1
+		ORDER BY effective_at ASC`
+	rows, err := r.db.Query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var migrations []domain.PlanVersionMigration
+	for rows.Next() {
+		var m domain.PlanVersionMigration
+		if err := rows.Scan(&m.ID, &m.PlanID, &m.FromVersionID, &m.ToVersionID, &m.EffectiveAt, &m.CompletedAt, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, rows.Err()
+}
+
+func (r *planPostgresRepository) CompleteVersionMigration(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE plan_version_migrations SET completed_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
 func (r *planPostgresRepository) FindByID(ctx context.Context, id int64) (*domain.SubscriptionPlan, error) {
-	query := `SELECT id, name, price, permissions, is_active FROM subscription_plans WHERE id = $1`
+	query := `SELECT ` + planColumns + ` FROM subscription_plans p WHERE p.id = $1`
 	var p domain.SubscriptionPlan
-	err := r.db.QueryRow(ctx, query, id).Scan(&p.ID, &p.Name, &p.Price, &p.Permissions, &p.IsActive)
-	if err != nil {
+	if err := scanPlan(r.db.QueryRow(ctx, query, id), &p); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ierr.ErrNotFound
 		}
@@ -33,10 +236,9 @@ func (r *planPostgresRepository) FindByID(ctx context.Context, id int64) (*domai
 }
 
 func (r *planPostgresRepository) FindByName(ctx context.Context, name string) (*domain.SubscriptionPlan, error) {
-	query := `SELECT id, name, price, permissions, is_active FROM subscription_plans WHERE name = $1`
+	query := `SELECT ` + planColumns + ` FROM subscription_plans p WHERE p.name = $1`
 	var p domain.SubscriptionPlan
-	err := r.db.QueryRow(ctx, query, name).Scan(&p.ID, &p.Name, &p.Price, &p.Permissions, &p.IsActive)
-	if err != nil {
+	if err := scanPlan(r.db.QueryRow(ctx, query, name), &p); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ierr.ErrNotFound
 		}
@@ -46,8 +248,17 @@ func (r *planPostgresRepository) FindByName(ctx context.Context, name string) (*
 }
 
 func (r *planPostgresRepository) FindAllActive(ctx context.Context) ([]domain.SubscriptionPlan, error) {
-	query := `SELECT id, name, price, permissions, is_active FROM subscription_plans WHERE is_active = true ORDER BY price ASC`
-	rows, err := r.db.Query(ctx, query)
+	query := `SELECT ` + planColumns + ` FROM subscription_plans p WHERE p.is_active = true ORDER BY p.sort_order ASC, p.id ASC`
+	return r.findMany(ctx, query)
+}
+
+func (r *planPostgresRepository) FindAll(ctx context.Context) ([]domain.SubscriptionPlan, error) {
+	query := `SELECT ` + planColumns + ` FROM subscription_plans p ORDER BY p.sort_order ASC, p.id ASC`
+	return r.findMany(ctx, query)
+}
+
+func (r *planPostgresRepository) findMany(ctx context.Context, query string, args ...interface{}) ([]domain.SubscriptionPlan, error) {
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,10 +267,52 @@ func (r *planPostgresRepository) FindAllActive(ctx context.Context) ([]domain.Su
 	var plans []domain.SubscriptionPlan
 	for rows.Next() {
 		var p domain.SubscriptionPlan
-		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Permissions, &p.IsActive); err != nil {
+		if err := scanPlan(rows, &p); err != nil {
 			return nil, err
 		}
 		plans = append(plans, p)
 	}
-	return plans, nil
+	return plans, rows.Err()
+}
+
+// Reorder assigns sort_order by position in planIDs, in a single transaction.
+func (r *planPostgresRepository) Reorder(ctx context.Context, planIDs []int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for i, id := range planIDs {
+		tag, err := tx.Exec(ctx, `UPDATE subscription_plans SET sort_order = $1, updated_at = NOW() WHERE id = $2`, i, id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ierr.ErrNotFound
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *planPostgresRepository) FindNextcloudGroups(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT jsonb_array_elements_text(permissions->'nextcloud_groups') AS grp
+		FROM plan_versions WHERE jsonb_typeof(permissions->'nextcloud_groups') = 'array'
+		ORDER BY grp`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []string
+	for rows.Next() {
+		var group string
+		if err := rows.Scan(&group); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
 }
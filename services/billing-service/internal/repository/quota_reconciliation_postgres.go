@@ -0,0 +1,51 @@
+// services/billing-service/internal/repository/quota_reconciliation_postgres.go
+package repository
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type quotaReconciliationPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewQuotaReconciliationPostgresRepository(db *pgxpool.Pool) QuotaReconciliationRepository {
+	return &quotaReconciliationPostgresRepository{db: db}
+}
+
+func (r *quotaReconciliationPostgresRepository) Create(ctx context.Context, rec *domain.QuotaReconciliation) error {
+	query := `
+		INSERT INTO quota_reconciliations (dry_run, checked, in_sync, drifted, corrected, unmanaged, missing, failed,
+			discrepancies, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id`
+	return r.db.QueryRow(ctx, query, rec.DryRun, rec.Checked, rec.InSync, rec.Drifted, rec.Corrected, rec.Unmanaged,
+		rec.Missing, rec.Failed, rec.Discrepancies, rec.StartedAt, rec.FinishedAt).Scan(&rec.ID)
+}
+
+func (r *quotaReconciliationPostgresRepository) FindLatest(ctx context.Context, limit int) ([]domain.QuotaReconciliation, error) {
+	query := `
+		SELECT id, dry_run, checked, in_sync, drifted, corrected, unmanaged, missing, failed, discrepancies, started_at,
+			finished_at
+		FROM quota_reconciliations ORDER BY started_at DESC LIMIT $1`
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recs := []domain.QuotaReconciliation{}
+	for rows.Next() {
+		var rec domain.QuotaReconciliation
+		err := rows.Scan(&rec.ID, &rec.DryRun, &rec.Checked, &rec.InSync, &rec.Drifted, &rec.Corrected, &rec.Unmanaged,
+			&rec.Missing, &rec.Failed, &rec.Discrepancies, &rec.StartedAt, &rec.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
@@ -0,0 +1,155 @@
+// services/billing-service/internal/repository/report_postgres.go
+package repository
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/domain"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type reportPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReportPostgresRepository(db *pgxpool.Pool) ReportRepository {
+	return &reportPostgresRepository{db: db}
+}
+
+// FindSnapshotsAt returns every subscription that was active or trialing just before `at`,
+// in the state of its last history entry before then. Subscriptions older than the history
+// itself (013_subscription_history.sql) appear from their start on in their state at that time.
+// The MRR is net of the coupon that discounted the subscription at `at`, rounded like invoices,
+// and includes the add-ons the user held at `at` at the prices they had then.
+func (r *reportPostgresRepository) FindSnapshotsAt(ctx context.Context, at time.Time) ([]domain.SubscriptionSnapshot, error) {
+	query := `
+		SELECT s.subscription_id, s.user_id, s.plan_id, p.name, s.status, s.currency,
+			CASE WHEN s.status = 'ACTIVE'
+				THEN GREATEST(COALESCE(pp.amount_minor, 0) - COALESCE(d.discount_minor, 0), 0)
+				ELSE 0 END
+		FROM (
+			SELECT DISTINCT ON (subscription_id) subscription_id, user_id, plan_id, plan_version_id, status, currency
+			FROM subscription_history
+			WHERE created_at < $1
+			ORDER BY subscription_id, created_at DESC, id DESC
+		) s
+		JOIN subscription_plans p ON p.id = s.plan_id
+		LEFT JOIN plan_prices pp ON pp.plan_version_id = s.plan_version_id AND pp.currency = s.currency
+		LEFT JOIN LATERAL (
+			SELECT CASE c.discount_type
+				WHEN 'PERCENT' THEN ROUND(COALESCE(pp.amount_minor, 0) * c.percent_off / 100)::BIGINT
+				WHEN 'FIXED' THEN CASE WHEN c.amount_off_currency = s.currency THEN c.amount_off_minor ELSE 0 END
+				ELSE 0 END AS discount_minor
+			FROM coupon_redemptions cr
+			JOIN coupons c ON c.id = cr.coupon_id
+			WHERE cr.subscription_id = s.subscription_id AND cr.redeemed_at < $1
+				AND (cr.ended_at IS NULL OR cr.ended_at >= $1)
+				AND (cardinality(c.plan_ids) = 0 OR s.plan_id = ANY (c.plan_ids))
+			ORDER BY cr.redeemed_at DESC
+			LIMIT 1
+		) d ON TRUE
+		WHERE s.status IN ('ACTIVE', 'TRIALING')
+		ORDER BY s.subscription_id`
+	rows, err := r.db.Query(ctx, query, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []domain.SubscriptionSnapshot{}
+	for rows.Next() {
+		var s domain.SubscriptionSnapshot
+		if err := rows.Scan(&s.SubscriptionID, &s.UserID, &s.PlanID, &s.PlanName, &s.Status, &s.MRR.Currency, &s.MRR.Amount); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	addOns, err := r.findAddOnsAt(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		s := &snapshots[i]
+		if s.Status != domain.SubscriptionStatusActive {
+			continue
+		}
+		for _, ua := range addOns[s.UserID] {
+			if price, ok := ua.AddOn.PriceIn(s.MRR.Currency); ok {
+				s.MRR = s.MRR.Add(price.Prorate(int64(ua.Quantity), 1))
+			}
+		}
+	}
+	return snapshots, nil
+}
+
+// findAddOnsAt returns the add-ons each user held just before `at`, with the prices they had
+// then. Only UserID, Quantity and the add-on's ID and Prices are set.
+func (r *reportPostgresRepository) findAddOnsAt(ctx context.Context, at time.Time) (map[int64][]domain.UserAddOn, error) {
+	query := `
+		SELECT user_id, add_on_id, quantity, prices FROM (
+			SELECT DISTINCT ON (user_id, add_on_id) user_id, add_on_id, quantity, prices
+			FROM user_add_on_history
+			WHERE created_at < $1
+			ORDER BY user_id, add_on_id, created_at DESC, id DESC
+		) h
+		WHERE quantity > 0`
+	rows, err := r.db.Query(ctx, query, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addOns := make(map[int64][]domain.UserAddOn)
+	for rows.Next() {
+		var ua domain.UserAddOn
+		if err := rows.Scan(&ua.UserID, &ua.AddOn.ID, &ua.Quantity, &ua.AddOn.Prices); err != nil {
+			return nil, err
+		}
+		addOns[ua.UserID] = append(addOns[ua.UserID], ua)
+	}
+	return addOns, rows.Err()
+}
+
+// FindTrialConversions counts, per plan, the outcome of the trials started in [from, to). A
+// trial's outcome is the first state of its subscription after it that is no longer trialing:
+// it converted if that is a paid period.
+func (r *reportPostgresRepository) FindTrialConversions(ctx context.Context, from, to time.Time) ([]domain.TrialConversion, error) {
+	query := `
+		SELECT t.plan_id, p.name, COUNT(*),
+			COUNT(*) FILTER (WHERE o.status = 'ACTIVE' AND COALESCE(pp.amount_minor, 0) > 0),
+			COUNT(*) FILTER (WHERE o.status IS NOT NULL AND NOT (o.status = 'ACTIVE' AND COALESCE(pp.amount_minor, 0) > 0)),
+			COUNT(*) FILTER (WHERE o.status IS NULL)
+		FROM subscription_history t
+		JOIN subscription_plans p ON p.id = t.plan_id
+		LEFT JOIN LATERAL (
+			SELECT h.status, h.plan_version_id, h.currency FROM subscription_history h
+			WHERE h.subscription_id = t.subscription_id AND h.status <> 'TRIALING'
+				AND (h.created_at, h.id) > (t.created_at, t.id)
+			ORDER BY h.created_at, h.id
+			LIMIT 1
+		) o ON TRUE
+		LEFT JOIN plan_prices pp ON pp.plan_version_id = o.plan_version_id AND pp.currency = o.currency
+		WHERE t.event = $1 AND t.created_at >= $2 AND t.created_at < $3
+		GROUP BY t.plan_id, p.name
+		ORDER BY t.plan_id`
+	rows, err := r.db.Query(ctx, query, domain.SubscriptionEventTrialStarted, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversions := []domain.TrialConversion{}
+	for rows.Next() {
+		var c domain.TrialConversion
+		if err := rows.Scan(&c.PlanID, &c.PlanName, &c.Started, &c.Converted, &c.NotConverted, &c.InTrial); err != nil {
+			return nil, err
+		}
+		conversions = append(conversions, c)
+	}
+	return conversions, rows.Err()
+}
@@ -0,0 +1,84 @@
+// services/billing-service/internal/repository/storage_usage_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type storageUsagePostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewStorageUsagePostgresRepository(db *pgxpool.Pool) StorageUsageRepository {
+	return &storageUsagePostgresRepository{db: db}
+}
+
+func (r *storageUsagePostgresRepository) FindByUserID(ctx context.Context, userID int64) (*domain.StorageUsage, error) {
+	query := `
+		SELECT s.user_id, s.used_bytes, s.free_bytes, COALESCE(v.used_bytes, 0), s.quota_gb, s.used_percent,
+			s.warning_level, s.fetched_at
+		FROM storage_usage s
+		LEFT JOIN video_storage_usage v ON v.user_id = s.user_id
+		WHERE s.user_id = $1`
+	var u domain.StorageUsage
+	err := r.db.QueryRow(ctx, query, userID).Scan(&u.UserID, &u.UsedBytes, &u.FreeBytes, &u.VideoBytes, &u.QuotaGB,
+		&u.UsedPercent, &u.WarningLevel, &u.FetchedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *storageUsagePostgresRepository) Save(ctx context.Context, usage *domain.StorageUsage) error {
+	query := `
+		INSERT INTO storage_usage (user_id, used_bytes, free_bytes, quota_gb, used_percent, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE
+		SET used_bytes = EXCLUDED.used_bytes, free_bytes = EXCLUDED.free_bytes, quota_gb = EXCLUDED.quota_gb,
+			used_percent = EXCLUDED.used_percent, fetched_at = EXCLUDED.fetched_at
+		RETURNING warning_level`
+	return r.db.QueryRow(ctx, query, usage.UserID, usage.UsedBytes, usage.FreeBytes, usage.QuotaGB, usage.UsedPercent,
+		usage.FetchedAt).Scan(&usage.WarningLevel)
+}
+
+func (r *storageUsagePostgresRepository) FindVideoBytes(ctx context.Context, userID int64) (int64, error) {
+	var usedBytes int64
+	err := r.db.QueryRow(ctx, `SELECT used_bytes FROM video_storage_usage WHERE user_id = $1`, userID).Scan(&usedBytes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return usedBytes, err
+}
+
+func (r *storageUsagePostgresRepository) SaveVideoBytes(ctx context.Context, userID, usedBytes int64, measuredAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO video_storage_usage (user_id, used_bytes, measured_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET used_bytes = EXCLUDED.used_bytes, measured_at = EXCLUDED.measured_at
+		WHERE video_storage_usage.measured_at < EXCLUDED.measured_at`
+	tag, err := r.db.Exec(ctx, query, userID, usedBytes, measuredAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *storageUsagePostgresRepository) SetWarningLevel(ctx context.Context, userID int64, from, to int) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE storage_usage SET warning_level = $3 WHERE user_id = $1 AND warning_level = $2`,
+		userID, from, to)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
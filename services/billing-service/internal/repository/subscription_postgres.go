@@ -4,8 +4,11 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/entitlements"
 	"jcloud-project/libs/go-common/ierr"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -20,30 +23,308 @@ func NewSubscriptionPostgresRepository(db *pgxpool.Pool) SubscriptionRepository
 	return &subscriptionPostgresRepository{db: db}
 }
 
-func (r *subscriptionPostgresRepository) Create(ctx context.Context, userID, planID int64) error {
+func (r *subscriptionPostgresRepository) Create(ctx context.Context, userID, planID int64, currency string, change domain.SubscriptionChange) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createSubscription(ctx, tx, userID, planID, currency, change); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *subscriptionPostgresRepository) Update(ctx context.Context, sub *domain.UserSubscription, change *domain.SubscriptionChange) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateSubscription(ctx, tx, sub, change); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *subscriptionPostgresRepository) UpdateWith(ctx context.Context, sub *domain.UserSubscription, change domain.SubscriptionChange, writes SubscriptionWrites) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateSubscription(ctx, tx, sub, &change); err != nil {
+		return err
+	}
+	if writes.Trial != nil {
+		if err := insertTrialRedemption(ctx, tx, writes.Trial); err != nil {
+			return err
+		}
+	}
+	if writes.Redemption != nil {
+		if err := redeemCoupon(ctx, tx, writes.Redemption); err != nil {
+			return err
+		}
+	}
+	if writes.Invoice != nil {
+		if err := insertInvoice(ctx, tx, writes.Invoice); err != nil {
+			return err
+		}
+	}
+	if writes.ConsumedRedemptionID != nil {
+		if err := consumeRedemptionPeriod(ctx, tx, *writes.ConsumedRedemptionID); err != nil {
+			return err
+		}
+	}
+	if writes.PastDueIfUnpaid && writes.Invoice != nil && writes.Invoice.Status != domain.InvoiceStatusPaid {
+		sub.Status = domain.SubscriptionStatusPastDue
+		pastDue := domain.ChangeBySystem(domain.SubscriptionEventPastDue,
+			fmt.Sprintf("Invoice %d not covered by the balance", writes.Invoice.ID))
+		if err := updateSubscription(ctx, tx, sub, &pastDue); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *subscriptionPostgresRepository) Replace(ctx context.Context, ended *domain.UserSubscription, change domain.SubscriptionChange, final *domain.Invoice, planID int64, created domain.SubscriptionChange) error {
+	return r.replace(ctx, ended, change, final, planID, created, false)
+}
+
+func (r *subscriptionPostgresRepository) ReplaceUnpaid(ctx context.Context, ended *domain.UserSubscription, change domain.SubscriptionChange, final *domain.Invoice, planID int64, created domain.SubscriptionChange) error {
+	return r.replace(ctx, ended, change, final, planID, created, true)
+}
+
+func (r *subscriptionPostgresRepository) replace(ctx context.Context, ended *domain.UserSubscription, change domain.SubscriptionChange, final *domain.Invoice, planID int64, created domain.SubscriptionChange, voidOpen bool) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateSubscription(ctx, tx, ended, &change); err != nil {
+		return err
+	}
+	if voidOpen {
+		if err := voidOpenInvoices(ctx, tx, ended.ID); err != nil {
+			return err
+		}
+	}
+	if final != nil {
+		if err := insertInvoice(ctx, tx, final); err != nil {
+			return err
+		}
+	}
+	if err := createSubscription(ctx, tx, ended.UserID, planID, ended.Currency, created); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func createSubscription(ctx context.Context, tx pgx.Tx, userID, planID int64, currency string, change domain.SubscriptionChange) error {
 	query := `
-		INSERT INTO user_subscriptions (user_id, plan_id, status, starts_at, ends_at)
-		VALUES ($1, $2, 'ACTIVE', NOW(), NOW() + INTERVAL '100 year')`
-	_, err := r.db.Exec(ctx, query, userID, planID)
-	return err
+		INSERT INTO user_subscriptions (user_id, plan_id, plan_version_id, status, starts_at, ends_at, currency)
+		SELECT $1, id, current_version_id, 'ACTIVE', NOW(), NOW() + INTERVAL '100 year', $3
+		FROM subscription_plans WHERE id = $2
+		RETURNING id`
+	var id int64
+	if err := tx.QueryRow(ctx, query, userID, planID, currency).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ierr.ErrNotFound
+		}
+		if strings.Contains(err.Error(), "unique constraint") {
+			return fmt.Errorf("user %d already has a live subscription: %w", userID, ierr.ErrConflict)
+		}
+		return err
+	}
+	if err := recordSubscriptionHistory(ctx, tx, id, change); err != nil {
+		return err
+	}
+	if err := enqueueNextcloudSync(ctx, tx, userID, subscriptionSyncKinds...); err != nil {
+		return err
+	}
+	if err := publishEntitlementsChanged(ctx, tx, userID); err != nil {
+		return err
+	}
+	return publishSubscriptionWebhook(ctx, tx, id, change)
 }
 
-func (r *subscriptionPostgresRepository) Update(ctx context.Context, userID, newPlanID int64, newEndDate time.Time) error {
+func updateSubscription(ctx context.Context, tx pgx.Tx, sub *domain.UserSubscription, change *domain.SubscriptionChange) error {
 	query := `
 		UPDATE user_subscriptions 
-		SET plan_id = $1, status = 'ACTIVE', starts_at = NOW(), ends_at = $2, updated_at = NOW()
-		WHERE user_id = $3`
-	_, err := r.db.Exec(ctx, query, newPlanID, newEndDate, userID)
+		SET plan_id = $1, plan_version_id = $2, status = $3, starts_at = $4, ends_at = $5, pending_plan_id = $6,
+			cancel_at_period_end = $7, canceled_at = $8, cancel_reason = $9, cancel_feedback = $10,
+			trial_ends_at = $11, trial_reminder_sent_at = $12, currency = $13, usage_billed_until = $14, updated_at = NOW()
+		WHERE id = $15`
+	_, err := tx.Exec(ctx, query, sub.PlanID, sub.PlanVersionID, sub.Status, sub.StartsAt, sub.EndsAt, sub.PendingPlanID,
+		sub.CancelAtPeriodEnd, sub.CanceledAt, sub.CancelReason, sub.CancelFeedback,
+		sub.TrialEndsAt, sub.TrialReminderSentAt, sub.Currency, sub.UsageBilledUntil, sub.ID)
+	if err != nil {
+		return err
+	}
+	if change == nil {
+		return nil
+	}
+	if err := recordSubscriptionHistory(ctx, tx, sub.ID, *change); err != nil {
+		return err
+	}
+	if err := enqueueNextcloudSync(ctx, tx, sub.UserID, subscriptionSyncKinds...); err != nil {
+		return err
+	}
+	if err := publishEntitlementsChanged(ctx, tx, sub.UserID); err != nil {
+		return err
+	}
+	return publishSubscriptionWebhook(ctx, tx, sub.ID, *change)
+}
+
+// recordSubscriptionHistory snapshots the subscription as it is within tx.
+func recordSubscriptionHistory(ctx context.Context, tx pgx.Tx, subscriptionID int64, change domain.SubscriptionChange) error {
+	query := `
+		INSERT INTO subscription_history (subscription_id, user_id, event, plan_id, plan_version_id, status, starts_at, ends_at,
+			currency, pending_plan_id, reason, actor_type, actor_id)
+		SELECT id, user_id, $2, plan_id, plan_version_id, status, starts_at, ends_at, currency, pending_plan_id, $3, $4, $5
+		FROM user_subscriptions WHERE id = $1`
+	var reason *string
+	if change.Reason != "" {
+		reason = &change.Reason
+	}
+	_, err := tx.Exec(ctx, query, subscriptionID, change.Event, reason, change.ActorType, change.ActorID)
 	return err
 }
 
+const subscriptionColumns = `id, user_id, plan_id, plan_version_id, status, starts_at, ends_at, currency, pending_plan_id,
+	cancel_at_period_end, canceled_at, cancel_reason, cancel_feedback, trial_ends_at, trial_reminder_sent_at, usage_billed_until`
+
+func scanSubscription(row pgx.Row, s *domain.UserSubscription) error {
+	return row.Scan(&s.ID, &s.UserID, &s.PlanID, &s.PlanVersionID, &s.Status, &s.StartsAt, &s.EndsAt, &s.Currency, &s.PendingPlanID,
+		&s.CancelAtPeriodEnd, &s.CanceledAt, &s.CancelReason, &s.CancelFeedback, &s.TrialEndsAt, &s.TrialReminderSentAt,
+		&s.UsageBilledUntil)
+}
+
+func (r *subscriptionPostgresRepository) FindByUserID(ctx context.Context, userID int64) (*domain.UserSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM user_subscriptions WHERE user_id = $1 AND status IN ('ACTIVE', 'TRIALING', 'PAST_DUE')`
+	var s domain.UserSubscription
+	if err := scanSubscription(r.db.QueryRow(ctx, query, userID), &s); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *subscriptionPostgresRepository) FindByUserIDs(ctx context.Context, userIDs []int64) ([]domain.UserSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM user_subscriptions
+		WHERE user_id = ANY($1) AND status IN ('ACTIVE', 'TRIALING', 'PAST_DUE')`
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.UserSubscription
+	for rows.Next() {
+		var s domain.UserSubscription
+		if err := scanSubscription(rows, &s); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+func (r *subscriptionPostgresRepository) FindDueForRenewal(ctx context.Context, now time.Time) ([]domain.UserSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM user_subscriptions
+		WHERE status IN ('ACTIVE', 'TRIALING') AND ends_at <= $1
+		ORDER BY ends_at ASC`
+	rows, err := r.db.Query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.UserSubscription
+	for rows.Next() {
+		var s domain.UserSubscription
+		if err := scanSubscription(rows, &s); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+func (r *subscriptionPostgresRepository) FindPastDueSince(ctx context.Context, before time.Time) ([]domain.UserSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM user_subscriptions
+		WHERE status = 'PAST_DUE' AND starts_at <= $1
+		ORDER BY starts_at ASC`
+	rows, err := r.db.Query(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.UserSubscription
+	for rows.Next() {
+		var s domain.UserSubscription
+		if err := scanSubscription(rows, &s); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// reactivatePaidSubscription makes the user's past-due subscription active again within tx
+// once none of its invoices is open anymore.
+func reactivatePaidSubscription(ctx context.Context, tx pgx.Tx, userID int64) error {
+	query := `SELECT ` + subscriptionColumns + ` FROM user_subscriptions s
+		WHERE user_id = $1 AND status = 'PAST_DUE'
+			AND NOT EXISTS (SELECT 1 FROM invoices i WHERE i.subscription_id = s.id AND i.status = 'OPEN')
+		FOR UPDATE`
+	var sub domain.UserSubscription
+	if err := scanSubscription(tx.QueryRow(ctx, query, userID), &sub); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	sub.Status = domain.SubscriptionStatusActive
+	change := domain.ChangeBySystem(domain.SubscriptionEventRenewalPaid, "Open invoices settled from the balance")
+	return updateSubscription(ctx, tx, &sub, &change)
+}
+
+func (r *subscriptionPostgresRepository) FindTrialsEndingBefore(ctx context.Context, before time.Time) ([]domain.UserSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM user_subscriptions
+		WHERE status = 'TRIALING' AND ends_at <= $1 AND trial_reminder_sent_at IS NULL`
+	rows, err := r.db.Query(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.UserSubscription
+	for rows.Next() {
+		var s domain.UserSubscription
+		if err := scanSubscription(rows, &s); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
 func (r *subscriptionPostgresRepository) FindDetailsByUserID(ctx context.Context, userID int64) (*domain.UserSubscriptionDetails, error) {
 	query := `
-		SELECT p.name, s.status, s.ends_at FROM user_subscriptions s
+		SELECT p.name, s.status, s.ends_at, pp.name, s.cancel_at_period_end, s.trial_ends_at FROM user_subscriptions s
 		JOIN subscription_plans p ON s.plan_id = p.id
-		WHERE s.user_id = $1 AND s.status = 'ACTIVE'`
+		LEFT JOIN subscription_plans pp ON s.pending_plan_id = pp.id
+		WHERE s.user_id = $1 AND s.status IN ('ACTIVE', 'TRIALING', 'PAST_DUE')`
 	var d domain.UserSubscriptionDetails
-	err := r.db.QueryRow(ctx, query, userID).Scan(&d.PlanName, &d.Status, &d.EndsAt)
+	err := r.db.QueryRow(ctx, query, userID).Scan(&d.PlanName, &d.Status, &d.EndsAt, &d.PendingPlanName, &d.CancelAtPeriodEnd, &d.TrialEndsAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ierr.ErrNotFound
@@ -55,9 +336,9 @@ func (r *subscriptionPostgresRepository) FindDetailsByUserID(ctx context.Context
 
 func (r *subscriptionPostgresRepository) FindPermissionsByUserID(ctx context.Context, userID int64) (*domain.SubscriptionPlan, error) {
 	query := `
-		SELECT p.permissions FROM user_subscriptions s
-		JOIN subscription_plans p ON s.plan_id = p.id
-		WHERE s.user_id = $1 AND s.status = 'ACTIVE'`
+		SELECT v.permissions FROM user_subscriptions s
+		JOIN plan_versions v ON s.plan_version_id = v.id
+		WHERE s.user_id = $1 AND s.status IN ('ACTIVE', 'TRIALING', 'PAST_DUE')`
 	var p domain.SubscriptionPlan
 	err := r.db.QueryRow(ctx, query, userID).Scan(&p.Permissions)
 	if err != nil {
@@ -68,3 +349,149 @@ func (r *subscriptionPostgresRepository) FindPermissionsByUserID(ctx context.Con
 	}
 	return &p, nil
 }
+
+func (r *subscriptionPostgresRepository) FindUserIDsByPlanVersion(ctx context.Context, planVersionID int64) ([]int64, error) {
+	query := `SELECT user_id FROM user_subscriptions WHERE plan_version_id = $1 AND status IN ('ACTIVE', 'TRIALING', 'PAST_DUE')`
+	rows, err := r.db.Query(ctx, query, planVersionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+func (r *subscriptionPostgresRepository) FindLiveUserIDs(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `SELECT user_id FROM user_subscriptions WHERE status IN ('ACTIVE', 'TRIALING', 'PAST_DUE') ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+func (r *subscriptionPostgresRepository) FindUserIDsWithoutLiveSubscription(ctx context.Context, userIDs []int64) ([]int64, error) {
+	query := `
+		SELECT u.id FROM unnest($1::BIGINT[]) AS u(id)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM user_subscriptions s WHERE s.user_id = u.id AND s.status IN ('ACTIVE', 'TRIALING', 'PAST_DUE')
+		)
+		ORDER BY u.id`
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missing []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		missing = append(missing, id)
+	}
+	return missing, rows.Err()
+}
+
+func (r *subscriptionPostgresRepository) MigratePlanVersion(ctx context.Context, fromVersionID, toVersionID int64) ([]int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		WITH migrated AS (
+			UPDATE user_subscriptions SET plan_version_id = $2, updated_at = NOW()
+			WHERE plan_version_id = $1 AND status IN ('ACTIVE', 'TRIALING', 'PAST_DUE')
+			RETURNING id, user_id, plan_id, plan_version_id, status, starts_at, ends_at, currency, pending_plan_id
+		), history AS (
+			INSERT INTO subscription_history (subscription_id, user_id, event, plan_id, plan_version_id, status, starts_at,
+				ends_at, currency, pending_plan_id, reason, actor_type)
+			SELECT id, user_id, $3, plan_id, plan_version_id, status, starts_at, ends_at, currency, pending_plan_id, $4, $5
+			FROM migrated
+		), synced AS (
+			INSERT INTO nextcloud_outbox (user_id, kind)
+			SELECT m.user_id, k.kind FROM migrated m CROSS JOIN unnest($6::TEXT[]) AS k (kind)
+			ON CONFLICT (user_id, kind) WHERE status = 'PENDING' DO UPDATE
+			SET generation = nextcloud_outbox.generation + 1, next_attempt_at = NOW(), updated_at = NOW()
+		)
+		SELECT id, user_id FROM migrated`
+	change := domain.ChangeBySystem(domain.SubscriptionEventVersionMigrated, fmt.Sprintf("Migrated from plan version %d", fromVersionID))
+	rows, err := tx.Query(ctx, query, fromVersionID, toVersionID, change.Event, change.Reason, change.ActorType,
+		subscriptionSyncKinds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subIDs, userIDs []int64
+	for rows.Next() {
+		var subID, userID int64
+		if err := rows.Scan(&subID, &userID); err != nil {
+			return nil, err
+		}
+		subIDs = append(subIDs, subID)
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, subID := range subIDs {
+		if err := publishSubscriptionWebhook(ctx, tx, subID, change); err != nil {
+			return nil, err
+		}
+	}
+	// One notification for all of them, a migration can move thousands of users.
+	if len(userIDs) > 0 {
+		if err := notifyEntitlements(ctx, tx, entitlements.AllUsers); err != nil {
+			return nil, err
+		}
+	}
+	return userIDs, tx.Commit(ctx)
+}
+
+func (r *subscriptionPostgresRepository) FindHistoryByUserID(ctx context.Context, userID int64) ([]domain.SubscriptionHistoryEntry, error) {
+	query := `
+		SELECT h.id, h.subscription_id, h.user_id, h.event, h.plan_id, p.name, h.plan_version_id, h.status, h.starts_at,
+			h.ends_at, h.currency, h.pending_plan_id, h.reason, h.actor_type, h.actor_id, h.created_at
+		FROM subscription_history h
+		JOIN subscription_plans p ON h.plan_id = p.id
+		WHERE h.user_id = $1
+		ORDER BY h.created_at DESC, h.id DESC`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.SubscriptionHistoryEntry{}
+	for rows.Next() {
+		var e domain.SubscriptionHistoryEntry
+		err := rows.Scan(&e.ID, &e.SubscriptionID, &e.UserID, &e.Event, &e.PlanID, &e.PlanName, &e.PlanVersionID, &e.Status,
+			&e.StartsAt, &e.EndsAt, &e.Currency, &e.PendingPlanID, &e.Reason, &e.ActorType, &e.ActorID, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
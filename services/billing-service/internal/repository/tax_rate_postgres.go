@@ -0,0 +1,83 @@
+// services/billing-service/internal/repository/tax_rate_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type taxRatePostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTaxRatePostgresRepository(db *pgxpool.Pool) TaxRateRepository {
+	return &taxRatePostgresRepository{db: db}
+}
+
+const taxRateColumns = `id, country, region, name, rate_bps, inclusive, created_at, updated_at`
+
+func scanTaxRate(row pgx.Row, t *domain.TaxRate) error {
+	return row.Scan(&t.ID, &t.Country, &t.Region, &t.Name, &t.RateBps, &t.Inclusive, &t.CreatedAt, &t.UpdatedAt)
+}
+
+// Save creates the rate of the country and region or replaces the existing one.
+func (r *taxRatePostgresRepository) Save(ctx context.Context, t *domain.TaxRate) error {
+	query := `
+		INSERT INTO tax_rates (country, region, name, rate_bps, inclusive)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (country, region) DO UPDATE
+		SET name = EXCLUDED.name, rate_bps = EXCLUDED.rate_bps, inclusive = EXCLUDED.inclusive, updated_at = NOW()
+		RETURNING id, created_at, updated_at`
+	return r.db.QueryRow(ctx, query, t.Country, t.Region, t.Name, t.RateBps, t.Inclusive).
+		Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *taxRatePostgresRepository) Delete(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM tax_rates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ierr.ErrNotFound
+	}
+	return nil
+}
+
+// FindForLocation returns the rate of the region, falling back to the country-wide rate.
+func (r *taxRatePostgresRepository) FindForLocation(ctx context.Context, country, region string) (*domain.TaxRate, error) {
+	query := `SELECT ` + taxRateColumns + ` FROM tax_rates
+		WHERE country = $1 AND region IN ($2, '')
+		ORDER BY region DESC
+		LIMIT 1`
+	var t domain.TaxRate
+	if err := scanTaxRate(r.db.QueryRow(ctx, query, country, region), &t); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *taxRatePostgresRepository) FindAll(ctx context.Context) ([]domain.TaxRate, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+taxRateColumns+` FROM tax_rates ORDER BY country ASC, region ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []domain.TaxRate
+	for rows.Next() {
+		var t domain.TaxRate
+		if err := scanTaxRate(rows, &t); err != nil {
+			return nil, err
+		}
+		rates = append(rates, t)
+	}
+	return rates, rows.Err()
+}
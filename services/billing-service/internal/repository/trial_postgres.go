@@ -0,0 +1,40 @@
+// services/billing-service/internal/repository/trial_postgres.go
+package repository
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type trialPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTrialPostgresRepository(db *pgxpool.Pool) TrialRepository {
+	return &trialPostgresRepository{db: db}
+}
+
+func (r *trialPostgresRepository) Exists(ctx context.Context, userID int64, email string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM trial_redemptions WHERE user_id = $1 OR email = $2)`
+	var exists bool
+	err := r.db.QueryRow(ctx, query, userID, email).Scan(&exists)
+	return exists, err
+}
+
+func insertTrialRedemption(ctx context.Context, tx pgx.Tx, trial *domain.TrialRedemption) error {
+	query := `INSERT INTO trial_redemptions (user_id, email, plan_id) VALUES ($1, $2, $3)`
+	_, err := tx.Exec(ctx, query, trial.UserID, trial.Email, trial.PlanID)
+	if err != nil {
+		// Both user_id and email are unique, a concurrent second trial ends up here
+		if strings.Contains(err.Error(), "unique constraint") {
+			return ierr.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// services/billing-service/internal/repository/usage_postgres.go
+package repository
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/domain"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type usagePostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUsagePostgresRepository(db *pgxpool.Pool) UsageRepository {
+	return &usagePostgresRepository{db: db}
+}
+
+// Record stores the events in a single transaction. Events whose event_id is already known
+// are skipped, so a producer can safely retry a batch.
+func (r *usagePostgresRepository) Record(ctx context.Context, events []domain.UsageEvent) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO usage_events (event_id, user_id, metric, quantity, occurred_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (event_id) DO NOTHING`
+	recorded := 0
+	for _, e := range events {
+		tag, err := tx.Exec(ctx, query, e.EventID, e.UserID, e.Metric, int64(e.Quantity), e.OccurredAt)
+		if err != nil {
+			return 0, err
+		}
+		recorded += int(tag.RowsAffected())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return recorded, nil
+}
+
+// SumByMetric totals the user's usage that occurred in [from, to) per metric.
+func (r *usagePostgresRepository) SumByMetric(ctx context.Context, userID int64, from, to time.Time) (map[string]domain.Quantity, error) {
+	query := `
+		SELECT metric, SUM(quantity)::BIGINT FROM usage_events
+		WHERE user_id = $1 AND occurred_at >= $2 AND occurred_at < $3
+		GROUP BY metric`
+	rows, err := r.db.Query(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]domain.Quantity)
+	for rows.Next() {
+		var metric string
+		var total int64
+		if err := rows.Scan(&metric, &total); err != nil {
+			return nil, err
+		}
+		totals[metric] = domain.Quantity(total)
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,244 @@
+// services/billing-service/internal/repository/wallet_postgres.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type walletPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWalletPostgresRepository(db *pgxpool.Pool) WalletRepository {
+	return &walletPostgresRepository{db: db}
+}
+
+// insertLedgerTransaction books a transaction within tx. Debits of a wallet are serialized per
+// user and rejected with ierr.ErrConflict if they would make the balance negative; a reference
+// that was already booked is a conflict as well.
+func insertLedgerTransaction(ctx context.Context, tx pgx.Tx, t *domain.LedgerTransaction) error {
+	for _, e := range t.Entries {
+		if e.Account != domain.LedgerAccountWallet || !e.Amount.IsNegative() {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, t.UserID); err != nil {
+			return err
+		}
+		var balance int64
+		if err := tx.QueryRow(ctx, walletBalanceQuery, t.UserID, e.Amount.Currency).Scan(&balance); err != nil {
+			return err
+		}
+		if balance+e.Amount.Amount < 0 {
+			return ierr.ErrConflict
+		}
+	}
+
+	query := `
+		INSERT INTO ledger_transactions (user_id, kind, description, reference)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	err := tx.QueryRow(ctx, query, t.UserID, t.Kind, t.Description, t.Reference).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "unique constraint") {
+			return ierr.ErrConflict
+		}
+		return err
+	}
+
+	entryQuery := `
+		INSERT INTO ledger_entries (transaction_id, user_id, account, amount_minor, currency)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+	for i := range t.Entries {
+		e := &t.Entries[i]
+		e.TransactionID = t.ID
+		if err := tx.QueryRow(ctx, entryQuery, t.ID, t.UserID, e.Account, e.Amount.Amount, e.Amount.Currency).Scan(&e.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const walletBalanceQuery = `
+	SELECT COALESCE(SUM(amount_minor), 0)::BIGINT FROM ledger_entries
+	WHERE user_id = $1 AND account = 'WALLET' AND currency = $2`
+
+func (r *walletPostgresRepository) Post(ctx context.Context, t *domain.LedgerTransaction) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertLedgerTransaction(ctx, tx, t); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *walletPostgresRepository) Balance(ctx context.Context, userID int64, currency string) (domain.Money, error) {
+	var balance int64
+	if err := r.db.QueryRow(ctx, walletBalanceQuery, userID, currency).Scan(&balance); err != nil {
+		return domain.Money{}, err
+	}
+	return domain.NewMoney(balance, currency), nil
+}
+
+func (r *walletPostgresRepository) Balances(ctx context.Context, userID int64) ([]domain.Money, error) {
+	query := `
+		SELECT currency, SUM(amount_minor)::BIGINT FROM ledger_entries
+		WHERE user_id = $1 AND account = 'WALLET'
+		GROUP BY currency
+		ORDER BY currency`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := []domain.Money{}
+	for rows.Next() {
+		var currency string
+		var amount int64
+		if err := rows.Scan(&currency, &amount); err != nil {
+			return nil, err
+		}
+		balances = append(balances, domain.NewMoney(amount, currency))
+	}
+	return balances, rows.Err()
+}
+
+func (r *walletPostgresRepository) FindWalletTransactions(ctx context.Context, userID int64, limit int) ([]domain.WalletTransaction, error) {
+	query := `
+		SELECT t.id, t.kind, t.description, e.amount_minor, e.currency, t.created_at
+		FROM ledger_entries e
+		JOIN ledger_transactions t ON t.id = e.transaction_id
+		WHERE e.user_id = $1 AND e.account = 'WALLET'
+		ORDER BY t.created_at DESC, e.id DESC
+		LIMIT $2`
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []domain.WalletTransaction{}
+	for rows.Next() {
+		var wt domain.WalletTransaction
+		if err := rows.Scan(&wt.TransactionID, &wt.Kind, &wt.Description, &wt.Amount.Amount, &wt.Amount.Currency, &wt.CreatedAt); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, wt)
+	}
+	return transactions, rows.Err()
+}
+
+func (r *walletPostgresRepository) CreateTopUp(ctx context.Context, t *domain.WalletTopUp) error {
+	query := `
+		INSERT INTO wallet_top_ups (user_id, amount_minor, currency, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	return r.db.QueryRow(ctx, query, t.UserID, t.Amount.Amount, t.Amount.Currency, t.Status).Scan(&t.ID, &t.CreatedAt)
+}
+
+const topUpColumns = `id, user_id, amount_minor, refunded_minor, currency, status, provider_payment_id, created_at, completed_at`
+
+func scanTopUp(row pgx.Row, t *domain.WalletTopUp) error {
+	err := row.Scan(&t.ID, &t.UserID, &t.Amount.Amount, &t.Refunded.Amount, &t.Amount.Currency, &t.Status,
+		&t.ProviderPaymentID, &t.CreatedAt, &t.CompletedAt)
+	t.Refunded.Currency = t.Amount.Currency
+	return err
+}
+
+func (r *walletPostgresRepository) FindTopUp(ctx context.Context, id int64) (*domain.WalletTopUp, error) {
+	query := `SELECT ` + topUpColumns + ` FROM wallet_top_ups WHERE id = $1`
+	var t domain.WalletTopUp
+	if err := scanTopUp(r.db.QueryRow(ctx, query, id), &t); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *walletPostgresRepository) CompleteTopUp(ctx context.Context, id int64, providerPaymentID string, credit *domain.LedgerTransaction) (bool, []int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE wallet_top_ups SET status = 'SUCCEEDED', provider_payment_id = $1, completed_at = $2
+		WHERE id = $3 AND status = 'PENDING'
+		RETURNING user_id, currency`
+	var userID int64
+	var currency string
+	if err := tx.QueryRow(ctx, query, providerPaymentID, time.Now(), id).Scan(&userID, &currency); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	if err := insertLedgerTransaction(ctx, tx, credit); err != nil {
+		return false, nil, err
+	}
+	settled, err := settleOpenInvoices(ctx, tx, userID, currency)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, settled, tx.Commit(ctx)
+}
+
+// FailTopUp marks a pending top-up as failed and emits payment.failed; top-ups that are no
+// longer pending are left as they are.
+func (r *walletPostgresRepository) FailTopUp(ctx context.Context, id int64, providerPaymentID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE wallet_top_ups SET status = 'FAILED', provider_payment_id = $1, completed_at = NOW()
+		WHERE id = $2 AND status = 'PENDING'
+		RETURNING ` + topUpColumns
+	var t domain.WalletTopUp
+	if err := scanTopUp(tx.QueryRow(ctx, query, providerPaymentID, id), &t); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	data := domain.PaymentWebhookData{UserID: t.UserID, TopUpID: t.ID, ProviderPaymentID: t.ProviderPaymentID, Amount: t.Amount}
+	if err := enqueueWebhookEvent(ctx, tx, domain.WebhookEventPaymentFailed, &t.UserID, data); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *walletPostgresRepository) FindRefundableTopUp(ctx context.Context, userID int64, amount domain.Money) (*domain.WalletTopUp, error) {
+	query := `
+		SELECT ` + topUpColumns + ` FROM wallet_top_ups
+		WHERE user_id = $1 AND currency = $2 AND status = 'SUCCEEDED' AND provider_payment_id IS NOT NULL
+			AND amount_minor - refunded_minor >= $3
+		ORDER BY created_at DESC
+		LIMIT 1`
+	var t domain.WalletTopUp
+	if err := scanTopUp(r.db.QueryRow(ctx, query, userID, amount.Currency, amount.Amount), &t); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
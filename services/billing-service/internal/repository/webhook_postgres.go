@@ -0,0 +1,325 @@
+// services/billing-service/internal/repository/webhook_postgres.go
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type webhookPostgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookPostgresRepository(db *pgxpool.Pool) WebhookRepository {
+	return &webhookPostgresRepository{db: db}
+}
+
+// enqueueWebhookEvent records an event and a delivery to every active endpoint subscribed to
+// its type as part of tx, so it is only sent if the change it describes is committed. Events
+// nobody listens to are not stored.
+func enqueueWebhookEvent(ctx context.Context, tx pgx.Tx, eventType string, userID *int64, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	query := `
+		WITH event AS (
+			INSERT INTO webhook_events (type, user_id, data)
+			SELECT $1::TEXT, $2::BIGINT, $3::JSONB
+			WHERE EXISTS (SELECT 1 FROM webhook_endpoints WHERE is_active AND $1 = ANY(event_types))
+			RETURNING id
+		)
+		INSERT INTO webhook_deliveries (event_id, endpoint_id)
+		SELECT event.id, ep.id FROM event JOIN webhook_endpoints ep ON ep.is_active AND $1 = ANY(ep.event_types)`
+	_, err = tx.Exec(ctx, query, eventType, userID, payload)
+	return err
+}
+
+// publishSubscriptionWebhook emits the subscription.* event of a change, with the
+// subscription as it is within tx.
+func publishSubscriptionWebhook(ctx context.Context, tx pgx.Tx, subscriptionID int64, change domain.SubscriptionChange) error {
+	query := `
+		SELECT s.id, s.user_id, s.plan_id, p.name, s.plan_version_id, s.status, s.currency, s.starts_at, s.ends_at,
+			s.cancel_at_period_end, s.trial_ends_at, s.pending_plan_id
+		FROM user_subscriptions s JOIN subscription_plans p ON p.id = s.plan_id
+		WHERE s.id = $1`
+	data := domain.SubscriptionWebhookData{Event: change.Event, Reason: change.Reason, ActorType: change.ActorType}
+	err := tx.QueryRow(ctx, query, subscriptionID).Scan(&data.SubscriptionID, &data.UserID, &data.PlanID, &data.PlanName,
+		&data.PlanVersionID, &data.Status, &data.Currency, &data.StartsAt, &data.EndsAt, &data.CancelAtPeriodEnd,
+		&data.TrialEndsAt, &data.PendingPlanID)
+	if err != nil {
+		return err
+	}
+	return enqueueWebhookEvent(ctx, tx, domain.SubscriptionWebhookEventType(change.Event), &data.UserID, data)
+}
+
+//
+// Endpoints
+//
+
+const webhookEndpointColumns = `id, url, description, event_types, is_active, created_at, updated_at`
+
+func scanWebhookEndpoint(row pgx.Row, e *domain.WebhookEndpoint) error {
+	return row.Scan(&e.ID, &e.URL, &e.Description, &e.EventTypes, &e.IsActive, &e.CreatedAt, &e.UpdatedAt)
+}
+
+func (r *webhookPostgresRepository) CreateEndpoint(ctx context.Context, e *domain.WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (url, description, secret, event_types, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+	return r.db.QueryRow(ctx, query, e.URL, e.Description, e.Secret, e.EventTypes, e.IsActive).
+		Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+}
+
+// UpdateEndpoint saves everything but the secret, which only changes through SetEndpointSecret.
+func (r *webhookPostgresRepository) UpdateEndpoint(ctx context.Context, e *domain.WebhookEndpoint) error {
+	query := `
+		UPDATE webhook_endpoints SET url = $1, description = $2, event_types = $3, is_active = $4, updated_at = NOW()
+		WHERE id = $5
+		RETURNING updated_at`
+	err := r.db.QueryRow(ctx, query, e.URL, e.Description, e.EventTypes, e.IsActive, e.ID).Scan(&e.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ierr.ErrNotFound
+	}
+	return err
+}
+
+func (r *webhookPostgresRepository) SetEndpointSecret(ctx context.Context, id int64, secret string) error {
+	tag, err := r.db.Exec(ctx, `UPDATE webhook_endpoints SET secret = $1, updated_at = NOW() WHERE id = $2`, secret, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ierr.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteEndpoint removes the endpoint together with its deliveries and their log.
+func (r *webhookPostgresRepository) DeleteEndpoint(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ierr.ErrNotFound
+	}
+	return nil
+}
+
+func (r *webhookPostgresRepository) FindEndpointByID(ctx context.Context, id int64) (*domain.WebhookEndpoint, error) {
+	query := `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints WHERE id = $1`
+	var e domain.WebhookEndpoint
+	if err := scanWebhookEndpoint(r.db.QueryRow(ctx, query, id), &e); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *webhookPostgresRepository) FindAllEndpoints(ctx context.Context) ([]domain.WebhookEndpoint, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []domain.WebhookEndpoint{}
+	for rows.Next() {
+		var e domain.WebhookEndpoint
+		if err := scanWebhookEndpoint(rows, &e); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
+
+//
+// Deliveries
+//
+
+const webhookDeliveryColumns = `d.id, d.event_id, e.type, d.endpoint_id, ep.url, d.status, d.attempts, d.next_attempt_at,
+	d.last_error, d.last_response_status, d.created_at, d.updated_at, d.delivered_at`
+
+const webhookDeliveryJoins = `
+	JOIN webhook_endpoints ep ON ep.id = d.endpoint_id
+	JOIN webhook_events e ON e.id = d.event_id`
+
+func scanWebhookDelivery(row pgx.Row, d *domain.WebhookDelivery, extra ...interface{}) error {
+	dest := []interface{}{&d.ID, &d.EventID, &d.EventType, &d.EndpointID, &d.URL, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastError, &d.LastResponseStatus, &d.CreatedAt, &d.UpdatedAt, &d.DeliveredAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func collectWebhookDeliveries(rows pgx.Rows, err error) ([]domain.WebhookDelivery, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimDeliveries leases up to `limit` due deliveries to active endpoints by pushing their next
+// attempt `lease` into the future, the same way NextcloudOutboxRepository.Claim does. The
+// deliveries come with their event and the endpoint's secret; their next attempt is the one
+// before the claim.
+func (r *webhookPostgresRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond', updated_at = NOW()
+			WHERE id IN (
+				SELECT d.id FROM webhook_deliveries d JOIN webhook_endpoints ep ON ep.id = d.endpoint_id
+				WHERE d.status = 'PENDING' AND d.next_attempt_at <= NOW() AND ep.is_active
+				ORDER BY d.next_attempt_at
+				LIMIT $1
+				FOR UPDATE OF d SKIP LOCKED
+			)
+			RETURNING id
+		)
+		SELECT ` + webhookDeliveryColumns + `, ep.secret, e.user_id, e.data, e.created_at
+		FROM claimed c JOIN webhook_deliveries d ON d.id = c.id` + webhookDeliveryJoins
+	rows, err := r.db.Query(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		event := domain.WebhookEvent{}
+		if err := scanWebhookDelivery(rows, &d, &d.Secret, &event.UserID, &event.Data, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.ID, event.Type = d.EventID, d.EventType
+		d.Event = &event
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// recordWebhookAttempt logs an attempt and applies update to its delivery in one transaction.
+func (r *webhookPostgresRepository) recordWebhookAttempt(ctx context.Context, attempt *domain.WebhookDeliveryAttempt, update string, args ...interface{}) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO webhook_delivery_attempts (delivery_id, response_status, response_body, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, attempted_at`
+	err = tx.QueryRow(ctx, query, attempt.DeliveryID, attempt.ResponseStatus, attempt.ResponseBody, attempt.Error,
+		attempt.DurationMs).Scan(&attempt.ID, &attempt.AttemptedAt)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, update, append([]interface{}{attempt.DeliveryID}, args...)...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *webhookPostgresRepository) MarkDelivered(ctx context.Context, attempt *domain.WebhookDeliveryAttempt) error {
+	update := `
+		UPDATE webhook_deliveries SET status = 'DELIVERED', attempts = attempts + 1, last_error = NULL,
+			last_response_status = $2, delivered_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'PENDING'`
+	return r.recordWebhookAttempt(ctx, attempt, update, attempt.ResponseStatus)
+}
+
+func (r *webhookPostgresRepository) MarkFailed(ctx context.Context, attempt *domain.WebhookDeliveryAttempt, nextAttemptAt time.Time, dead bool) error {
+	status := domain.WebhookDeliveryPending
+	if dead {
+		status = domain.WebhookDeliveryDead
+	}
+	update := `
+		UPDATE webhook_deliveries SET attempts = attempts + 1, last_error = $2, last_response_status = $3,
+			next_attempt_at = $4, status = $5, updated_at = NOW()
+		WHERE id = $1 AND status = 'PENDING'`
+	return r.recordWebhookAttempt(ctx, attempt, update, attempt.Error, attempt.ResponseStatus, nextAttemptAt, status)
+}
+
+// FindDeliveries lists the newest deliveries to an endpoint, of any status if status is empty.
+func (r *webhookPostgresRepository) FindDeliveries(ctx context.Context, endpointID int64, status string, limit int) ([]domain.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d` + webhookDeliveryJoins + `
+		WHERE d.endpoint_id = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $3`
+	return collectWebhookDeliveries(r.db.Query(ctx, query, endpointID, status, limit))
+}
+
+// FindDeliveryByID returns the delivery with its event and the log of its attempts.
+func (r *webhookPostgresRepository) FindDeliveryByID(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `, e.user_id, e.data, e.created_at
+		FROM webhook_deliveries d` + webhookDeliveryJoins + `
+		WHERE d.id = $1`
+	var d domain.WebhookDelivery
+	event := domain.WebhookEvent{}
+	if err := scanWebhookDelivery(r.db.QueryRow(ctx, query, id), &d, &event.UserID, &event.Data, &event.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ierr.ErrNotFound
+		}
+		return nil, err
+	}
+	event.ID, event.Type = d.EventID, d.EventType
+	d.Event = &event
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, delivery_id, response_status, response_body, error, duration_ms, attempted_at
+		FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY attempted_at, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	d.Log = []domain.WebhookDeliveryAttempt{}
+	for rows.Next() {
+		var a domain.WebhookDeliveryAttempt
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.ResponseStatus, &a.ResponseBody, &a.Error, &a.DurationMs, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+		d.Log = append(d.Log, a)
+	}
+	return &d, rows.Err()
+}
+
+// Redeliver makes a delivery of any status pending again with a fresh set of attempts. Its
+// log is kept.
+func (r *webhookPostgresRepository) Redeliver(ctx context.Context, id int64) error {
+	query := `
+		UPDATE webhook_deliveries SET status = 'PENDING', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1`
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ierr.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+// services/billing-service/internal/service/addon.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"time"
+)
+
+// maxAddOnQuantity caps how many units of one add-on a single purchase can add.
+const maxAddOnQuantity = 100
+
+func (s *billingService) GetAvailableAddOns(ctx context.Context) ([]domain.AddOn, error) {
+	return s.addOnRepo.FindAllActive(ctx)
+}
+
+func (s *billingService) GetUserAddOns(ctx context.Context, userID int64) ([]domain.UserAddOn, error) {
+	return s.addOnRepo.FindByUserID(ctx, userID)
+}
+
+// AddAddOn buys `quantity` units of an add-on, stacking on units the user already has.
+// The remainder of the current period is charged right away, later periods with the plan.
+// Users on a free plan have no billing period yet, so a monthly one starts now.
+func (s *billingService) AddAddOn(ctx context.Context, userID, addOnID int64, quantity int) ([]domain.UserAddOn, error) {
+	if quantity <= 0 || quantity > maxAddOnQuantity {
+		return nil, fmt.Errorf("quantity must be between 1 and %d: %w", maxAddOnQuantity, ierr.ErrInvalidInput)
+	}
+	addOn, err := s.addOnRepo.FindByID(ctx, addOnID)
+	if err != nil {
+		return nil, fmt.Errorf("add-on not found: %w", ierr.ErrNotFound)
+	}
+	if !addOn.IsActive {
+		return nil, fmt.Errorf("add-on '%s' is no longer available: %w", addOn.Name, ierr.ErrConflict)
+	}
+
+	sub, err := s.subRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("active subscription not found: %w", err)
+	}
+	if sub.Status == domain.SubscriptionStatusTrialing {
+		return nil, fmt.Errorf("add-ons can be bought once the trial has ended: %w", ierr.ErrConflict)
+	}
+	if sub.Status == domain.SubscriptionStatusPastDue {
+		return nil, fmt.Errorf("top up your balance to pay the open invoice first: %w", ierr.ErrConflict)
+	}
+	price, ok := addOn.PriceIn(sub.Currency)
+	if !ok {
+		return nil, fmt.Errorf("add-on '%s' is not sold in %s: %w", addOn.Name, sub.Currency, ierr.ErrConflict)
+	}
+	plan, err := s.subscribedPlan(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := s.addOnRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tax, err := s.taxTreatmentFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	amount := price.Prorate(int64(quantity), 1)
+	startsPeriod := plan.IsFree() && len(owned) == 0
+	if startsPeriod {
+		sub.StartsAt = now
+		sub.EndsAt = now.AddDate(0, 1, 0)
+	} else {
+		remaining, total := unusedPeriod(sub, now)
+		amount = amount.Prorate(int64(remaining), int64(total))
+	}
+
+	if err := s.addOnRepo.AddToUser(ctx, userID, addOn.ID, quantity); err != nil {
+		return nil, err
+	}
+	if startsPeriod {
+		change := domain.ChangeByUser(domain.SubscriptionEventPeriodStarted, userID, fmt.Sprintf("Bought add-on '%s'", addOn.Name))
+		if err := s.subRepo.Update(ctx, sub, &change); err != nil {
+			return nil, err
+		}
+	}
+	if amount.IsPositive() {
+		invoice := linesInvoice(sub, []domain.InvoiceLine{{
+			Kind:        domain.InvoiceLineAddOn,
+			Description: fmt.Sprintf("%s × %d until %s", addOn.Name, quantity, sub.EndsAt.Format(time.DateOnly)),
+			Amount:      amount,
+		}})
+		tax.applyToInvoice(invoice)
+		if err := s.invoiceRepo.Create(ctx, invoice); err != nil {
+			return nil, fmt.Errorf("failed to create add-on invoice: %w", err)
+		}
+	}
+
+	s.recheckStorage(ctx, userID)
+	log.Printf("User %d bought %d x add-on %d.", userID, quantity, addOn.ID)
+	return s.addOnRepo.FindByUserID(ctx, userID)
+}
+
+// RemoveAddOn removes all units of an add-on right away. The rest of the paid period is not refunded.
+func (s *billingService) RemoveAddOn(ctx context.Context, userID, addOnID int64) ([]domain.UserAddOn, error) {
+	if err := s.addOnRepo.RemoveFromUser(ctx, userID, addOnID); err != nil {
+		return nil, fmt.Errorf("add-on %d is not part of the subscription: %w", addOnID, err)
+	}
+
+	s.recheckStorage(ctx, userID)
+	log.Printf("User %d removed add-on %d.", userID, addOnID)
+	return s.addOnRepo.FindByUserID(ctx, userID)
+}
+
+// carryOverAddOns keeps billing the add-ons of a user who was just moved to the default plan
+// after their subscription ended at `at`. The add-ons are paid until paidUntil; if that is
+// already over, a new monthly period starts and is invoiced right away.
+func (s *billingService) carryOverAddOns(ctx context.Context, userID int64, at, paidUntil time.Time) error {
+	addOns, err := s.addOnRepo.FindByUserID(ctx, userID)
+	if err != nil || len(addOns) == 0 {
+		return err
+	}
+	sub, err := s.subRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := addOnsSoldIn(addOns, sub.Currency); err != nil {
+		return err
+	}
+
+	sub.StartsAt = at
+	sub.EndsAt = paidUntil
+	change := domain.ChangeBySystem(domain.SubscriptionEventPeriodStarted, "Add-ons carried over")
+	if paidUntil.After(at) {
+		return s.subRepo.Update(ctx, sub, &change)
+	}
+
+	tax, err := s.taxTreatmentFor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	sub.EndsAt = at.AddDate(0, 1, 0)
+	if err := s.subRepo.Update(ctx, sub, &change); err != nil {
+		return err
+	}
+	invoice := linesInvoice(sub, addOnLines(sub, addOns))
+	tax.applyToInvoice(invoice)
+	if err := s.invoiceRepo.Create(ctx, invoice); err != nil {
+		return fmt.Errorf("failed to create add-on invoice: %w", err)
+	}
+	return nil
+}
+
+// billingPeriodEnd is periodEndFor, except that add-ons are billed monthly even on a free plan.
+func billingPeriodEnd(plan *domain.SubscriptionPlan, addOns []domain.UserAddOn, start time.Time) time.Time {
+	if len(addOns) > 0 {
+		return start.AddDate(0, 1, 0)
+	}
+	return periodEndFor(plan, start)
+}
+
+// addOnsSoldIn checks that every add-on can be billed in currency.
+func addOnsSoldIn(addOns []domain.UserAddOn, currency string) error {
+	for _, ua := range addOns {
+		if _, ok := ua.AddOn.PriceIn(currency); !ok {
+			return fmt.Errorf("add-on '%s' is not sold in %s: %w", ua.AddOn.Name, currency, ierr.ErrConflict)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// services/billing-service/internal/service/addon_service.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"strings"
+)
+
+// AddOnService is the admin side of add-ons. Buying them happens in BillingService.
+type AddOnService interface {
+	GetAllAddOns(ctx context.Context) ([]domain.AddOn, error)
+	CreateAddOn(ctx context.Context, addOn *domain.AddOn) (*domain.AddOn, error)
+	PatchAddOn(ctx context.Context, id int64, patch AddOnPatch) (*domain.AddOn, error)
+	ArchiveAddOn(ctx context.Context, id int64) error
+}
+
+// AddOnPatch holds the add-on fields an admin may change, nil fields are left untouched.
+// Permission deltas are immutable: users who bought an add-on keep what they paid for.
+type AddOnPatch struct {
+	Name        *string
+	Description *string
+	Prices      *[]domain.Money
+	IsActive    *bool
+}
+
+type addOnService struct {
+	addOnRepo       repository.AddOnRepository
+	defaultCurrency string
+}
+
+func NewAddOnService(addOnRepo repository.AddOnRepository, defaultCurrency string) AddOnService {
+	return &addOnService{
+		addOnRepo:       addOnRepo,
+		defaultCurrency: defaultCurrency,
+	}
+}
+
+func (s *addOnService) GetAllAddOns(ctx context.Context) ([]domain.AddOn, error) {
+	return s.addOnRepo.FindAll(ctx)
+}
+
+func (s *addOnService) CreateAddOn(ctx context.Context, addOn *domain.AddOn) (*domain.AddOn, error) {
+	addOn.Name = strings.TrimSpace(addOn.Name)
+	addOn.Description = strings.TrimSpace(addOn.Description)
+	addOn.IsActive = true
+	if err := addOn.PermissionDeltas.Validate(); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ierr.ErrInvalidInput)
+	}
+	if err := s.validateAddOn(addOn); err != nil {
+		return nil, err
+	}
+
+	if err := s.addOnRepo.Create(ctx, addOn); err != nil {
+		return nil, fmt.Errorf("add-on '%s' already exists: %w", addOn.Name, err)
+	}
+	return addOn, nil
+}
+
+// PatchAddOn edits an add-on. New prices apply to existing buyers from their next period on.
+func (s *addOnService) PatchAddOn(ctx context.Context, id int64, patch AddOnPatch) (*domain.AddOn, error) {
+	addOn, err := s.addOnRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Name != nil {
+		addOn.Name = strings.TrimSpace(*patch.Name)
+	}
+	if patch.Description != nil {
+		addOn.Description = strings.TrimSpace(*patch.Description)
+	}
+	if patch.Prices != nil {
+		addOn.Prices = *patch.Prices
+	}
+	if patch.IsActive != nil {
+		addOn.IsActive = *patch.IsActive
+	}
+	if err := s.validateAddOn(addOn); err != nil {
+		return nil, err
+	}
+
+	if err := s.addOnRepo.Update(ctx, addOn); err != nil {
+		return nil, err
+	}
+	return addOn, nil
+}
+
+// ArchiveAddOn stops sales of an add-on. Users who already have it keep it.
+func (s *addOnService) ArchiveAddOn(ctx context.Context, id int64) error {
+	addOn, err := s.addOnRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	addOn.IsActive = false
+	return s.addOnRepo.Update(ctx, addOn)
+}
+
+func (s *addOnService) validateAddOn(addOn *domain.AddOn) error {
+	if addOn.Name == "" {
+		return fmt.Errorf("add-on name is required: %w", ierr.ErrInvalidInput)
+	}
+	seen := make(map[string]bool, len(addOn.Prices))
+	for _, price := range addOn.Prices {
+		if !domain.IsSupportedCurrency(price.Currency) {
+			return fmt.Errorf("unsupported currency '%s': %w", price.Currency, ierr.ErrInvalidInput)
+		}
+		if seen[price.Currency] {
+			return fmt.Errorf("add-on has more than one %s price: %w", price.Currency, ierr.ErrInvalidInput)
+		}
+		seen[price.Currency] = true
+		if !price.IsPositive() {
+			return fmt.Errorf("add-on price must be positive: %w", ierr.ErrInvalidInput)
+		}
+	}
+	if _, ok := addOn.PriceIn(s.defaultCurrency); !ok {
+		return fmt.Errorf("add-ons must have a price in %s: %w", s.defaultCurrency, ierr.ErrInvalidInput)
+	}
+	return nil
+}
@@ -8,52 +8,130 @@ import (
 	"jcloud-project/billing-service/internal/client"
 	"jcloud-project/billing-service/internal/domain"
 	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/entitlements"
 	"jcloud-project/libs/go-common/ierr"
 	"log"
 	"time"
 )
 
 type BillingService interface {
-	GetUserPermissions(ctx context.Context, userID int64) (map[string]interface{}, error)
-	CreateInitialSubscription(ctx context.Context, userID int64, planName string) error
+	GetUserPermissions(ctx context.Context, userID int64) (entitlements.Entitlements, error)
+	// CreateInitialSubscription puts a new user on the plan. It reports false, and changes
+	// nothing, if the user already has a live subscription, so that callers may retry it.
+	CreateInitialSubscription(ctx context.Context, userID int64, planName string) (bool, error)
 	GetAllPlans(ctx context.Context) ([]domain.SubscriptionPlan, error)
 	GetUserSubscription(ctx context.Context, userID int64) (*domain.UserSubscriptionDetails, error)
-	ChangeSubscription(ctx context.Context, userID, newPlanID int64) error
+	GetSubscriptionHistory(ctx context.Context, userID int64) ([]domain.SubscriptionHistoryEntry, error)
+	PreviewSubscriptionChange(ctx context.Context, userID, newPlanID int64, opts PlanChangeOptions) (*domain.ProrationPreview, error)
+	ChangeSubscription(ctx context.Context, userID, newPlanID int64, opts PlanChangeOptions) (*domain.ProrationPreview, error)
+	CancelSubscription(ctx context.Context, userID int64, atPeriodEnd bool, reason, feedback string) (*domain.UserSubscriptionDetails, error)
+	ResumeSubscription(ctx context.Context, userID int64) (*domain.UserSubscriptionDetails, error)
+	StartTrial(ctx context.Context, userID, planID int64, autoConvert bool, pref CurrencyPreference) (*domain.UserSubscriptionDetails, error)
+	RecordUsage(ctx context.Context, events []domain.UsageEvent) (*domain.UsageRecording, error)
+	GetCurrentUsage(ctx context.Context, userID int64) ([]domain.UsageSummary, error)
+	GetAvailableAddOns(ctx context.Context) ([]domain.AddOn, error)
+	GetUserAddOns(ctx context.Context, userID int64) ([]domain.UserAddOn, error)
+	AddAddOn(ctx context.Context, userID, addOnID int64, quantity int) ([]domain.UserAddOn, error)
+	RemoveAddOn(ctx context.Context, userID, addOnID int64) ([]domain.UserAddOn, error)
+	RefundInvoice(ctx context.Context, adminID, invoiceID int64, req RefundRequest) (*domain.CreditNote, error)
+	GetCreditNotes(ctx context.Context, invoiceID int64) ([]domain.CreditNote, error)
+	ProcessDueSubscriptions(ctx context.Context) error
+	// RepairMissingSubscriptions puts every user without a live subscription on the default plan.
+	RepairMissingSubscriptions(ctx context.Context) (*domain.SubscriptionRepair, error)
 }
 
+// defaultPlanName is the plan every user falls back to when a paid subscription ends.
+const defaultPlanName = "Free"
+
+// pastDueGrace is how long a renewal the balance did not cover may stay unpaid before the
+// subscription ends.
+const pastDueGrace = 7 * 24 * time.Hour
+
 type billingService struct {
 	planRepo        repository.PlanRepository
 	subRepo         repository.SubscriptionRepository
-	nextcloudClient client.NextcloudClient
+	invoiceRepo     repository.InvoiceRepository
+	trialRepo       repository.TrialRepository
+	couponRepo      repository.CouponRepository
+	taxRateRepo     repository.TaxRateRepository
+	profileRepo     repository.BillingProfileRepository
+	usageRepo       repository.UsageRepository
+	addOnRepo       repository.AddOnRepository
+	walletRepo      repository.WalletRepository
+	creditNoteRepo  repository.CreditNoteRepository
 	userSvcClient   client.UserServiceClient
+	notifier        client.Notifier
+	paymentProvider client.PaymentProvider
+	overLimit       OverLimitService
+	defaultCurrency string
+	sellerCountry   string
 }
 
-func NewBillingService(planRepo repository.PlanRepository, subRepo repository.SubscriptionRepository, ncClient client.NextcloudClient, userSvcClient client.UserServiceClient) BillingService {
+func NewBillingService(planRepo repository.PlanRepository, subRepo repository.SubscriptionRepository, invoiceRepo repository.InvoiceRepository, trialRepo repository.TrialRepository, couponRepo repository.CouponRepository, taxRateRepo repository.TaxRateRepository, profileRepo repository.BillingProfileRepository, usageRepo repository.UsageRepository, addOnRepo repository.AddOnRepository, walletRepo repository.WalletRepository, creditNoteRepo repository.CreditNoteRepository, userSvcClient client.UserServiceClient, notifier client.Notifier, paymentProvider client.PaymentProvider, overLimit OverLimitService, defaultCurrency, sellerCountry string) BillingService {
 	return &billingService{
 		planRepo:        planRepo,
 		subRepo:         subRepo,
-		nextcloudClient: ncClient,
+		invoiceRepo:     invoiceRepo,
+		trialRepo:       trialRepo,
+		couponRepo:      couponRepo,
+		taxRateRepo:     taxRateRepo,
+		profileRepo:     profileRepo,
+		usageRepo:       usageRepo,
+		addOnRepo:       addOnRepo,
+		walletRepo:      walletRepo,
+		creditNoteRepo:  creditNoteRepo,
 		userSvcClient:   userSvcClient,
+		notifier:        notifier,
+		paymentProvider: paymentProvider,
+		overLimit:       overLimit,
+		defaultCurrency: defaultCurrency,
+		sellerCountry:   sellerCountry,
 	}
 }
 
-func (s *billingService) GetUserPermissions(ctx context.Context, userID int64) (map[string]interface{}, error) {
+// GetUserPermissions returns the permissions of the user's plan with their add-ons added on
+// top, read-only while the user stores more than they allow.
+func (s *billingService) GetUserPermissions(ctx context.Context, userID int64) (entitlements.Entitlements, error) {
 	plan, err := s.subRepo.FindPermissionsByUserID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, ierr.ErrNotFound) {
-			return make(map[string]interface{}), nil // No subscription = empty permissions
+			return entitlements.Default(), nil // No subscription = no permissions
 		}
-		return nil, err
+		return entitlements.Entitlements{}, err
+	}
+	addOns, err := s.addOnRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return entitlements.Entitlements{}, err
 	}
-	return plan.Permissions, nil
+	overLimit, err := s.overLimit.GetCase(ctx, userID)
+	if err != nil {
+		return entitlements.Entitlements{}, err
+	}
+	permissions := domain.MergePermissions(plan.Permissions, addOns)
+	permissions.ReadOnly = overLimit != nil
+	return permissions, nil
+}
+
+func (s *billingService) CreateInitialSubscription(ctx context.Context, userID int64, planName string) (bool, error) {
+	return s.createInitialSubscription(ctx, userID, planName, "Registration")
 }
 
-func (s *billingService) CreateInitialSubscription(ctx context.Context, userID int64, planName string) error {
+func (s *billingService) createInitialSubscription(ctx context.Context, userID int64, planName, reason string) (bool, error) {
+	if _, err := s.subRepo.FindByUserID(ctx, userID); err == nil {
+		return false, nil
+	} else if !errors.Is(err, ierr.ErrNotFound) {
+		return false, err
+	}
+
 	plan, err := s.planRepo.FindByName(ctx, planName)
 	if err != nil {
-		return fmt.Errorf("could not find plan '%s': %w", planName, err)
+		return false, fmt.Errorf("could not find plan '%s': %w", planName, err)
 	}
-	return s.subRepo.Create(ctx, userID, plan.ID)
+	err = s.subRepo.Create(ctx, userID, plan.ID, s.defaultCurrency, domain.ChangeBySystem(domain.SubscriptionEventCreated, reason))
+	if errors.Is(err, ierr.ErrConflict) {
+		return false, nil // Created by a concurrent request
+	}
+	return err == nil, err
 }
 
 func (s *billingService) GetAllPlans(ctx context.Context) ([]domain.SubscriptionPlan, error) {
@@ -64,51 +142,341 @@ func (s *billingService) GetUserSubscription(ctx context.Context, userID int64)
 	return s.subRepo.FindDetailsByUserID(ctx, userID)
 }
 
-func (s *billingService) ChangeSubscription(ctx context.Context, userID, newPlanID int64) error {
-	plan, err := s.planRepo.FindByID(ctx, newPlanID)
+// GetSubscriptionHistory returns every change of the user's subscriptions, newest first.
+func (s *billingService) GetSubscriptionHistory(ctx context.Context, userID int64) ([]domain.SubscriptionHistoryEntry, error) {
+	return s.subRepo.FindHistoryByUserID(ctx, userID)
+}
+
+func (s *billingService) PreviewSubscriptionChange(ctx context.Context, userID, newPlanID int64, opts PlanChangeOptions) (*domain.ProrationPreview, error) {
+	sub, current, next, err := s.loadPlanChange(ctx, userID, newPlanID)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := s.billingCurrency(sub, current, next, opts.CurrencyPreference)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	preview := calculateProration(sub, current, next, currency, now)
+	if opts.CouponCode != "" {
+		if err := couponTakesEffect(preview, now); err != nil {
+			return nil, err
+		}
+		coupon, err := s.findRedeemableCoupon(ctx, opts.CouponCode, next.ID, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		applyCouponToPreview(preview, coupon)
+	}
+
+	tax, err := s.taxTreatmentFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tax.applyToPreview(preview)
+
+	if preview.StorageWarning, err = s.storageWarning(ctx, userID, next); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+func (s *billingService) ChangeSubscription(ctx context.Context, userID, newPlanID int64, opts PlanChangeOptions) (*domain.ProrationPreview, error) {
+	sub, current, next, err := s.loadPlanChange(ctx, userID, newPlanID)
 	if err != nil {
-		return fmt.Errorf("plan not found: %w", ierr.ErrNotFound)
+		return nil, err
+	}
+	currency, err := s.billingCurrency(sub, current, next, opts.CurrencyPreference)
+	if err != nil {
+		return nil, err
+	}
+
+	tax, err := s.taxTreatmentFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	preview := calculateProration(sub, current, next, currency, now)
+
+	if preview.StorageWarning, err = s.storageWarning(ctx, userID, next); err != nil {
+		return nil, err
+	}
+	if preview.StorageWarning != nil && preview.StorageWarning.Blocked {
+		return nil, fmt.Errorf("%s: %w", preview.StorageWarning.Message, ierr.ErrConflict)
+	}
+
+	var coupon *domain.Coupon
+	if opts.CouponCode != "" {
+		if err := couponTakesEffect(preview, now); err != nil {
+			return nil, err
+		}
+		if coupon, err = s.findRedeemableCoupon(ctx, opts.CouponCode, next.ID, currency, now); err != nil {
+			return nil, err
+		}
+		applyCouponToPreview(preview, coupon)
+	}
+	tax.applyToPreview(preview)
+
+	// Picking a plan explicitly supersedes a scheduled cancellation.
+	clearCancellation(sub)
+
+	if preview.EffectiveAt.After(now) {
+		sub.PendingPlanID = &next.ID
+		change := domain.ChangeByUser(domain.SubscriptionEventChangeScheduled, userID, fmt.Sprintf("Downgrade to %s", next.Name))
+		if err := s.subRepo.Update(ctx, sub, &change); err != nil {
+			return nil, err
+		}
+		log.Printf("User %d scheduled a downgrade to plan %d at %s.", userID, newPlanID, sub.EndsAt.Format(time.RFC3339))
+		return preview, nil
+	}
+
+	// The usage so far is billed on the plan it was used on, in the currency of the invoice.
+	sub.Currency = currency
+	usage, err := s.closingUsageLines(ctx, sub, now)
+	if err != nil {
+		return nil, err
+	}
+	sub.UsageBilledUntil = now
+
+	sub.PlanID = next.ID
+	sub.PlanVersionID = next.CurrentVersionID
+	sub.Status = domain.SubscriptionStatusActive
+	sub.PendingPlanID = nil
+	if !preview.PeriodEndsAt.Equal(sub.EndsAt) {
+		sub.StartsAt = now
+		sub.EndsAt = preview.PeriodEndsAt
+	}
+	// The change, its invoice and the coupon redemption are stored together, so a failed
+	// write leaves the plan and the coupon as they were and the change can simply be retried.
+	var writes repository.SubscriptionWrites
+	if preview.Charge.IsPositive() || preview.Credit.IsPositive() {
+		writes.Invoice = prorationInvoice(sub, current, next, preview, coupon)
+		addLines(writes.Invoice, usage)
+	} else if len(usage) > 0 {
+		writes.Invoice = linesInvoice(sub, usage)
+	}
+	if writes.Invoice != nil {
+		tax.applyToInvoice(writes.Invoice)
+	}
+	if coupon != nil {
+		writes.Redemption = newRedemption(sub, coupon, preview.Discount.IsPositive())
+	}
+	change := domain.ChangeByUser(domain.SubscriptionEventPlanChanged, userID, fmt.Sprintf("Changed from %s to %s", current.Name, next.Name))
+	if err := s.subRepo.UpdateWith(ctx, sub, change, writes); err != nil {
+		if coupon != nil && errors.Is(err, ierr.ErrConflict) {
+			return nil, fmt.Errorf("coupon '%s' cannot be redeemed: %w", coupon.Code, err)
+		}
+		return nil, err
 	}
-	if !plan.IsActive {
-		return fmt.Errorf("cannot switch to an inactive plan: %w", ierr.ErrConflict)
+
+	s.recheckStorage(ctx, userID)
+	log.Printf("User %d successfully changed subscription to plan %d. Quota sync queued.", userID, newPlanID)
+	return preview, nil
+}
+
+// ProcessDueSubscriptions rolls every subscription whose period has ended into the next one,
+// applying scheduled downgrades, converting finished trials and invoicing paid plans.
+func (s *billingService) ProcessDueSubscriptions(ctx context.Context) error {
+	now := time.Now()
+	if err := s.sendTrialReminders(ctx, now); err != nil {
+		log.Printf("Failed to send trial reminders: %v", err)
+	}
+	if err := s.applyVersionMigrations(ctx, now); err != nil {
+		log.Printf("Failed to apply plan version migrations: %v", err)
 	}
 
-	var newEndDate time.Time
-	if plan.Name == "Free" {
-		newEndDate = time.Now().AddDate(100, 0, 0)
-	} else {
-		newEndDate = time.Now().AddDate(0, 1, 0)
+	if err := s.endUnpaidSubscriptions(ctx, now); err != nil {
+		log.Printf("Failed to end unpaid subscriptions: %v", err)
 	}
 
-	if err := s.subRepo.Update(ctx, userID, newPlanID, newEndDate); err != nil {
+	subs, err := s.subRepo.FindDueForRenewal(ctx, now)
+	if err != nil {
 		return err
 	}
 
-	go s.syncUserQuotaWithNextcloud(userID, plan.Permissions)
+	for i := range subs {
+		if err := s.renewSubscription(ctx, &subs[i], now); err != nil {
+			log.Printf("Failed to renew subscription %d for user %d: %v", subs[i].ID, subs[i].UserID, err)
+		}
+	}
+	return nil
+}
 
-	log.Printf("User %d successfully changed subscription to plan %d. Quota sync initiated.", userID, newPlanID)
+// endUnpaidSubscriptions moves users whose renewal stayed unpaid for pastDueGrace to the
+// default plan and voids the unpaid invoices.
+func (s *billingService) endUnpaidSubscriptions(ctx context.Context, now time.Time) error {
+	subs, err := s.subRepo.FindPastDueSince(ctx, now.Add(-pastDueGrace))
+	if err != nil {
+		return err
+	}
+
+	for i := range subs {
+		sub := &subs[i]
+		change := domain.ChangeBySystem(domain.SubscriptionEventCanceled, "Renewal not paid")
+		if err := s.endSubscription(ctx, sub, now, change); err != nil {
+			log.Printf("Failed to end unpaid subscription %d of user %d: %v", sub.ID, sub.UserID, err)
+			continue
+		}
+		message := fmt.Sprintf("Your renewal was not paid within %d days, so you were moved to the %s plan.",
+			int(pastDueGrace.Hours()/24), defaultPlanName)
+		if err := s.notifier.Notify(ctx, sub.UserID, "Your subscription has ended", message); err != nil {
+			log.Printf("Failed to notify user %d about their ended subscription: %v", sub.UserID, err)
+		}
+	}
 	return nil
 }
 
-func (s *billingService) syncUserQuotaWithNextcloud(userID int64, permissions map[string]interface{}) {
-	ctx := context.Background()
+// --- Private methods ---
+
+// loadPlanChange fetches everything needed to price a plan change and validates the target plan.
+func (s *billingService) loadPlanChange(ctx context.Context, userID, newPlanID int64) (*domain.UserSubscription, *domain.SubscriptionPlan, *domain.SubscriptionPlan, error) {
+	next, err := s.planRepo.FindByID(ctx, newPlanID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("plan not found: %w", ierr.ErrNotFound)
+	}
+	if !next.IsActive {
+		return nil, nil, nil, fmt.Errorf("cannot switch to an inactive plan: %w", ierr.ErrConflict)
+	}
+
+	sub, err := s.subRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("active subscription not found: %w", err)
+	}
+	if sub.Status == domain.SubscriptionStatusPastDue {
+		return nil, nil, nil, fmt.Errorf("top up your balance to pay the open invoice first: %w", ierr.ErrConflict)
+	}
+	if sub.PlanID == next.ID && sub.PendingPlanID == nil {
+		return nil, nil, nil, fmt.Errorf("already subscribed to this plan: %w", ierr.ErrConflict)
+	}
+
+	current, err := s.subscribedPlan(ctx, sub)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return sub, current, next, nil
+}
+
+// storageWarning checks the user's stored data against the storage they would have on `next`.
+// Add-ons carry over to the new plan.
+func (s *billingService) storageWarning(ctx context.Context, userID int64, next *domain.SubscriptionPlan) (*domain.StorageWarning, error) {
+	current, err := s.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	addOns, err := s.addOnRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	quotaGB := domain.MergePermissions(next.Permissions, addOns).StorageQuotaGB
+	return s.overLimit.CheckPlanChange(ctx, userID, quotaGB, quotaGB < current.StorageQuotaGB)
+}
+
+// recheckStorage opens or resolves the user's over-limit case after their storage quota
+// changed. Failures are only logged, the next storage usage refresh catches up.
+func (s *billingService) recheckStorage(ctx context.Context, userID int64) {
+	permissions, err := s.GetUserPermissions(ctx, userID)
+	if err == nil {
+		err = s.overLimit.EvaluateQuota(ctx, userID, permissions.StorageQuotaGB)
+	}
+	if err != nil {
+		log.Printf("Failed to check the storage of user %d against their new quota: %v", userID, err)
+	}
+}
 
-	userDetails, err := s.userSvcClient.GetUserDetails(ctx, userID)
+func (s *billingService) renewSubscription(ctx context.Context, sub *domain.UserSubscription, now time.Time) error {
+	if sub.CancelAtPeriodEnd {
+		change := domain.ChangeBySystem(domain.SubscriptionEventCanceled, "Canceled at the end of the period")
+		return s.endSubscription(ctx, sub, sub.EndsAt, change)
+	}
+
+	// Metered usage is billed in arrears, on the terms of the period it was used in.
+	usage, err := s.closingUsageLines(ctx, sub, sub.EndsAt)
 	if err != nil {
-		log.Printf("CRITICAL: Failed to get details for user %d to sync quota: %v", userID, err)
-		return
+		return err
 	}
+	sub.UsageBilledUntil = sub.EndsAt
 
-	quotaGB, ok := permissions["storage_quota_gb"].(float64)
+	var plan *domain.SubscriptionPlan
+	if sub.PendingPlanID != nil {
+		// A scheduled downgrade starts on the latest version of the target plan
+		if plan, err = s.planRepo.FindByID(ctx, *sub.PendingPlanID); err != nil {
+			return fmt.Errorf("could not load plan %d: %w", *sub.PendingPlanID, err)
+		}
+		sub.PlanVersionID = plan.CurrentVersionID
+	} else if plan, err = s.subscribedPlan(ctx, sub); err != nil {
+		return err
+	}
+	price, ok := plan.PriceIn(sub.Currency)
 	if !ok {
-		log.Printf("Warning: 'storage_quota_gb' not found for user %d", userID)
-		return
+		return fmt.Errorf("plan %d has no price in %s: %w", plan.ID, sub.Currency, ierr.ErrConflict)
+	}
+	addOns, err := s.addOnRepo.FindByUserID(ctx, sub.UserID)
+	if err != nil {
+		return err
+	}
+	if err := addOnsSoldIn(addOns, sub.Currency); err != nil {
+		return err
+	}
+	billable := price.IsPositive() || len(addOns) > 0 || len(usage) > 0
+	var tax taxTreatment
+	if billable {
+		if tax, err = s.taxTreatmentFor(ctx, sub.UserID); err != nil {
+			return err
+		}
+	}
+
+	planChanged := plan.ID != sub.PlanID
+	change := domain.ChangeBySystem(domain.SubscriptionEventRenewed, "")
+	if planChanged {
+		change = domain.ChangeBySystem(domain.SubscriptionEventPlanChanged, fmt.Sprintf("Scheduled change to %s", plan.Name))
+	} else if sub.Status == domain.SubscriptionStatusTrialing {
+		change.Reason = "Trial converted"
+	}
+	sub.PlanID = plan.ID
+	sub.Status = domain.SubscriptionStatusActive // A finished trial converts into a paid period
+	sub.PendingPlanID = nil
+	sub.StartsAt = sub.EndsAt
+	sub.EndsAt = billingPeriodEnd(plan, addOns, sub.StartsAt)
+	if !sub.EndsAt.After(now) {
+		// The subscription lapsed for longer than a whole period, restart it from now.
+		sub.StartsAt = now
+		sub.EndsAt = billingPeriodEnd(plan, addOns, now)
 	}
 
-	if err := s.nextcloudClient.SetUserQuota(ctx, userDetails.Email, int(quotaGB)); err != nil {
-		log.Printf("CRITICAL: Failed to set Nextcloud quota for user %s (ID: %d): %v", userDetails.Email, userID, err)
-		return
+	// The new period and its invoice are stored together, a renewal that fails is retried as
+	// a whole by the next run. An invoice the balance does not cover leaves the subscription
+	// past due until a top-up settles it.
+	writes := repository.SubscriptionWrites{PastDueIfUnpaid: true}
+	if billable {
+		writes.Invoice = renewalInvoice(sub, plan, price, addOns, usage)
+		redemption, err := s.applyRecurringDiscount(ctx, sub, writes.Invoice)
+		if err != nil {
+			return fmt.Errorf("failed to apply coupon: %w", err)
+		}
+		if redemption != nil && redemption.PeriodsRemaining != nil {
+			writes.ConsumedRedemptionID = &redemption.ID
+		}
+		tax.applyToInvoice(writes.Invoice)
+	}
+	if err := s.subRepo.UpdateWith(ctx, sub, change, writes); err != nil {
+		return err
+	}
+	if planChanged {
+		s.recheckStorage(ctx, sub.UserID)
 	}
 
-	log.Printf("Successfully synced quota for user %s to %d GB.", userDetails.Email, int(quotaGB))
+	if sub.Status == domain.SubscriptionStatusPastDue {
+		due := writes.Invoice.Total.Sub(writes.Invoice.PaidFromBalance)
+		message := fmt.Sprintf("Your balance did not cover the renewal of your %s plan. Top up %s within %d days to keep it.",
+			plan.Name, due, int(pastDueGrace.Hours()/24))
+		if err := s.notifier.Notify(ctx, sub.UserID, "Your renewal is unpaid", message); err != nil {
+			log.Printf("Failed to notify user %d about their unpaid renewal: %v", sub.UserID, err)
+		}
+		log.Printf("Subscription %d of user %d is past due, invoice %d is unpaid.", sub.ID, sub.UserID, writes.Invoice.ID)
+		return nil
+	}
+	log.Printf("Renewed subscription %d for user %d on plan %d until %s.", sub.ID, sub.UserID, plan.ID, sub.EndsAt.Format(time.RFC3339))
+	return nil
 }
@@ -0,0 +1,145 @@
+// services/billing-service/internal/service/cancellation.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"time"
+)
+
+// CancelSubscription cancels the user's paid subscription either right away or when the
+// already paid period ends. In both cases the user falls back to the default plan afterwards.
+func (s *billingService) CancelSubscription(ctx context.Context, userID int64, atPeriodEnd bool, reason, feedback string) (*domain.UserSubscriptionDetails, error) {
+	if reason != "" && !domain.IsValidCancelReason(reason) {
+		return nil, fmt.Errorf("unknown cancellation reason '%s': %w", reason, ierr.ErrInvalidInput)
+	}
+
+	sub, err := s.subRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("active subscription not found: %w", err)
+	}
+	plan, err := s.planRepo.FindByID(ctx, sub.PlanID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load current plan %d: %w", sub.PlanID, err)
+	}
+	if plan.Name == defaultPlanName {
+		return nil, fmt.Errorf("the %s plan cannot be canceled: %w", defaultPlanName, ierr.ErrConflict)
+	}
+	if sub.CancelAtPeriodEnd && atPeriodEnd {
+		return nil, fmt.Errorf("subscription is already scheduled for cancellation: %w", ierr.ErrConflict)
+	}
+	if sub.Status == domain.SubscriptionStatusPastDue && atPeriodEnd {
+		// The unpaid period is never renewed, so there is no period end to cancel at.
+		return nil, fmt.Errorf("an unpaid subscription can only be canceled right away: %w", ierr.ErrConflict)
+	}
+
+	now := time.Now()
+	sub.CanceledAt = &now
+	sub.CancelReason = optionalString(reason)
+	sub.CancelFeedback = optionalString(feedback)
+	// A scheduled downgrade is meaningless once the subscription is canceled.
+	sub.PendingPlanID = nil
+
+	if atPeriodEnd {
+		sub.CancelAtPeriodEnd = true
+		change := domain.ChangeByUser(domain.SubscriptionEventCancellationScheduled, userID, reason)
+		if err := s.subRepo.Update(ctx, sub, &change); err != nil {
+			return nil, err
+		}
+		log.Printf("User %d scheduled cancellation of subscription %d at %s.", userID, sub.ID, sub.EndsAt.Format(time.RFC3339))
+	} else {
+		if err := s.endSubscription(ctx, sub, now, domain.ChangeByUser(domain.SubscriptionEventCanceled, userID, reason)); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.subRepo.FindDetailsByUserID(ctx, userID)
+}
+
+// ResumeSubscription withdraws a scheduled cancellation while the paid period is still running.
+func (s *billingService) ResumeSubscription(ctx context.Context, userID int64) (*domain.UserSubscriptionDetails, error) {
+	sub, err := s.subRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("active subscription not found: %w", err)
+	}
+	if !sub.CancelAtPeriodEnd {
+		return nil, fmt.Errorf("subscription is not scheduled for cancellation: %w", ierr.ErrConflict)
+	}
+
+	clearCancellation(sub)
+	change := domain.ChangeByUser(domain.SubscriptionEventResumed, userID, "")
+	if err := s.subRepo.Update(ctx, sub, &change); err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %d resumed subscription %d.", userID, sub.ID)
+	return s.subRepo.FindDetailsByUserID(ctx, userID)
+}
+
+// endSubscription marks the subscription CANCELED as of `at` and moves the user to the default
+// plan in one transaction, which shrinks their Nextcloud quota accordingly. Add-ons stay and
+// are billed on the new plan.
+func (s *billingService) endSubscription(ctx context.Context, sub *domain.UserSubscription, at time.Time, change domain.SubscriptionChange) error {
+	freePlan, err := s.planRepo.FindByName(ctx, defaultPlanName)
+	if err != nil {
+		return fmt.Errorf("could not find plan '%s': %w", defaultPlanName, err)
+	}
+
+	// The usage up to the end is billed on the plan it was used on, together with the end.
+	usage, err := s.closingUsageLines(ctx, sub, at)
+	if err != nil {
+		return err
+	}
+	final, err := s.finalUsageInvoice(ctx, sub, usage)
+	if err != nil {
+		return err
+	}
+	if at.After(sub.UsageBilledUntil) {
+		sub.UsageBilledUntil = at
+	}
+
+	// A past-due period was never paid: its invoices are voided with it and add-ons are only
+	// paid until it started.
+	unpaid := sub.Status == domain.SubscriptionStatusPastDue
+	paidUntil := sub.EndsAt
+	if unpaid {
+		paidUntil = sub.StartsAt
+	}
+	sub.Status = domain.SubscriptionStatusCanceled
+	sub.EndsAt = at
+	sub.CancelAtPeriodEnd = false
+	moved := change
+	moved.Event = domain.SubscriptionEventCreated
+	moved.Reason = fmt.Sprintf("Subscription %d ended", sub.ID)
+	replace := s.subRepo.Replace
+	if unpaid {
+		replace = s.subRepo.ReplaceUnpaid
+	}
+	if err := replace(ctx, sub, change, final, freePlan.ID, moved); err != nil {
+		return fmt.Errorf("failed to move user %d to the '%s' plan: %w", sub.UserID, defaultPlanName, err)
+	}
+	if err := s.carryOverAddOns(ctx, sub.UserID, at, paidUntil); err != nil {
+		log.Printf("Failed to carry over add-ons of user %d to the %s plan: %v", sub.UserID, defaultPlanName, err)
+	}
+	s.recheckStorage(ctx, sub.UserID)
+
+	log.Printf("Subscription %d of user %d canceled, user moved to the %s plan.", sub.ID, sub.UserID, defaultPlanName)
+	return nil
+}
+
+func clearCancellation(sub *domain.UserSubscription) {
+	sub.CancelAtPeriodEnd = false
+	sub.CanceledAt = nil
+	sub.CancelReason = nil
+	sub.CancelFeedback = nil
+}
+
+func optionalString(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,161 @@
+// services/billing-service/internal/service/coupon_service.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CouponService is the admin side of coupons. Redemption happens in BillingService.
+type CouponService interface {
+	GetAllCoupons(ctx context.Context) ([]domain.Coupon, error)
+	GetCoupon(ctx context.Context, id int64) (*domain.Coupon, error)
+	CreateCoupon(ctx context.Context, coupon *domain.Coupon) (*domain.Coupon, error)
+	PatchCoupon(ctx context.Context, id int64, patch CouponPatch) (*domain.Coupon, error)
+	DeactivateCoupon(ctx context.Context, id int64) error
+}
+
+// CouponPatch holds the coupon fields an admin may change, nil fields are left untouched.
+type CouponPatch struct {
+	MaxRedemptions *int
+	ExpiresAt      *time.Time
+	PlanIDs        *[]int64
+	IsActive       *bool
+}
+
+type couponService struct {
+	couponRepo repository.CouponRepository
+	planRepo   repository.PlanRepository
+}
+
+func NewCouponService(couponRepo repository.CouponRepository, planRepo repository.PlanRepository) CouponService {
+	return &couponService{
+		couponRepo: couponRepo,
+		planRepo:   planRepo,
+	}
+}
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,64}$`)
+
+func (s *couponService) GetAllCoupons(ctx context.Context) ([]domain.Coupon, error) {
+	return s.couponRepo.FindAll(ctx)
+}
+
+func (s *couponService) GetCoupon(ctx context.Context, id int64) (*domain.Coupon, error) {
+	return s.couponRepo.FindByID(ctx, id)
+}
+
+func (s *couponService) CreateCoupon(ctx context.Context, coupon *domain.Coupon) (*domain.Coupon, error) {
+	coupon.Code = normalizeCouponCode(coupon.Code)
+	coupon.IsActive = true
+	if coupon.PlanIDs == nil {
+		coupon.PlanIDs = []int64{}
+	}
+	if err := s.validateCoupon(ctx, coupon); err != nil {
+		return nil, err
+	}
+
+	if err := s.couponRepo.Create(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("coupon '%s' already exists: %w", coupon.Code, err)
+	}
+	return coupon, nil
+}
+
+// PatchCoupon changes availability settings only. Discount and duration stay immutable so that
+// already issued invoices and running redemptions keep matching their coupon.
+func (s *couponService) PatchCoupon(ctx context.Context, id int64, patch CouponPatch) (*domain.Coupon, error) {
+	coupon, err := s.couponRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.MaxRedemptions != nil {
+		coupon.MaxRedemptions = patch.MaxRedemptions
+	}
+	if patch.ExpiresAt != nil {
+		coupon.ExpiresAt = patch.ExpiresAt
+	}
+	if patch.PlanIDs != nil {
+		coupon.PlanIDs = *patch.PlanIDs
+	}
+	if patch.IsActive != nil {
+		coupon.IsActive = *patch.IsActive
+	}
+	if err := s.validateCoupon(ctx, coupon); err != nil {
+		return nil, err
+	}
+	if coupon.MaxRedemptions != nil && *coupon.MaxRedemptions < coupon.TimesRedeemed {
+		return nil, fmt.Errorf("coupon has already been redeemed %d times: %w", coupon.TimesRedeemed, ierr.ErrConflict)
+	}
+
+	if err := s.couponRepo.Update(ctx, coupon); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// DeactivateCoupon stops new redemptions. Coupons are never deleted because invoices reference them.
+func (s *couponService) DeactivateCoupon(ctx context.Context, id int64) error {
+	coupon, err := s.couponRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	coupon.IsActive = false
+	return s.couponRepo.Update(ctx, coupon)
+}
+
+func (s *couponService) validateCoupon(ctx context.Context, c *domain.Coupon) error {
+	if !couponCodePattern.MatchString(c.Code) {
+		return fmt.Errorf("coupon code must be 3-64 characters of A-Z, 0-9, '_' or '-': %w", ierr.ErrInvalidInput)
+	}
+
+	switch c.DiscountType {
+	case domain.DiscountTypePercent:
+		if c.PercentOff <= 0 || c.PercentOff > 100 {
+			return fmt.Errorf("percent_off must be in (0, 100]: %w", ierr.ErrInvalidInput)
+		}
+		c.AmountOff = nil
+	case domain.DiscountTypeFixed:
+		if c.AmountOff == nil || !c.AmountOff.IsPositive() {
+			return fmt.Errorf("amount_off must be positive: %w", ierr.ErrInvalidInput)
+		}
+		if !domain.IsSupportedCurrency(c.AmountOff.Currency) {
+			return fmt.Errorf("unsupported currency '%s': %w", c.AmountOff.Currency, ierr.ErrInvalidInput)
+		}
+		c.PercentOff = 0
+	default:
+		return fmt.Errorf("unknown discount type '%s': %w", c.DiscountType, ierr.ErrInvalidInput)
+	}
+
+	switch c.Duration {
+	case domain.CouponDurationOnce, domain.CouponDurationForever:
+		c.DurationMonths = nil
+	case domain.CouponDurationRepeating:
+		if c.DurationMonths == nil || *c.DurationMonths <= 0 {
+			return fmt.Errorf("duration_months must be positive for repeating coupons: %w", ierr.ErrInvalidInput)
+		}
+	default:
+		return fmt.Errorf("unknown coupon duration '%s': %w", c.Duration, ierr.ErrInvalidInput)
+	}
+
+	if c.MaxRedemptions != nil && *c.MaxRedemptions <= 0 {
+		return fmt.Errorf("max_redemptions must be positive: %w", ierr.ErrInvalidInput)
+	}
+
+	for _, planID := range c.PlanIDs {
+		if _, err := s.planRepo.FindByID(ctx, planID); err != nil {
+			return fmt.Errorf("plan %d does not exist: %w", planID, ierr.ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,57 @@
+// services/billing-service/internal/service/currency.go
+package service
+
+import (
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"strings"
+)
+
+// CurrencyPreference is how a user wants to be billed: an explicit ISO 4217 currency, or a
+// locale (e.g. an Accept-Language header) to derive one from. Both are optional.
+type CurrencyPreference struct {
+	Currency string
+	Locale   string
+}
+
+// PlanChangeOptions are the optional parameters of a plan change.
+type PlanChangeOptions struct {
+	CouponCode string
+	CurrencyPreference
+}
+
+// billingCurrency picks the currency `sub` is billed in after moving from `current` to `next`.
+//
+// A paid subscription keeps its currency for as long as it lasts. Leaving a free plan, the
+// explicitly requested currency wins, then the one of the user's locale if the plan is sold in
+// it, then the default currency, which every paid plan has a price in.
+func (s *billingService) billingCurrency(sub *domain.UserSubscription, current, next *domain.SubscriptionPlan, pref CurrencyPreference) (string, error) {
+	requested := strings.ToUpper(strings.TrimSpace(pref.Currency))
+	if requested != "" && !domain.IsSupportedCurrency(requested) {
+		return "", fmt.Errorf("unsupported currency '%s': %w", requested, ierr.ErrInvalidInput)
+	}
+
+	var currency string
+	switch {
+	case !current.IsFree():
+		if requested != "" && requested != sub.Currency {
+			return "", fmt.Errorf("subscription is billed in %s, its currency cannot be changed: %w", sub.Currency, ierr.ErrConflict)
+		}
+		currency = sub.Currency
+	case requested != "":
+		currency = requested
+	default:
+		currency = s.defaultCurrency
+		if localCurrency := domain.CurrencyForLocale(pref.Locale); localCurrency != "" {
+			if _, ok := next.PriceIn(localCurrency); ok {
+				currency = localCurrency
+			}
+		}
+	}
+
+	if _, ok := next.PriceIn(currency); !ok {
+		return "", fmt.Errorf("plan '%s' is not available in %s: %w", next.Name, currency, ierr.ErrConflict)
+	}
+	return currency, nil
+}
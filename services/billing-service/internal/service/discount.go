@@ -0,0 +1,138 @@
+// services/billing-service/internal/service/discount.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"math"
+	"time"
+)
+
+// findRedeemableCoupon looks up a coupon code and checks that it can be used for the plan,
+// billed in currency, right now.
+func (s *billingService) findRedeemableCoupon(ctx context.Context, code string, planID int64, currency string, now time.Time) (*domain.Coupon, error) {
+	coupon, err := s.couponRepo.FindByCode(ctx, normalizeCouponCode(code))
+	if err != nil {
+		return nil, fmt.Errorf("coupon '%s' not found: %w", code, err)
+	}
+
+	switch {
+	case !coupon.IsActive,
+		coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(now),
+		coupon.MaxRedemptions != nil && coupon.TimesRedeemed >= *coupon.MaxRedemptions:
+		return nil, fmt.Errorf("coupon '%s' is no longer valid: %w", coupon.Code, ierr.ErrConflict)
+	case !coupon.AppliesToPlan(planID):
+		return nil, fmt.Errorf("coupon '%s' cannot be used with this plan: %w", coupon.Code, ierr.ErrConflict)
+	case coupon.AmountOff != nil && coupon.AmountOff.Currency != currency:
+		return nil, fmt.Errorf("coupon '%s' can only be used when paying in %s: %w", coupon.Code, coupon.AmountOff.Currency, ierr.ErrConflict)
+	}
+	return coupon, nil
+}
+
+// couponTakesEffect rejects coupons for scheduled plan changes. Nothing is invoiced for them
+// until the next renewal, so redeeming the coupon right away would only spend it.
+func couponTakesEffect(preview *domain.ProrationPreview, now time.Time) error {
+	if preview.EffectiveAt.After(now) {
+		return fmt.Errorf("coupons can only be used with a plan change that takes effect right away: %w", ierr.ErrConflict)
+	}
+	return nil
+}
+
+// applyCouponToPreview reduces the amount due of a plan change by the coupon's discount.
+func applyCouponToPreview(preview *domain.ProrationPreview, coupon *domain.Coupon) {
+	preview.CouponCode = coupon.Code
+	preview.Discount = couponDiscount(coupon, preview.AmountDue)
+	preview.AmountDue = preview.AmountDue.Sub(preview.Discount)
+}
+
+// newRedemption redeems the coupon for the subscription. If the plan change itself was
+// discounted, that invoice already used up one of the coupon's periods.
+func newRedemption(sub *domain.UserSubscription, coupon *domain.Coupon, discounted bool) *domain.CouponRedemption {
+	redemption := &domain.CouponRedemption{
+		CouponID:         coupon.ID,
+		UserID:           sub.UserID,
+		SubscriptionID:   sub.ID,
+		PeriodsRemaining: couponPeriods(coupon),
+		IsActive:         true,
+	}
+	if discounted && redemption.PeriodsRemaining != nil {
+		*redemption.PeriodsRemaining--
+		redemption.IsActive = *redemption.PeriodsRemaining > 0
+	}
+	return redemption
+}
+
+// applyRecurringDiscount adds the discount of the subscription's active coupon to a renewal
+// invoice. It returns the redemption that was applied, or nil if there is none.
+func (s *billingService) applyRecurringDiscount(ctx context.Context, sub *domain.UserSubscription, invoice *domain.Invoice) (*domain.CouponRedemption, error) {
+	redemption, err := s.couponRepo.FindActiveRedemption(ctx, sub.ID)
+	if err != nil {
+		if errors.Is(err, ierr.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	coupon, err := s.couponRepo.FindByID(ctx, redemption.CouponID)
+	if err != nil {
+		return nil, err
+	}
+	if !coupon.AppliesToPlan(sub.PlanID) {
+		return nil, nil
+	}
+
+	discount := couponDiscount(coupon, invoice.Total)
+	if !discount.IsPositive() {
+		return nil, nil
+	}
+	invoice.Lines = append(invoice.Lines, discountLine(coupon, discount))
+	invoice.Total = invoice.Total.Sub(discount)
+	return redemption, nil
+}
+
+// couponDiscount returns how much the coupon takes off `amount`; it never exceeds the amount.
+// A fixed discount only applies to amounts in its own currency.
+func couponDiscount(coupon *domain.Coupon, amount domain.Money) domain.Money {
+	discount := domain.NewMoney(0, amount.Currency)
+	if !amount.IsPositive() {
+		return discount
+	}
+	switch coupon.DiscountType {
+	case domain.DiscountTypePercent:
+		discount = amount.MulBasisPoints(int64(math.Round(coupon.PercentOff * 100)))
+	case domain.DiscountTypeFixed:
+		if coupon.AmountOff != nil && coupon.AmountOff.Currency == amount.Currency {
+			discount = *coupon.AmountOff
+		}
+	}
+	return discount.Min(amount)
+}
+
+// couponPeriods returns for how many invoices a fresh redemption applies, nil means forever.
+func couponPeriods(coupon *domain.Coupon) *int {
+	var periods int
+	switch coupon.Duration {
+	case domain.CouponDurationOnce:
+		periods = 1
+	case domain.CouponDurationRepeating:
+		periods = *coupon.DurationMonths
+	default:
+		return nil
+	}
+	return &periods
+}
+
+func discountLine(coupon *domain.Coupon, discount domain.Money) domain.InvoiceLine {
+	description := fmt.Sprintf("Coupon %s", coupon.Code)
+	if coupon.DiscountType == domain.DiscountTypePercent {
+		description = fmt.Sprintf("Coupon %s (%g%% off)", coupon.Code, coupon.PercentOff)
+	}
+	return domain.InvoiceLine{
+		Kind:        domain.InvoiceLineDiscount,
+		Description: description,
+		Amount:      discount.Neg(),
+		CouponID:    &coupon.ID,
+	}
+}
@@ -0,0 +1,40 @@
+// services/billing-service/internal/service/discount_test.go
+package service
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"testing"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	percent := func(off float64) *domain.Coupon {
+		return &domain.Coupon{DiscountType: domain.DiscountTypePercent, PercentOff: off}
+	}
+	fixed := func(off domain.Money) *domain.Coupon {
+		return &domain.Coupon{DiscountType: domain.DiscountTypeFixed, AmountOff: &off}
+	}
+
+	tests := []struct {
+		name   string
+		coupon *domain.Coupon
+		amount domain.Money
+		want   domain.Money
+	}{
+		{"percent", percent(20), domain.NewMoney(1000, "EUR"), domain.NewMoney(200, "EUR")},
+		{"fractional percent rounds", percent(12.5), domain.NewMoney(999, "EUR"), domain.NewMoney(125, "EUR")}, // 124.875
+		{"full percent", percent(100), domain.NewMoney(999, "EUR"), domain.NewMoney(999, "EUR")},
+		{"percent in a zero-decimal currency", percent(15), domain.NewMoney(1000, "JPY"), domain.NewMoney(150, "JPY")},
+		{"fixed", fixed(domain.NewMoney(500, "EUR")), domain.NewMoney(1200, "EUR"), domain.NewMoney(500, "EUR")},
+		{"fixed capped at the amount", fixed(domain.NewMoney(500, "EUR")), domain.NewMoney(300, "EUR"), domain.NewMoney(300, "EUR")},
+		{"fixed in another currency", fixed(domain.NewMoney(500, "USD")), domain.NewMoney(1200, "EUR"), domain.NewMoney(0, "EUR")},
+		{"nothing due", percent(50), domain.NewMoney(0, "EUR"), domain.NewMoney(0, "EUR")},
+		{"credit", fixed(domain.NewMoney(500, "EUR")), domain.NewMoney(-300, "EUR"), domain.NewMoney(0, "EUR")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := couponDiscount(tt.coupon, tt.amount); got != tt.want {
+				t.Errorf("couponDiscount(%v) = %v, want %v", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
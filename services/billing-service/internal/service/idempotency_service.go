@@ -0,0 +1,70 @@
+// services/billing-service/internal/service/idempotency_service.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"time"
+)
+
+const (
+	// maxIdempotencyKeyLength is the longest idempotency key accepted.
+	maxIdempotencyKeyLength = 255
+	// idempotencyLease is how long a request may hold its key without completing before a
+	// retry may run it again, e.g. after the service crashed mid-request.
+	idempotencyLease = time.Minute
+)
+
+// IdempotencyService lets callers retry writes safely: the first request with a key runs, and
+// retries with the same key get its response instead of running it again.
+type IdempotencyService interface {
+	// Begin claims the key for a request with the given hash. It returns nil if the request
+	// should run, or the stored response of the earlier request with the key. A key that is
+	// still in progress or was used for a different request fails with ierr.ErrConflict.
+	Begin(ctx context.Context, scope, key, requestHash string) (*domain.IdempotencyRecord, error)
+	// Complete stores the response of a request that Begin let run.
+	Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error
+	// Release frees the key of a request that failed, so that a retry runs it again.
+	Release(ctx context.Context, scope, key string) error
+}
+
+type idempotencyService struct {
+	idempotencyRepo repository.IdempotencyRepository
+	ttl             time.Duration
+}
+
+// NewIdempotencyService returns an IdempotencyService that keeps responses for ttl, after
+// which their keys may be reused.
+func NewIdempotencyService(idempotencyRepo repository.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{idempotencyRepo: idempotencyRepo, ttl: ttl}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, scope, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("idempotency key must be at most %d characters: %w", maxIdempotencyKeyLength, ierr.ErrInvalidInput)
+	}
+
+	now := time.Now()
+	rec, err := s.idempotencyRepo.Reserve(ctx, scope, key, requestHash, now.Add(-s.ttl), now.Add(-idempotencyLease))
+	if err != nil || rec == nil {
+		return nil, err
+	}
+	if rec.RequestHash != requestHash {
+		return nil, fmt.Errorf("idempotency key '%s' was already used for a different request: %w", key, ierr.ErrConflict)
+	}
+	if rec.StatusCode == nil {
+		return nil, fmt.Errorf("a request with idempotency key '%s' is still in progress: %w", key, ierr.ErrConflict)
+	}
+	return rec, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	return s.idempotencyRepo.Complete(ctx, scope, key, statusCode, contentType, body)
+}
+
+func (s *idempotencyService) Release(ctx context.Context, scope, key string) error {
+	return s.idempotencyRepo.Release(ctx, scope, key)
+}
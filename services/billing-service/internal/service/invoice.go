@@ -0,0 +1,85 @@
+// services/billing-service/internal/service/invoice.go
+package service
+
+import (
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"time"
+)
+
+// prorationInvoice builds the untaxed invoice for an immediate plan change.
+func prorationInvoice(sub *domain.UserSubscription, current, next *domain.SubscriptionPlan, preview *domain.ProrationPreview, coupon *domain.Coupon) *domain.Invoice {
+	invoice := &domain.Invoice{
+		UserID:         sub.UserID,
+		SubscriptionID: sub.ID,
+		Status:         domain.InvoiceStatusOpen,
+		Total:          preview.Subtotal,
+	}
+	if preview.Credit.IsPositive() {
+		invoice.Lines = append(invoice.Lines, domain.InvoiceLine{
+			Kind:        domain.InvoiceLineProrationCredit,
+			Description: fmt.Sprintf("Unused time on %s plan", current.Name),
+			Amount:      preview.Credit.Neg(),
+		})
+	}
+	invoice.Lines = append(invoice.Lines, domain.InvoiceLine{
+		Kind:        domain.InvoiceLineProrationCharge,
+		Description: fmt.Sprintf("Remaining time on %s plan until %s", next.Name, preview.PeriodEndsAt.Format(time.DateOnly)),
+		Amount:      preview.Charge,
+	})
+	if coupon != nil && preview.Discount.IsPositive() {
+		invoice.Lines = append(invoice.Lines, discountLine(coupon, preview.Discount))
+	}
+	return invoice
+}
+
+// renewalInvoice builds the untaxed invoice for a new billing period of a plan at `price` and
+// the user's add-ons, together with the metered usage of the period that just ended.
+func renewalInvoice(sub *domain.UserSubscription, plan *domain.SubscriptionPlan, price domain.Money, addOns []domain.UserAddOn, usage []domain.InvoiceLine) *domain.Invoice {
+	var lines []domain.InvoiceLine
+	if price.IsPositive() {
+		lines = append(lines, domain.InvoiceLine{
+			Kind:        domain.InvoiceLineSubscription,
+			Description: fmt.Sprintf("%s plan, %s – %s", plan.Name, sub.StartsAt.Format(time.DateOnly), sub.EndsAt.Format(time.DateOnly)),
+			Amount:      price,
+		})
+	}
+	lines = append(lines, addOnLines(sub, addOns)...)
+	return linesInvoice(sub, append(lines, usage...))
+}
+
+// addOnLines charges the add-ons for the subscription's current period. Every add-on must
+// have a price in the subscription's currency.
+func addOnLines(sub *domain.UserSubscription, addOns []domain.UserAddOn) []domain.InvoiceLine {
+	lines := make([]domain.InvoiceLine, 0, len(addOns))
+	for _, ua := range addOns {
+		price, _ := ua.AddOn.PriceIn(sub.Currency)
+		lines = append(lines, domain.InvoiceLine{
+			Kind: domain.InvoiceLineAddOn,
+			Description: fmt.Sprintf("%s × %d, %s – %s", ua.AddOn.Name, ua.Quantity,
+				sub.StartsAt.Format(time.DateOnly), sub.EndsAt.Format(time.DateOnly)),
+			Amount: price.Prorate(int64(ua.Quantity), 1),
+		})
+	}
+	return lines
+}
+
+// linesInvoice builds the untaxed invoice for the given lines, its total is their sum.
+func linesInvoice(sub *domain.UserSubscription, lines []domain.InvoiceLine) *domain.Invoice {
+	invoice := &domain.Invoice{
+		UserID:         sub.UserID,
+		SubscriptionID: sub.ID,
+		Status:         domain.InvoiceStatusOpen,
+		Total:          domain.NewMoney(0, sub.Currency),
+	}
+	addLines(invoice, lines)
+	return invoice
+}
+
+// addLines adds untaxed lines to an invoice and its total.
+func addLines(invoice *domain.Invoice, lines []domain.InvoiceLine) {
+	for _, line := range lines {
+		invoice.Lines = append(invoice.Lines, line)
+		invoice.Total = invoice.Total.Add(line.Amount)
+	}
+}
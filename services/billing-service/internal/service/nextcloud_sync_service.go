@@ -0,0 +1,210 @@
+// services/billing-service/internal/service/nextcloud_sync_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/client"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/entitlements"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"time"
+)
+
+// maxListedNextcloudSyncs caps how many syncs an admin listing returns.
+const maxListedNextcloudSyncs = 200
+
+// NextcloudSyncService delivers the Nextcloud outbox, which the repositories fill in the same
+// transaction as the changes that require a sync.
+type NextcloudSyncService interface {
+	// DeliverDue sends all due syncs. Failures are retried with exponential backoff and the
+	// sync is dead-lettered once it has failed maxAttempts times.
+	DeliverDue(ctx context.Context) error
+	GetSyncs(ctx context.Context, status string, limit int) ([]domain.NextcloudSync, error)
+	ReplaySync(ctx context.Context, id int64) (*domain.NextcloudSync, error)
+}
+
+type nextcloudSyncService struct {
+	outboxRepo      repository.NextcloudOutboxRepository
+	planRepo        repository.PlanRepository
+	billingService  BillingService
+	userSvcClient   client.UserServiceClient
+	nextcloudClient client.NextcloudClient
+	outbox          outboxDelivery
+}
+
+func NewNextcloudSyncService(outboxRepo repository.NextcloudOutboxRepository, planRepo repository.PlanRepository, billingService BillingService, userSvcClient client.UserServiceClient, ncClient client.NextcloudClient, maxAttempts int, retryDelay time.Duration) NextcloudSyncService {
+	return &nextcloudSyncService{
+		outboxRepo:      outboxRepo,
+		planRepo:        planRepo,
+		billingService:  billingService,
+		userSvcClient:   userSvcClient,
+		nextcloudClient: ncClient,
+		outbox:          outboxDelivery{maxAttempts: maxAttempts, retryDelay: retryDelay},
+	}
+}
+
+func (s *nextcloudSyncService) DeliverDue(ctx context.Context) error {
+	return deliverDue(ctx, s.outboxRepo.Claim, s.deliver)
+}
+
+func (s *nextcloudSyncService) deliver(ctx context.Context, sync *domain.NextcloudSync) {
+	var err error
+	switch sync.Kind {
+	case domain.NextcloudSyncQuota:
+		err = s.syncQuota(ctx, sync.UserID)
+	case domain.NextcloudSyncGroups:
+		err = s.syncGroups(ctx, sync.UserID)
+	default:
+		err = fmt.Errorf("unknown sync kind '%s'", sync.Kind)
+	}
+
+	if err == nil {
+		if err := s.outboxRepo.MarkDelivered(ctx, sync.ID, sync.Generation); err != nil {
+			log.Printf("Failed to mark Nextcloud sync %d as delivered: %v", sync.ID, err)
+		}
+		return
+	}
+
+	what := fmt.Sprintf("Nextcloud %s sync %d of user %d", sync.Kind, sync.ID, sync.UserID)
+	s.outbox.recordFailure(what, sync.Attempts+1, err, func(next time.Time, dead bool) error {
+		return s.outboxRepo.MarkFailed(ctx, sync.ID, err.Error(), next, dead)
+	})
+}
+
+// syncQuota sets the user's Nextcloud quota to the storage of their plan and add-ons, as
+// stored at the time of the call. Users without a Nextcloud account yet get one, so the same
+// sync provisions new users.
+func (s *nextcloudSyncService) syncQuota(ctx context.Context, userID int64) error {
+	permissions, err := s.billingService.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get permissions: %w", err)
+	}
+	quotaGB := permissions.StorageQuotaGB
+
+	userDetails, err := s.userSvcClient.GetUserDetails(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user details: %w", err)
+	}
+
+	exists, err := s.nextcloudClient.UserExists(ctx, userDetails.Email)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %w", userDetails.Email, err)
+	}
+	if !exists {
+		return s.provisionAccount(ctx, userID, userDetails.Email, quotaGB)
+	}
+
+	if err := s.nextcloudClient.SetUserQuota(ctx, userDetails.Email, quotaGB); err != nil {
+		return fmt.Errorf("failed to set quota of %s: %w", userDetails.Email, err)
+	}
+
+	log.Printf("Successfully synced quota for user %s to %d GB.", userDetails.Email, quotaGB)
+	return nil
+}
+
+// provisionAccount creates the user's Nextcloud account, named after their email. It has no
+// password: Nextcloud emails the user a link to set their own. If the account was created
+// meanwhile, e.g. by an earlier attempt, only the quota is set.
+func (s *nextcloudSyncService) provisionAccount(ctx context.Context, userID int64, email string, quotaGB int) error {
+	err := s.nextcloudClient.CreateUser(ctx, client.NextcloudNewUser{
+		Username: email,
+		Email:    email,
+		QuotaGB:  quotaGB,
+	})
+	if errors.Is(err, client.ErrNextcloudUserExists) {
+		err = s.nextcloudClient.SetUserQuota(ctx, email, quotaGB)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to provision %s: %w", email, err)
+	}
+
+	log.Printf("Provisioned Nextcloud account %s for user %d with %d GB.", email, userID, quotaGB)
+	return nil
+}
+
+// syncGroups puts the user in the Nextcloud groups of their plan and takes them out of the groups
+// only other plans grant. Groups no plan mentions are left alone, so groups admins assign by
+// hand survive plan changes. Reserved groups such as admin are never touched, even if a plan
+// saved before they were rejected lists them.
+func (s *nextcloudSyncService) syncGroups(ctx context.Context, userID int64) error {
+	permissions, err := s.billingService.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get permissions: %w", err)
+	}
+	wanted := permissions.NextcloudGroups
+	managed, err := s.planRepo.FindNextcloudGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get plan groups: %w", err)
+	}
+
+	userDetails, err := s.userSvcClient.GetUserDetails(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user details: %w", err)
+	}
+	current, err := s.nextcloudClient.GetUserGroups(ctx, userDetails.Email)
+	if err != nil {
+		// The account is created by the quota sync; retry until it has run.
+		return fmt.Errorf("failed to get groups of %s: %w", userDetails.Email, err)
+	}
+	member := make(map[string]bool, len(current))
+	for _, group := range current {
+		member[group] = true
+	}
+
+	keep := make(map[string]bool, len(wanted))
+	for _, group := range wanted {
+		keep[group] = true
+		if member[group] || entitlements.IsReservedGroup(group) {
+			continue
+		}
+		if err := s.nextcloudClient.CreateGroup(ctx, group); err != nil {
+			return fmt.Errorf("failed to create group %s: %w", group, err)
+		}
+		if err := s.nextcloudClient.AddUserToGroup(ctx, userDetails.Email, group); err != nil {
+			return fmt.Errorf("failed to add %s to group %s: %w", userDetails.Email, group, err)
+		}
+	}
+	for _, group := range managed {
+		if keep[group] || !member[group] || entitlements.IsReservedGroup(group) {
+			continue
+		}
+		if err := s.nextcloudClient.RemoveUserFromGroup(ctx, userDetails.Email, group); err != nil {
+			return fmt.Errorf("failed to remove %s from group %s: %w", userDetails.Email, group, err)
+		}
+	}
+
+	log.Printf("Successfully synced Nextcloud groups for user %s to %v.", userDetails.Email, wanted)
+	return nil
+}
+
+func (s *nextcloudSyncService) GetSyncs(ctx context.Context, status string, limit int) ([]domain.NextcloudSync, error) {
+	switch status {
+	case domain.NextcloudSyncPending, domain.NextcloudSyncDelivered, domain.NextcloudSyncDead:
+	default:
+		return nil, fmt.Errorf("unknown sync status '%s': %w", status, ierr.ErrInvalidInput)
+	}
+	if limit <= 0 || limit > maxListedNextcloudSyncs {
+		limit = maxListedNextcloudSyncs
+	}
+	return s.outboxRepo.FindByStatus(ctx, status, limit)
+}
+
+// ReplaySync gives a dead-lettered sync a fresh set of attempts, starting right away.
+func (s *nextcloudSyncService) ReplaySync(ctx context.Context, id int64) (*domain.NextcloudSync, error) {
+	sync, err := s.outboxRepo.Replay(ctx, id)
+	if err != nil {
+		if errors.Is(err, ierr.ErrNotFound) {
+			return nil, fmt.Errorf("no dead-lettered sync %d: %w", id, err)
+		}
+		if errors.Is(err, ierr.ErrConflict) {
+			return nil, fmt.Errorf("the user of sync %d already has a pending sync: %w", id, err)
+		}
+		return nil, err
+	}
+	log.Printf("Nextcloud sync %d of user %d was replayed.", sync.ID, sync.UserID)
+	return sync, nil
+}
@@ -0,0 +1,68 @@
+// services/billing-service/internal/service/outbox.go
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// outboxBatch is how many items a delivery run claims at once.
+	outboxBatch = 50
+	// outboxLease is how long a claimed item is reserved for the run delivering it. It must
+	// exceed the time one delivery may take, including the request timeouts.
+	outboxLease = 5 * time.Minute
+	// maxOutboxDelay caps the backoff between two attempts.
+	maxOutboxDelay = 6 * time.Hour
+)
+
+// outboxDelivery delivers an outbox table the repositories fill in the same transaction as the
+// changes that require a delivery, e.g. Nextcloud syncs and webhook deliveries. Failed items
+// are retried with exponential backoff and dead-lettered once they failed maxAttempts times.
+type outboxDelivery struct {
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+// deliverDue claims due items under a lease and delivers them, until a batch comes back short.
+// deliver records the outcome of each item itself, e.g. with recordFailure.
+func deliverDue[T any](ctx context.Context, claim func(ctx context.Context, limit int, lease time.Duration) ([]T, error), deliver func(ctx context.Context, item *T)) error {
+	for {
+		items, err := claim(ctx, outboxBatch, outboxLease)
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			deliver(ctx, &items[i])
+		}
+		if len(items) < outboxBatch {
+			return nil
+		}
+	}
+}
+
+// recordFailure schedules the next attempt of an item that failed for the `attempts`th time,
+// or dead-letters it, and logs the outcome. `what` names the item in the logs, mark stores the
+// outcome in the outbox.
+func (o outboxDelivery) recordFailure(what string, attempts int, err error, mark func(next time.Time, dead bool) error) {
+	dead := attempts >= o.maxAttempts
+	next := time.Now().Add(o.backoff(attempts))
+	if markErr := mark(next, dead); markErr != nil {
+		log.Printf("Failed to record failed attempt of %s: %v", what, markErr)
+	}
+	if dead {
+		log.Printf("CRITICAL: %s failed %d times and was dead-lettered: %v", what, attempts, err)
+	} else {
+		log.Printf("%s failed (attempt %d), retrying at %s: %v", what, attempts, next.Format(time.RFC3339), err)
+	}
+}
+
+// backoff doubles the retry delay with every failed attempt, up to maxOutboxDelay.
+func (o outboxDelivery) backoff(attempts int) time.Duration {
+	delay := o.retryDelay
+	for i := 1; i < attempts && delay < maxOutboxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxOutboxDelay)
+}
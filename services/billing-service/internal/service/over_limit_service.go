@@ -0,0 +1,214 @@
+// services/billing-service/internal/service/over_limit_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/client"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"time"
+)
+
+const (
+	// overLimitReminderLead is how long before the end of the grace period users are reminded.
+	overLimitReminderLead = 3 * 24 * time.Hour
+	// maxListedOverLimitCases caps how many cases an admin listing returns.
+	maxListedOverLimitCases = 200
+)
+
+// OverLimitService handles users who store more than their storage quota, typically after a
+// downgrade. They keep their data but are read-only until they free up space or upgrade, and
+// are notified when that starts, before their grace period ends and when it has ended.
+type OverLimitService interface {
+	// CheckPlanChange compares the user's last fetched usage, Nextcloud and videos, with quotaGB, the storage they
+	// would have after a plan change, and returns a warning if it exceeds it. reducesQuota
+	// tells whether the change lowers their storage, which the BLOCK_DOWNGRADE policy refuses.
+	// It returns nil if the usage fits or was never fetched.
+	CheckPlanChange(ctx context.Context, userID int64, quotaGB int, reducesQuota bool) (*domain.StorageWarning, error)
+	// Evaluate opens a case if usedBytes, the user's Nextcloud and video storage together,
+	// exceeds quotaGB and resolves the user's case if not.
+	Evaluate(ctx context.Context, userID, usedBytes int64, quotaGB int) error
+	// EvaluateQuota is Evaluate with the user's last fetched usage, for when their quota
+	// changed. It does nothing if their usage was never fetched.
+	EvaluateQuota(ctx context.Context, userID int64, quotaGB int) error
+	// GetCase returns the user's unresolved case, nil if they are within their quota.
+	GetCase(ctx context.Context, userID int64) (*domain.OverLimitCase, error)
+	GetCases(ctx context.Context, status string, limit int) ([]domain.OverLimitCase, error)
+	// ProcessGracePeriods reminds users whose grace period ends soon and expires the ones
+	// that have ended.
+	ProcessGracePeriods(ctx context.Context) error
+}
+
+type overLimitService struct {
+	overLimitRepo    repository.OverLimitRepository
+	storageUsageRepo repository.StorageUsageRepository
+	notifier         client.Notifier
+	policy           string
+	grace            time.Duration
+}
+
+func NewOverLimitService(overLimitRepo repository.OverLimitRepository, storageUsageRepo repository.StorageUsageRepository, notifier client.Notifier, policy string, grace time.Duration) OverLimitService {
+	return &overLimitService{
+		overLimitRepo:    overLimitRepo,
+		storageUsageRepo: storageUsageRepo,
+		notifier:         notifier,
+		policy:           policy,
+		grace:            grace,
+	}
+}
+
+func (s *overLimitService) CheckPlanChange(ctx context.Context, userID int64, quotaGB int, reducesQuota bool) (*domain.StorageWarning, error) {
+	usage, err := s.storageUsageRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ierr.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	usedBytes := usage.TotalBytes()
+	quotaBytes := domain.QuotaBytes(quotaGB)
+	if usedBytes <= quotaBytes {
+		return nil, nil
+	}
+
+	warning := &domain.StorageWarning{
+		UsedBytes:   usedBytes,
+		QuotaGB:     quotaGB,
+		OverByBytes: usedBytes - quotaBytes,
+		Policy:      s.policy,
+		Blocked:     reducesQuota && s.policy == domain.OverLimitPolicyBlockDowngrade,
+	}
+	if warning.Blocked {
+		warning.Message = fmt.Sprintf("You are using %.1f GB, but the new plan includes %d GB. Free up %.1f GB before changing to it.",
+			gigabytes(usedBytes), quotaGB, gigabytes(warning.OverByBytes))
+		return warning, nil
+	}
+	warning.GraceDays = int(s.grace.Hours() / 24)
+	warning.Message = fmt.Sprintf("You are using %.1f GB, but the new plan includes %d GB. Your files stay available, but uploads are paused until you free up %.1f GB or upgrade. You have %d days to do so.",
+		gigabytes(usedBytes), quotaGB, gigabytes(warning.OverByBytes), warning.GraceDays)
+	return warning, nil
+}
+
+func (s *overLimitService) Evaluate(ctx context.Context, userID, usedBytes int64, quotaGB int) error {
+	if usedBytes <= domain.QuotaBytes(quotaGB) {
+		return s.resolve(ctx, userID)
+	}
+
+	c := &domain.OverLimitCase{
+		UserID:      userID,
+		UsedBytes:   usedBytes,
+		QuotaGB:     quotaGB,
+		GraceEndsAt: time.Now().Add(s.grace),
+	}
+	opened, err := s.overLimitRepo.Open(ctx, c)
+	if err != nil || !opened {
+		return err
+	}
+
+	log.Printf("User %d stores %d bytes with a quota of %d GB and is read-only until %s.",
+		userID, usedBytes, quotaGB, c.GraceEndsAt.Format(time.RFC3339))
+	message := fmt.Sprintf("You are using %.1f GB, but your plan includes %d GB. Your files stay available, but uploads are paused until you free up space or upgrade your plan. Please do so by %s.",
+		gigabytes(usedBytes), quotaGB, c.GraceEndsAt.Format(time.DateOnly))
+	if err := s.notifier.Notify(ctx, userID, "Your storage exceeds your plan", message); err != nil {
+		log.Printf("Failed to notify user %d about exceeding their storage: %v", userID, err)
+	}
+	return nil
+}
+
+func (s *overLimitService) EvaluateQuota(ctx context.Context, userID int64, quotaGB int) error {
+	usage, err := s.storageUsageRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ierr.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.Evaluate(ctx, userID, usage.TotalBytes(), quotaGB)
+}
+
+func (s *overLimitService) resolve(ctx context.Context, userID int64) error {
+	c, err := s.overLimitRepo.Resolve(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ierr.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	log.Printf("Over-limit case %d of user %d was resolved.", c.ID, userID)
+	if err := s.notifier.Notify(ctx, userID, "Uploads are enabled again",
+		"Your storage is within your plan again, so you can upload again."); err != nil {
+		log.Printf("Failed to notify user %d about their resolved over-limit case: %v", userID, err)
+	}
+	return nil
+}
+
+func (s *overLimitService) GetCase(ctx context.Context, userID int64) (*domain.OverLimitCase, error) {
+	c, err := s.overLimitRepo.FindUnresolvedByUserID(ctx, userID)
+	if errors.Is(err, ierr.ErrNotFound) {
+		return nil, nil
+	}
+	return c, err
+}
+
+func (s *overLimitService) GetCases(ctx context.Context, status string, limit int) ([]domain.OverLimitCase, error) {
+	switch status {
+	case "", domain.OverLimitOpen, domain.OverLimitExpired, domain.OverLimitResolved:
+	default:
+		return nil, fmt.Errorf("unknown over-limit status '%s': %w", status, ierr.ErrInvalidInput)
+	}
+	if limit <= 0 || limit > maxListedOverLimitCases {
+		limit = maxListedOverLimitCases
+	}
+	return s.overLimitRepo.FindByStatus(ctx, status, limit)
+}
+
+func (s *overLimitService) ProcessGracePeriods(ctx context.Context) error {
+	now := time.Now()
+	due, err := s.overLimitRepo.FindReminderDue(ctx, now.Add(overLimitReminderLead))
+	if err != nil {
+		return err
+	}
+	for _, c := range due {
+		if !c.GraceEndsAt.After(now) {
+			continue // Expired below, a reminder would come too late
+		}
+		claimed, err := s.overLimitRepo.MarkReminderSent(ctx, c.ID)
+		if err != nil {
+			log.Printf("Failed to mark the reminder of over-limit case %d as sent: %v", c.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		message := fmt.Sprintf("You still store more than the %d GB your plan includes. Please free up space or upgrade your plan by %s.",
+			c.QuotaGB, c.GraceEndsAt.Format(time.DateOnly))
+		if err := s.notifier.Notify(ctx, c.UserID, "Your storage grace period ends soon", message); err != nil {
+			log.Printf("Failed to send over-limit reminder to user %d: %v", c.UserID, err)
+		}
+	}
+
+	expired, err := s.overLimitRepo.ExpireDue(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, c := range expired {
+		// Nothing is deleted automatically; support decides what happens to the data.
+		log.Printf("CRITICAL: Grace period of over-limit case %d ended, user %d still stores more than %d GB.",
+			c.ID, c.UserID, c.QuotaGB)
+		if err := s.notifier.Notify(ctx, c.UserID, "Your storage grace period has ended",
+			"Your account stays read-only until you free up space or upgrade your plan. Our support team will contact you about your stored files."); err != nil {
+			log.Printf("Failed to notify user %d about their expired grace period: %v", c.UserID, err)
+		}
+	}
+	return nil
+}
+
+// gigabytes converts bytes to binary GB, as Nextcloud counts them.
+func gigabytes(bytes int64) float64 {
+	return float64(bytes) / (1 << 30)
+}
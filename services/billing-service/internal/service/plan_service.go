@@ -0,0 +1,308 @@
+// services/billing-service/internal/service/plan_service.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/client"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/entitlements"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// versionMigrationNotice is the minimum time subscribers are warned ahead of being moved
+// to a newer version of their plan.
+const versionMigrationNotice = 30 * 24 * time.Hour
+
+// PlanService is the admin side of subscription plans.
+type PlanService interface {
+	GetAllPlans(ctx context.Context) ([]domain.SubscriptionPlan, error)
+	CreatePlan(ctx context.Context, plan *domain.SubscriptionPlan) (*domain.SubscriptionPlan, error)
+	PatchPlan(ctx context.Context, id int64, patch PlanPatch) (*domain.SubscriptionPlan, error)
+	ArchivePlan(ctx context.Context, id int64) error
+	ReorderPlans(ctx context.Context, planIDs []int64) error
+	GetPlanVersions(ctx context.Context, planID int64) ([]domain.PlanVersionReport, error)
+	ScheduleVersionMigration(ctx context.Context, planID, fromVersionID int64, effectiveAt time.Time) (*domain.PlanVersionMigration, error)
+}
+
+// PlanPatch holds the plan fields an admin may change, nil fields are left untouched.
+type PlanPatch struct {
+	Name          *string
+	Prices        *[]domain.Money
+	MeteredPrices *[]domain.MeteredPrice
+	Permissions   *entitlements.Entitlements
+	TrialDays     *int
+	IsActive      *bool
+}
+
+type planService struct {
+	planRepo        repository.PlanRepository
+	subRepo         repository.SubscriptionRepository
+	notifier        client.Notifier
+	defaultCurrency string
+}
+
+func NewPlanService(planRepo repository.PlanRepository, subRepo repository.SubscriptionRepository, notifier client.Notifier, defaultCurrency string) PlanService {
+	return &planService{
+		planRepo:        planRepo,
+		subRepo:         subRepo,
+		notifier:        notifier,
+		defaultCurrency: defaultCurrency,
+	}
+}
+
+// GetAllPlans returns every plan including archived ones, in display order.
+func (s *planService) GetAllPlans(ctx context.Context) ([]domain.SubscriptionPlan, error) {
+	return s.planRepo.FindAll(ctx)
+}
+
+func (s *planService) CreatePlan(ctx context.Context, plan *domain.SubscriptionPlan) (*domain.SubscriptionPlan, error) {
+	plan.Name = strings.TrimSpace(plan.Name)
+	plan.IsActive = true
+	if err := s.validatePlan(plan); err != nil {
+		return nil, err
+	}
+
+	if err := s.planRepo.Create(ctx, plan); err != nil {
+		return nil, fmt.Errorf("plan '%s' already exists: %w", plan.Name, err)
+	}
+	return plan, nil
+}
+
+// PatchPlan edits a plan. Changing prices, metered prices or permissions creates a new plan
+// version: new subscriptions get it, existing subscribers stay on the version they signed up for.
+func (s *planService) PatchPlan(ctx context.Context, id int64, patch PlanPatch) (*domain.SubscriptionPlan, error) {
+	plan, err := s.planRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	newVersion := (patch.Prices != nil && !samePrices(*patch.Prices, plan.Prices)) ||
+		(patch.MeteredPrices != nil && !reflect.DeepEqual(*patch.MeteredPrices, plan.MeteredPrices)) ||
+		(patch.Permissions != nil && !reflect.DeepEqual(*patch.Permissions, plan.Permissions))
+
+	if patch.Name != nil {
+		if plan.Name == defaultPlanName && strings.TrimSpace(*patch.Name) != defaultPlanName {
+			return nil, fmt.Errorf("the %s plan cannot be renamed: %w", defaultPlanName, ierr.ErrConflict)
+		}
+		plan.Name = strings.TrimSpace(*patch.Name)
+	}
+	if patch.Prices != nil {
+		plan.Prices = *patch.Prices
+	}
+	if patch.MeteredPrices != nil {
+		plan.MeteredPrices = *patch.MeteredPrices
+	}
+	if patch.Permissions != nil {
+		plan.Permissions = *patch.Permissions
+	}
+	if patch.TrialDays != nil {
+		plan.TrialDays = *patch.TrialDays
+	}
+	if patch.IsActive != nil {
+		if plan.Name == defaultPlanName && !*patch.IsActive {
+			return nil, fmt.Errorf("the %s plan cannot be archived: %w", defaultPlanName, ierr.ErrConflict)
+		}
+		plan.IsActive = *patch.IsActive
+	}
+	if err := s.validatePlan(plan); err != nil {
+		return nil, err
+	}
+
+	if err := s.planRepo.Update(ctx, plan, newVersion); err != nil {
+		return nil, fmt.Errorf("could not update plan '%s': %w", plan.Name, err)
+	}
+	return plan, nil
+}
+
+// ArchivePlan hides the plan from new subscriptions. Existing subscribers keep it.
+func (s *planService) ArchivePlan(ctx context.Context, id int64) error {
+	isActive := false
+	_, err := s.PatchPlan(ctx, id, PlanPatch{IsActive: &isActive})
+	return err
+}
+
+// ReorderPlans sets the display order. planIDs must list every plan exactly once.
+func (s *planService) ReorderPlans(ctx context.Context, planIDs []int64) error {
+	plans, err := s.planRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	if len(planIDs) != len(plans) {
+		return fmt.Errorf("expected %d plan ids, got %d: %w", len(plans), len(planIDs), ierr.ErrInvalidInput)
+	}
+
+	known := make(map[int64]bool, len(plans))
+	for _, p := range plans {
+		known[p.ID] = true
+	}
+	for _, id := range planIDs {
+		if !known[id] {
+			return fmt.Errorf("plan %d is unknown or listed twice: %w", id, ierr.ErrInvalidInput)
+		}
+		delete(known, id)
+	}
+
+	return s.planRepo.Reorder(ctx, planIDs)
+}
+
+// GetPlanVersions lists every version of the plan with the number of subscribers pinned to it.
+func (s *planService) GetPlanVersions(ctx context.Context, planID int64) ([]domain.PlanVersionReport, error) {
+	if _, err := s.planRepo.FindByID(ctx, planID); err != nil {
+		return nil, err
+	}
+	return s.planRepo.FindVersionReports(ctx, planID)
+}
+
+// ScheduleVersionMigration moves subscribers of an old plan version to the plan's current version
+// at effectiveAt, and notifies them right away.
+func (s *planService) ScheduleVersionMigration(ctx context.Context, planID, fromVersionID int64, effectiveAt time.Time) (*domain.PlanVersionMigration, error) {
+	plan, err := s.planRepo.FindByID(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.planRepo.FindVersionByID(ctx, fromVersionID)
+	if err != nil {
+		return nil, err
+	}
+	if from.PlanID != plan.ID {
+		return nil, fmt.Errorf("version %d does not belong to plan '%s': %w", fromVersionID, plan.Name, ierr.ErrNotFound)
+	}
+	if from.ID == plan.CurrentVersionID {
+		return nil, fmt.Errorf("version %d is already the current version: %w", from.Version, ierr.ErrConflict)
+	}
+	if effectiveAt.Before(time.Now().Add(versionMigrationNotice)) {
+		return nil, fmt.Errorf("subscribers must be given at least %d days notice: %w",
+			int(versionMigrationNotice.Hours()/24), ierr.ErrInvalidInput)
+	}
+
+	migration := &domain.PlanVersionMigration{
+		PlanID:        plan.ID,
+		FromVersionID: from.ID,
+		ToVersionID:   plan.CurrentVersionID,
+		EffectiveAt:   effectiveAt,
+	}
+	if err := s.planRepo.CreateVersionMigration(ctx, migration); err != nil {
+		return nil, err
+	}
+
+	userIDs, err := s.subRepo.FindUserIDsByPlanVersion(ctx, from.ID)
+	if err != nil {
+		return nil, err
+	}
+	message := fmt.Sprintf("On %s your %s plan will be updated to its current terms.",
+		effectiveAt.Format(time.DateOnly), plan.Name)
+	if !plan.IsFree() {
+		prices := make([]string, 0, len(plan.Prices))
+		for _, price := range plan.Prices {
+			prices = append(prices, price.String())
+		}
+		message = fmt.Sprintf("On %s your %s plan will be updated to its current terms: %s per month.",
+			effectiveAt.Format(time.DateOnly), plan.Name, strings.Join(prices, " / "))
+	}
+	for _, userID := range userIDs {
+		if err := s.notifier.Notify(ctx, userID, "Changes to your subscription", message); err != nil {
+			log.Printf("Failed to notify user %d about plan version migration %d: %v", userID, migration.ID, err)
+		}
+	}
+
+	log.Printf("Scheduled migration %d of %d subscribers from plan %d version %d at %s.",
+		migration.ID, len(userIDs), plan.ID, from.Version, effectiveAt.Format(time.RFC3339))
+	return migration, nil
+}
+
+func (s *planService) validatePlan(plan *domain.SubscriptionPlan) error {
+	if plan.Name == "" {
+		return fmt.Errorf("plan name is required: %w", ierr.ErrInvalidInput)
+	}
+	if plan.Prices == nil {
+		plan.Prices = []domain.Money{}
+	}
+	seen := make(map[string]bool, len(plan.Prices))
+	for _, price := range plan.Prices {
+		if !domain.IsSupportedCurrency(price.Currency) {
+			return fmt.Errorf("unsupported currency '%s': %w", price.Currency, ierr.ErrInvalidInput)
+		}
+		if seen[price.Currency] {
+			return fmt.Errorf("plan has more than one %s price: %w", price.Currency, ierr.ErrInvalidInput)
+		}
+		seen[price.Currency] = true
+		if price.IsNegative() {
+			return fmt.Errorf("plan price must not be negative: %w", ierr.ErrInvalidInput)
+		}
+	}
+	// Users whose locale currency a plan is not sold in are billed in the default currency.
+	if _, ok := plan.PriceIn(s.defaultCurrency); !ok {
+		return fmt.Errorf("paid plans must have a price in %s: %w", s.defaultCurrency, ierr.ErrInvalidInput)
+	}
+	if err := validateMeteredPrices(plan); err != nil {
+		return err
+	}
+	if plan.TrialDays < 0 {
+		return fmt.Errorf("trial days must not be negative: %w", ierr.ErrInvalidInput)
+	}
+	if err := plan.Permissions.Validate(); err != nil {
+		return fmt.Errorf("%v: %w", err, ierr.ErrInvalidInput)
+	}
+	return nil
+}
+
+// samePrices reports whether two price lists contain the same amounts, in any order.
+func samePrices(a, b []domain.Money) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, price := range a {
+		found := false
+		for _, other := range b {
+			if other == price {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// validateMeteredPrices checks that every metered price can be billed in each currency the
+// plan is sold in. Overage is invoiced on renewal, so free plans cannot have metered prices.
+func validateMeteredPrices(plan *domain.SubscriptionPlan) error {
+	if plan.MeteredPrices == nil {
+		plan.MeteredPrices = []domain.MeteredPrice{}
+	}
+	if len(plan.MeteredPrices) > 0 && plan.IsFree() {
+		return fmt.Errorf("free plans cannot have metered prices: %w", ierr.ErrInvalidInput)
+	}
+
+	seen := make(map[string]bool, len(plan.MeteredPrices))
+	for _, mp := range plan.MeteredPrices {
+		if !domain.IsValidUsageMetric(mp.Metric) {
+			return fmt.Errorf("unknown usage metric '%s': %w", mp.Metric, ierr.ErrInvalidInput)
+		}
+		if seen[mp.Metric] {
+			return fmt.Errorf("metric %s is priced more than once: %w", mp.Metric, ierr.ErrInvalidInput)
+		}
+		seen[mp.Metric] = true
+		if mp.Included < 0 || mp.UnitSize <= 0 {
+			return fmt.Errorf("metric %s needs a non-negative included amount and a positive unit size: %w", mp.Metric, ierr.ErrInvalidInput)
+		}
+		for _, price := range plan.Prices {
+			unitPrice, ok := mp.UnitPriceIn(price.Currency)
+			if !ok {
+				return fmt.Errorf("metric %s has no unit price in %s: %w", mp.Metric, price.Currency, ierr.ErrInvalidInput)
+			}
+			if unitPrice.IsNegative() {
+				return fmt.Errorf("unit price of metric %s must not be negative: %w", mp.Metric, ierr.ErrInvalidInput)
+			}
+		}
+	}
+	return nil
+}
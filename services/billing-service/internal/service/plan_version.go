@@ -0,0 +1,53 @@
+// services/billing-service/internal/service/plan_version.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"log"
+	"time"
+)
+
+// subscribedPlan returns the plan of the subscription with the prices and permissions of the
+// version the subscription is pinned to, which may differ from the plan's current terms.
+func (s *billingService) subscribedPlan(ctx context.Context, sub *domain.UserSubscription) (*domain.SubscriptionPlan, error) {
+	plan, err := s.planRepo.FindByID(ctx, sub.PlanID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load plan %d: %w", sub.PlanID, err)
+	}
+	if sub.PlanVersionID == plan.CurrentVersionID {
+		return plan, nil
+	}
+
+	version, err := s.planRepo.FindVersionByID(ctx, sub.PlanVersionID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load plan version %d: %w", sub.PlanVersionID, err)
+	}
+	plan.Prices = version.Prices
+	plan.MeteredPrices = version.MeteredPrices
+	plan.Permissions = version.Permissions
+	return plan, nil
+}
+
+// applyVersionMigrations executes scheduled plan version migrations that became due.
+// New permissions apply immediately, the new price from the next renewal on.
+func (s *billingService) applyVersionMigrations(ctx context.Context, now time.Time) error {
+	migrations, err := s.planRepo.FindDueVersionMigrations(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		userIDs, err := s.subRepo.MigratePlanVersion(ctx, m.FromVersionID, m.ToVersionID)
+		if err != nil {
+			log.Printf("Failed to apply plan version migration %d: %v", m.ID, err)
+			continue
+		}
+		if err := s.planRepo.CompleteVersionMigration(ctx, m.ID); err != nil {
+			log.Printf("Failed to mark plan version migration %d as completed: %v", m.ID, err)
+		}
+		log.Printf("Applied plan version migration %d to %d subscriptions.", m.ID, len(userIDs))
+	}
+	return nil
+}
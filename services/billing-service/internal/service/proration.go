@@ -0,0 +1,105 @@
+// services/billing-service/internal/service/proration.go
+package service
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"time"
+)
+
+// periodEndFor returns the end of a billing period for the given plan that starts at `start`.
+// Free plans never expire, paid plans are billed monthly.
+func periodEndFor(plan *domain.SubscriptionPlan, start time.Time) time.Time {
+	if plan.IsFree() {
+		return start.AddDate(100, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// calculateProration computes what switching `sub` from `current` to `next` at `now` costs,
+// in `currency`. Both plans must have a price in that currency.
+//
+// Upgrades take effect immediately: the user is credited for the unused part of the current
+// period and charged for the same part on the new plan. If the current plan is free there is
+// no paid period to keep, so a fresh full period is started on the new plan.
+// Downgrades are scheduled for the end of the current period and cost nothing now.
+// A trial has not been paid for, so any change ends it immediately and starts a regular period.
+func calculateProration(sub *domain.UserSubscription, current, next *domain.SubscriptionPlan, currency string, now time.Time) *domain.ProrationPreview {
+	zero := domain.NewMoney(0, currency)
+	preview := &domain.ProrationPreview{
+		CurrentPlanID: current.ID,
+		NewPlanID:     next.ID,
+		Credit:        zero,
+		Charge:        zero,
+		Discount:      zero,
+		Subtotal:      zero,
+		AmountDue:     zero,
+	}
+	currentPrice, _ := current.PriceIn(currency)
+	nextPrice, _ := next.PriceIn(currency)
+
+	if sub.Status == domain.SubscriptionStatusTrialing {
+		preview.ChangeType = changeTypeFor(currentPrice, nextPrice)
+		preview.EffectiveAt = now
+		preview.Charge = nextPrice
+		preview.AmountDue = preview.Charge
+		preview.PeriodEndsAt = periodEndFor(next, now)
+		return preview
+	}
+
+	preview.ChangeType = changeTypeFor(currentPrice, nextPrice)
+	switch preview.ChangeType {
+	case domain.ChangeTypeDowngrade:
+		preview.EffectiveAt = sub.EndsAt
+		preview.PeriodEndsAt = periodEndFor(next, sub.EndsAt)
+		return preview
+	case domain.ChangeTypeLateral:
+		preview.EffectiveAt = now
+		preview.PeriodEndsAt = sub.EndsAt
+		return preview
+	}
+
+	preview.EffectiveAt = now
+
+	if currentPrice.IsZero() {
+		preview.Charge = nextPrice
+		preview.AmountDue = preview.Charge
+		preview.PeriodEndsAt = periodEndFor(next, now)
+		return preview
+	}
+
+	remaining, total := unusedPeriod(sub, now)
+	preview.Credit = currentPrice.Prorate(int64(remaining), int64(total))
+	preview.Charge = nextPrice.Prorate(int64(remaining), int64(total))
+	if due := preview.Charge.Sub(preview.Credit); due.IsPositive() {
+		preview.AmountDue = due
+	}
+	preview.PeriodEndsAt = sub.EndsAt
+	return preview
+}
+
+func changeTypeFor(currentPrice, nextPrice domain.Money) string {
+	switch nextPrice.Cmp(currentPrice) {
+	case 1:
+		return domain.ChangeTypeUpgrade
+	case -1:
+		return domain.ChangeTypeDowngrade
+	}
+	return domain.ChangeTypeLateral
+}
+
+// unusedPeriod returns how much of the current period has not been consumed yet, out of its
+// total length. Prices are prorated by the exact ratio, without going through floating point.
+func unusedPeriod(sub *domain.UserSubscription, now time.Time) (remaining, total time.Duration) {
+	total = sub.EndsAt.Sub(sub.StartsAt)
+	if total <= 0 {
+		return 0, 1
+	}
+	remaining = sub.EndsAt.Sub(now)
+	if remaining <= 0 {
+		return 0, total
+	}
+	if remaining >= total {
+		return total, total
+	}
+	return remaining, total
+}
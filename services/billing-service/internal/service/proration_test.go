@@ -0,0 +1,109 @@
+// services/billing-service/internal/service/proration_test.go
+package service
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestCalculateProration(t *testing.T) {
+	start := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	now := start.AddDate(0, 0, 20) // a third of the period is left
+
+	free := &domain.SubscriptionPlan{ID: 1}
+	basic := &domain.SubscriptionPlan{ID: 2, Prices: []domain.Money{domain.NewMoney(900, "EUR")}}
+	pro := &domain.SubscriptionPlan{ID: 3, Prices: []domain.Money{domain.NewMoney(1800, "EUR")}}
+	team := &domain.SubscriptionPlan{ID: 4, Prices: []domain.Money{domain.NewMoney(1800, "EUR")}}
+
+	eur := func(amount int64) domain.Money { return domain.NewMoney(amount, "EUR") }
+	tests := []struct {
+		name          string
+		status        string
+		current, next *domain.SubscriptionPlan
+		changeType    string
+		credit        domain.Money
+		charge        domain.Money
+		amountDue     domain.Money
+		effectiveAt   time.Time
+		periodEndsAt  time.Time
+	}{
+		{
+			name: "upgrade credits the unused part", status: domain.SubscriptionStatusActive,
+			current: basic, next: pro, changeType: domain.ChangeTypeUpgrade,
+			credit: eur(300), charge: eur(600), amountDue: eur(300),
+			effectiveAt: now, periodEndsAt: end,
+		},
+		{
+			name: "upgrade from free starts a new period", status: domain.SubscriptionStatusActive,
+			current: free, next: basic, changeType: domain.ChangeTypeUpgrade,
+			credit: eur(0), charge: eur(900), amountDue: eur(900),
+			effectiveAt: now, periodEndsAt: now.AddDate(0, 1, 0),
+		},
+		{
+			name: "downgrade waits for the period to end", status: domain.SubscriptionStatusActive,
+			current: pro, next: basic, changeType: domain.ChangeTypeDowngrade,
+			credit: eur(0), charge: eur(0), amountDue: eur(0),
+			effectiveAt: end, periodEndsAt: end.AddDate(0, 1, 0),
+		},
+		{
+			name: "lateral change is free", status: domain.SubscriptionStatusActive,
+			current: pro, next: team, changeType: domain.ChangeTypeLateral,
+			credit: eur(0), charge: eur(0), amountDue: eur(0),
+			effectiveAt: now, periodEndsAt: end,
+		},
+		{
+			name: "trial charges a full period", status: domain.SubscriptionStatusTrialing,
+			current: pro, next: basic, changeType: domain.ChangeTypeDowngrade,
+			credit: eur(0), charge: eur(900), amountDue: eur(900),
+			effectiveAt: now, periodEndsAt: now.AddDate(0, 1, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &domain.UserSubscription{Status: tt.status, StartsAt: start, EndsAt: end}
+			got := calculateProration(sub, tt.current, tt.next, "EUR", now)
+
+			if got.ChangeType != tt.changeType {
+				t.Errorf("ChangeType = %s, want %s", got.ChangeType, tt.changeType)
+			}
+			if got.Credit != tt.credit || got.Charge != tt.charge || got.AmountDue != tt.amountDue {
+				t.Errorf("credit, charge, due = %v, %v, %v, want %v, %v, %v",
+					got.Credit, got.Charge, got.AmountDue, tt.credit, tt.charge, tt.amountDue)
+			}
+			if !got.EffectiveAt.Equal(tt.effectiveAt) || !got.PeriodEndsAt.Equal(tt.periodEndsAt) {
+				t.Errorf("effective %v until %v, want %v until %v",
+					got.EffectiveAt, got.PeriodEndsAt, tt.effectiveAt, tt.periodEndsAt)
+			}
+			if got.CurrentPlanID != tt.current.ID || got.NewPlanID != tt.next.ID {
+				t.Errorf("plans = %d -> %d, want %d -> %d", got.CurrentPlanID, got.NewPlanID, tt.current.ID, tt.next.ID)
+			}
+		})
+	}
+}
+
+func TestUnusedPeriod(t *testing.T) {
+	start := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(30 * 24 * time.Hour)
+	sub := &domain.UserSubscription{StartsAt: start, EndsAt: end}
+	total := end.Sub(start)
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		remaining time.Duration
+	}{
+		{"before the period", start.Add(-time.Hour), total},
+		{"at the start", start, total},
+		{"midway", start.Add(10 * 24 * time.Hour), 20 * 24 * time.Hour},
+		{"at the end", end, 0},
+		{"after the period", end.Add(time.Hour), 0},
+	}
+	for _, tt := range tests {
+		remaining, gotTotal := unusedPeriod(sub, tt.now)
+		if remaining != tt.remaining || gotTotal != total {
+			t.Errorf("%s: unusedPeriod = %v of %v, want %v of %v", tt.name, remaining, gotTotal, tt.remaining, total)
+		}
+	}
+}
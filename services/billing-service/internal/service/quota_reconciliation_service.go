@@ -0,0 +1,159 @@
+// services/billing-service/internal/service/quota_reconciliation_service.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/client"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// nextcloudUsersPage is how many Nextcloud users are listed per request.
+	nextcloudUsersPage = 500
+	// maxListedReconciliations caps how many reports an admin listing returns.
+	maxListedReconciliations = 50
+)
+
+// QuotaReconciliationService finds Nextcloud quotas that drifted from what users are entitled
+// to, e.g. because they were edited in the Nextcloud admin UI.
+type QuotaReconciliationService interface {
+	// Reconcile compares every subscriber's Nextcloud quota with their permissions and stores
+	// a report. Unless dryRun is set, a quota sync is queued for every drifted account.
+	Reconcile(ctx context.Context, dryRun bool) (*domain.QuotaReconciliation, error)
+	GetReconciliations(ctx context.Context, limit int) ([]domain.QuotaReconciliation, error)
+}
+
+type quotaReconciliationService struct {
+	reconciliationRepo repository.QuotaReconciliationRepository
+	subRepo            repository.SubscriptionRepository
+	outboxRepo         repository.NextcloudOutboxRepository
+	billingService     BillingService
+	userSvcClient      client.UserServiceClient
+	nextcloudClient    client.NextcloudClient
+}
+
+func NewQuotaReconciliationService(reconciliationRepo repository.QuotaReconciliationRepository, subRepo repository.SubscriptionRepository, outboxRepo repository.NextcloudOutboxRepository, billingService BillingService, userSvcClient client.UserServiceClient, ncClient client.NextcloudClient) QuotaReconciliationService {
+	return &quotaReconciliationService{
+		reconciliationRepo: reconciliationRepo,
+		subRepo:            subRepo,
+		outboxRepo:         outboxRepo,
+		billingService:     billingService,
+		userSvcClient:      userSvcClient,
+		nextcloudClient:    ncClient,
+	}
+}
+
+func (s *quotaReconciliationService) Reconcile(ctx context.Context, dryRun bool) (*domain.QuotaReconciliation, error) {
+	rec := &domain.QuotaReconciliation{DryRun: dryRun, Discrepancies: []domain.QuotaDiscrepancy{}, StartedAt: time.Now()}
+
+	// Nextcloud accounts are named after the user's email.
+	subscribers, err := s.subscribersByUsername(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := 0
+	for offset := 0; ; offset += nextcloudUsersPage {
+		usernames, err := s.nextcloudClient.ListUsers(ctx, offset, nextcloudUsersPage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Nextcloud users: %w", err)
+		}
+		for _, username := range usernames {
+			userID, ok := subscribers[strings.ToLower(username)]
+			if !ok {
+				rec.Unmanaged++
+				continue
+			}
+			matched++
+			s.reconcileUser(ctx, rec, userID, username)
+		}
+		if len(usernames) < nextcloudUsersPage {
+			break
+		}
+	}
+	rec.Missing = len(subscribers) - matched
+	rec.FinishedAt = time.Now()
+
+	if err := s.reconciliationRepo.Create(ctx, rec); err != nil {
+		return nil, fmt.Errorf("failed to store reconciliation report: %w", err)
+	}
+	log.Printf("Quota reconciliation %d (dry run: %t): %d checked, %d in sync, %d drifted, %d corrected, %d unmanaged, %d missing, %d failed.",
+		rec.ID, rec.DryRun, rec.Checked, rec.InSync, rec.Drifted, rec.Corrected, rec.Unmanaged, rec.Missing, rec.Failed)
+	return rec, nil
+}
+
+// subscribersByUsername maps the lowercased email of every subscriber to their user ID.
+// Users whose details cannot be loaded are counted as failed.
+func (s *quotaReconciliationService) subscribersByUsername(ctx context.Context, rec *domain.QuotaReconciliation) (map[string]int64, error) {
+	userIDs, err := s.subRepo.FindLiveUserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribers := make(map[string]int64, len(userIDs))
+	for _, userID := range userIDs {
+		details, err := s.userSvcClient.GetUserDetails(ctx, userID)
+		if err != nil {
+			log.Printf("Quota reconciliation: failed to get details of user %d: %v", userID, err)
+			rec.Failed++
+			continue
+		}
+		subscribers[strings.ToLower(details.Email)] = userID
+	}
+	return subscribers, nil
+}
+
+func (s *quotaReconciliationService) reconcileUser(ctx context.Context, rec *domain.QuotaReconciliation, userID int64, username string) {
+	permissions, err := s.billingService.GetUserPermissions(ctx, userID)
+	if err != nil {
+		log.Printf("Quota reconciliation: failed to get permissions of user %d: %v", userID, err)
+		rec.Failed++
+		return
+	}
+	quotaGB := permissions.StorageQuotaGB
+	actual, err := s.nextcloudClient.GetUserQuota(ctx, username)
+	if err != nil {
+		log.Printf("Quota reconciliation: failed to get Nextcloud quota of %s: %v", username, err)
+		rec.Failed++
+		return
+	}
+
+	rec.Checked++
+	// Nextcloud reads "100 GB" with binary units.
+	if !actual.Unlimited && actual.Bytes == int64(quotaGB)<<30 {
+		rec.InSync++
+		return
+	}
+
+	rec.Drifted++
+	discrepancy := domain.QuotaDiscrepancy{
+		UserID:          userID,
+		Username:        username,
+		ExpectedGB:      quotaGB,
+		ActualBytes:     actual.Bytes,
+		ActualUnlimited: actual.Unlimited,
+	}
+	if !rec.DryRun {
+		if err := s.outboxRepo.Enqueue(ctx, userID, domain.NextcloudSyncQuota); err != nil {
+			log.Printf("Quota reconciliation: failed to queue quota sync of user %d: %v", userID, err)
+		} else {
+			discrepancy.Corrected = true
+			rec.Corrected++
+		}
+	}
+	rec.Discrepancies = append(rec.Discrepancies, discrepancy)
+	log.Printf("Quota reconciliation: %s (user %d) has %d bytes (unlimited: %t), expected %d GB.",
+		username, userID, actual.Bytes, actual.Unlimited, quotaGB)
+}
+
+func (s *quotaReconciliationService) GetReconciliations(ctx context.Context, limit int) ([]domain.QuotaReconciliation, error) {
+	if limit <= 0 || limit > maxListedReconciliations {
+		limit = maxListedReconciliations
+	}
+	return s.reconciliationRepo.FindLatest(ctx, limit)
+}
@@ -0,0 +1,154 @@
+// services/billing-service/internal/service/refund.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"strings"
+	"time"
+)
+
+// RefundRequest describes a refund an admin issues for an invoice.
+type RefundRequest struct {
+	Amount   *domain.Money // nil refunds everything that has not been refunded yet
+	Reason   string
+	RefundTo string // domain.RefundToWallet or domain.RefundToProvider
+	// Downgrade also ends the subscription the invoice was for and moves the user to the
+	// default plan right away.
+	Downgrade bool
+}
+
+func (s *billingService) GetCreditNotes(ctx context.Context, invoiceID int64) ([]domain.CreditNote, error) {
+	if _, err := s.invoiceRepo.FindByID(ctx, invoiceID); err != nil {
+		return nil, fmt.Errorf("invoice %d not found: %w", invoiceID, err)
+	}
+	return s.creditNoteRepo.FindByInvoiceID(ctx, invoiceID)
+}
+
+// RefundInvoice gives back part or all of what was paid for an invoice and records it as a
+// credit note. Only what was actually paid can be refunded, minus earlier credit notes.
+// PROVIDER refunds are paid back against the user's latest top-up payment that covers them.
+func (s *billingService) RefundInvoice(ctx context.Context, adminID, invoiceID int64, req RefundRequest) (*domain.CreditNote, error) {
+	if req.RefundTo != domain.RefundToWallet && req.RefundTo != domain.RefundToProvider {
+		return nil, fmt.Errorf("refunds go to %s or %s: %w", domain.RefundToWallet, domain.RefundToProvider, ierr.ErrInvalidInput)
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("a refund reason is required: %w", ierr.ErrInvalidInput)
+	}
+
+	invoice, err := s.invoiceRepo.FindByID(ctx, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("invoice %d not found: %w", invoiceID, err)
+	}
+	notes, err := s.creditNoteRepo.FindByInvoiceID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := invoice.Total.Currency
+	refundable := domain.NewMoney(invoice.PaidFromBalance.Amount, currency)
+	for _, n := range notes {
+		refundable = refundable.Sub(n.Amount)
+	}
+	if !refundable.IsPositive() {
+		return nil, fmt.Errorf("invoice %d has nothing left to refund: %w", invoiceID, ierr.ErrConflict)
+	}
+
+	amount := refundable
+	if req.Amount != nil {
+		amount = *req.Amount
+		if amount.Currency != currency {
+			return nil, fmt.Errorf("invoice %d was paid in %s: %w", invoiceID, currency, ierr.ErrInvalidInput)
+		}
+		if !amount.IsPositive() {
+			return nil, fmt.Errorf("refund amount must be positive: %w", ierr.ErrInvalidInput)
+		}
+		if amount.Cmp(refundable) > 0 {
+			return nil, fmt.Errorf("at most %s of invoice %d can be refunded: %w", refundable, invoiceID, ierr.ErrConflict)
+		}
+	}
+
+	note := &domain.CreditNote{
+		InvoiceID: invoice.ID,
+		UserID:    invoice.UserID,
+		Amount:    amount,
+		Tax:       domain.NewMoney(0, currency),
+		Reason:    reason,
+		RefundTo:  req.RefundTo,
+		IssuedBy:  adminID,
+	}
+	if invoice.Tax != nil && !invoice.Tax.ReverseCharge {
+		note.Tax = invoice.Tax.Amount.Prorate(amount.Amount, invoice.Total.Amount)
+	}
+
+	if req.RefundTo == domain.RefundToProvider {
+		topUp, err := s.walletRepo.FindRefundableTopUp(ctx, invoice.UserID, amount)
+		if err != nil {
+			if errors.Is(err, ierr.ErrNotFound) {
+				return nil, fmt.Errorf("no payment of user %d covers %s, refund to the wallet instead: %w", invoice.UserID, amount, ierr.ErrConflict)
+			}
+			return nil, err
+		}
+		// The provider deduplicates by reference, so a retry after a failed write below does
+		// not pay out twice.
+		reference := fmt.Sprintf("invoice:%d:refund:%d", invoice.ID, len(notes)+1)
+		refundID, err := s.paymentProvider.Refund(ctx, *topUp.ProviderPaymentID, amount.Amount, currency, reference)
+		if err != nil {
+			return nil, fmt.Errorf("payment provider refused the refund: %w", err)
+		}
+		note.TopUpID = &topUp.ID
+		note.ProviderRefundID = &refundID
+	}
+
+	if err := s.creditNoteRepo.Create(ctx, note); err != nil {
+		if note.ProviderRefundID != nil {
+			log.Printf("CRITICAL: refund %s of invoice %d was paid out but not recorded: %v", *note.ProviderRefundID, invoice.ID, err)
+		}
+		if errors.Is(err, ierr.ErrConflict) {
+			return nil, fmt.Errorf("invoice %d was refunded concurrently, try again: %w", invoice.ID, err)
+		}
+		return nil, err
+	}
+	log.Printf("Admin %d refunded %s of invoice %d to %s (credit note %d).", adminID, amount, invoice.ID, req.RefundTo, note.ID)
+
+	message := fmt.Sprintf("We have refunded %s of invoice %d. Reason: %s", amount, invoice.ID, reason)
+	if err := s.notifier.Notify(ctx, invoice.UserID, "You received a refund", message); err != nil {
+		log.Printf("Failed to notify user %d about credit note %d: %v", invoice.UserID, note.ID, err)
+	}
+
+	if req.Downgrade {
+		if err := s.downgradeRefunded(ctx, invoice, note); err != nil {
+			return nil, fmt.Errorf("credit note %d was issued, but the downgrade failed: %w", note.ID, err)
+		}
+	}
+	return note, nil
+}
+
+// downgradeRefunded ends the subscription a refunded invoice was for, if it is still running.
+func (s *billingService) downgradeRefunded(ctx context.Context, invoice *domain.Invoice, note *domain.CreditNote) error {
+	sub, err := s.subRepo.FindByUserID(ctx, invoice.UserID)
+	if err != nil {
+		return fmt.Errorf("active subscription not found: %w", err)
+	}
+	if sub.ID != invoice.SubscriptionID {
+		return fmt.Errorf("subscription %d of invoice %d has already ended: %w", invoice.SubscriptionID, invoice.ID, ierr.ErrConflict)
+	}
+	plan, err := s.planRepo.FindByID(ctx, sub.PlanID)
+	if err != nil {
+		return fmt.Errorf("could not load current plan %d: %w", sub.PlanID, err)
+	}
+	if plan.Name == defaultPlanName {
+		return fmt.Errorf("the user is already on the %s plan: %w", defaultPlanName, ierr.ErrConflict)
+	}
+
+	now := time.Now()
+	sub.CanceledAt = &now
+	sub.CancelFeedback = optionalString(fmt.Sprintf("Refunded with credit note %d", note.ID))
+	sub.PendingPlanID = nil
+	return s.endSubscription(ctx, sub, now, domain.ChangeByAdmin(domain.SubscriptionEventCanceled, note.IssuedBy, *sub.CancelFeedback))
+}
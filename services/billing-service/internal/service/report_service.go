@@ -0,0 +1,193 @@
+// services/billing-service/internal/service/report_service.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"sort"
+	"time"
+)
+
+// maxReportPeriods caps how many monthly periods a revenue report covers.
+const maxReportPeriods = 61
+
+// ReportService computes revenue and subscription reports from the subscription history.
+type ReportService interface {
+	// GetMRRReport returns the MRR movements of every calendar month (UTC) overlapping
+	// [from, to), with the first and last period cut to the range.
+	GetMRRReport(ctx context.Context, from, to time.Time) ([]domain.MRRReport, error)
+	// GetPlanMix returns the users on each plan just before `at`.
+	GetPlanMix(ctx context.Context, at time.Time) ([]domain.PlanMixEntry, error)
+	// GetTrialConversions returns the outcome of the trials started in [from, to) per plan.
+	GetTrialConversions(ctx context.Context, from, to time.Time) ([]domain.TrialConversion, error)
+}
+
+type reportService struct {
+	reportRepo repository.ReportRepository
+}
+
+func NewReportService(reportRepo repository.ReportRepository) ReportService {
+	return &reportService{reportRepo: reportRepo}
+}
+
+// userCurrency identifies the revenue of one user in one currency. Revenue is compared per
+// user rather than per subscription, since a user who cancels gets a new free subscription.
+type userCurrency struct {
+	userID   int64
+	currency string
+}
+
+func (s *reportService) GetMRRReport(ctx context.Context, from, to time.Time) ([]domain.MRRReport, error) {
+	boundaries, err := reportBoundaries(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	mrrAt := make([]map[userCurrency]int64, len(boundaries))
+	currencies := map[string]bool{}
+	for i, at := range boundaries {
+		snapshots, err := s.reportRepo.FindSnapshotsAt(ctx, at)
+		if err != nil {
+			return nil, err
+		}
+		mrr := map[userCurrency]int64{}
+		for _, snap := range snapshots {
+			if snap.MRR.IsPositive() {
+				mrr[userCurrency{snap.UserID, snap.MRR.Currency}] += snap.MRR.Amount
+				currencies[snap.MRR.Currency] = true
+			}
+		}
+		mrrAt[i] = mrr
+	}
+
+	reports := []domain.MRRReport{}
+	for i := 0; i+1 < len(boundaries); i++ {
+		byCurrency := make(map[string]*domain.MRRReport, len(currencies))
+		for _, currency := range sortedCurrencies(currencies) {
+			zero := domain.NewMoney(0, currency)
+			byCurrency[currency] = &domain.MRRReport{
+				PeriodStart: boundaries[i], PeriodEnd: boundaries[i+1], Currency: currency,
+				StartingMRR: zero, NewMRR: zero, ExpansionMRR: zero, ContractionMRR: zero, ChurnedMRR: zero,
+				EndingMRR: zero, EndingARR: zero,
+			}
+		}
+		start, end := mrrAt[i], mrrAt[i+1]
+		for key, before := range start {
+			r := byCurrency[key.currency]
+			after := end[key]
+			r.StartingMRR.Amount += before
+			switch {
+			case after == 0:
+				r.ChurnedMRR.Amount += before
+				r.ChurnedSubscribers++
+			case after > before:
+				r.ExpansionMRR.Amount += after - before
+			case after < before:
+				r.ContractionMRR.Amount += before - after
+			}
+		}
+		for key, after := range end {
+			r := byCurrency[key.currency]
+			r.EndingMRR.Amount += after
+			r.Subscribers++
+			if start[key] == 0 {
+				r.NewMRR.Amount += after
+				r.NewSubscribers++
+			}
+		}
+		for _, currency := range sortedCurrencies(currencies) {
+			r := byCurrency[currency]
+			r.EndingARR.Amount = r.EndingMRR.Amount * 12
+			reports = append(reports, *r)
+		}
+	}
+	return reports, nil
+}
+
+func (s *reportService) GetPlanMix(ctx context.Context, at time.Time) ([]domain.PlanMixEntry, error) {
+	snapshots, err := s.reportRepo.FindSnapshotsAt(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	type planCurrency struct {
+		planID   int64
+		currency string
+	}
+	entries := map[planCurrency]*domain.PlanMixEntry{}
+	for _, snap := range snapshots {
+		key := planCurrency{snap.PlanID, snap.MRR.Currency}
+		entry, ok := entries[key]
+		if !ok {
+			entry = &domain.PlanMixEntry{PlanID: snap.PlanID, PlanName: snap.PlanName, Currency: snap.MRR.Currency,
+				MRR: domain.NewMoney(0, snap.MRR.Currency)}
+			entries[key] = entry
+		}
+		if snap.Status == domain.SubscriptionStatusTrialing {
+			entry.Trialing++
+		} else {
+			entry.Active++
+		}
+		entry.MRR = entry.MRR.Add(snap.MRR)
+	}
+
+	mix := make([]domain.PlanMixEntry, 0, len(entries))
+	for _, entry := range entries {
+		mix = append(mix, *entry)
+	}
+	sort.Slice(mix, func(i, j int) bool {
+		if mix[i].PlanID != mix[j].PlanID {
+			return mix[i].PlanID < mix[j].PlanID
+		}
+		return mix[i].Currency < mix[j].Currency
+	})
+	return mix, nil
+}
+
+func (s *reportService) GetTrialConversions(ctx context.Context, from, to time.Time) ([]domain.TrialConversion, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("the report range must end after it starts: %w", ierr.ErrInvalidInput)
+	}
+	conversions, err := s.reportRepo.FindTrialConversions(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range conversions {
+		c := &conversions[i]
+		if ended := c.Converted + c.NotConverted; ended > 0 {
+			c.ConversionRate = float64(c.Converted) / float64(ended)
+		}
+	}
+	return conversions, nil
+}
+
+// reportBoundaries splits [from, to) at the start of every calendar month (UTC) and returns
+// from, the month starts in between and to.
+func reportBoundaries(from, to time.Time) ([]time.Time, error) {
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		return nil, fmt.Errorf("the report range must end after it starts: %w", ierr.ErrInvalidInput)
+	}
+
+	boundaries := []time.Time{from}
+	month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	for ; month.Before(to); month = month.AddDate(0, 1, 0) {
+		if len(boundaries) == maxReportPeriods {
+			return nil, fmt.Errorf("the report range may span at most %d months: %w", maxReportPeriods-1, ierr.ErrInvalidInput)
+		}
+		boundaries = append(boundaries, month)
+	}
+	return append(boundaries, to), nil
+}
+
+func sortedCurrencies(set map[string]bool) []string {
+	currencies := make([]string, 0, len(set))
+	for currency := range set {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
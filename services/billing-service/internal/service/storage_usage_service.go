@@ -0,0 +1,184 @@
+// services/billing-service/internal/service/storage_usage_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/client"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"time"
+)
+
+// StorageUsageService reads users' storage usage back from Nextcloud, adds the size of their
+// videos as video-service reports it, warns them when they are running out of storage and
+// hands it to the OverLimitService.
+type StorageUsageService interface {
+	// GetStorageUsage returns the user's usage, fetching it from Nextcloud if the stored one is
+	// older than the cache TTL.
+	GetStorageUsage(ctx context.Context, userID int64) (*domain.StorageUsage, error)
+	// RefreshAll fetches the usage of every subscriber, sending the warnings that are due.
+	RefreshAll(ctx context.Context) error
+	// RecordVideoUsage stores how many bytes the user's videos took at measuredAt and checks
+	// their storage against their quota again. Reports older than the stored one are ignored.
+	RecordVideoUsage(ctx context.Context, userID, usedBytes int64, measuredAt time.Time) error
+}
+
+type storageUsageService struct {
+	usageRepo       repository.StorageUsageRepository
+	subRepo         repository.SubscriptionRepository
+	billingService  BillingService
+	userSvcClient   client.UserServiceClient
+	nextcloudClient client.NextcloudClient
+	notifier        client.Notifier
+	overLimit       OverLimitService
+	cacheTTL        time.Duration
+}
+
+func NewStorageUsageService(usageRepo repository.StorageUsageRepository, subRepo repository.SubscriptionRepository, billingService BillingService, userSvcClient client.UserServiceClient, ncClient client.NextcloudClient, notifier client.Notifier, overLimit OverLimitService, cacheTTL time.Duration) StorageUsageService {
+	return &storageUsageService{
+		usageRepo:       usageRepo,
+		subRepo:         subRepo,
+		billingService:  billingService,
+		userSvcClient:   userSvcClient,
+		nextcloudClient: ncClient,
+		notifier:        notifier,
+		overLimit:       overLimit,
+		cacheTTL:        cacheTTL,
+	}
+}
+
+func (s *storageUsageService) GetStorageUsage(ctx context.Context, userID int64) (*domain.StorageUsage, error) {
+	usage, err := s.usageRepo.FindByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, ierr.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil || time.Since(usage.FetchedAt) >= s.cacheTTL {
+		if usage, err = s.refresh(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if usage.OverLimit, err = s.overLimit.GetCase(ctx, userID); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (s *storageUsageService) RefreshAll(ctx context.Context) error {
+	userIDs, err := s.subRepo.FindLiveUserIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if _, err := s.refresh(ctx, userID); err != nil {
+			log.Printf("Failed to refresh storage usage of user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+// refresh fetches the user's usage from Nextcloud, stores it, warns the user if it crossed
+// one of the warning levels since the last refresh and opens or resolves their over-limit case.
+func (s *storageUsageService) refresh(ctx context.Context, userID int64) (*domain.StorageUsage, error) {
+	permissions, err := s.billingService.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+
+	userDetails, err := s.userSvcClient.GetUserDetails(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user details: %w", err)
+	}
+	quota, err := s.nextcloudClient.GetUserQuota(ctx, userDetails.Email)
+	if err != nil {
+		if errors.Is(err, client.ErrNextcloudUserNotFound) {
+			return nil, fmt.Errorf("user %d has no Nextcloud account yet: %w", userID, ierr.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get Nextcloud usage of %s: %w", userDetails.Email, err)
+	}
+
+	videoBytes, err := s.usageRepo.FindVideoBytes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video storage: %w", err)
+	}
+
+	usage := &domain.StorageUsage{
+		UserID:     userID,
+		UsedBytes:  quota.UsedBytes,
+		FreeBytes:  quota.FreeBytes,
+		VideoBytes: videoBytes,
+		QuotaGB:    permissions.StorageQuotaGB,
+		FetchedAt:  time.Now(),
+	}
+	if usage.QuotaGB > 0 {
+		usage.UsedPercent = float64(usage.TotalBytes()) / float64(domain.QuotaBytes(usage.QuotaGB)) * 100
+	}
+	if err := s.usageRepo.Save(ctx, usage); err != nil {
+		return nil, fmt.Errorf("failed to store storage usage: %w", err)
+	}
+
+	s.warnIfDue(ctx, usage)
+	if err := s.overLimit.Evaluate(ctx, userID, usage.TotalBytes(), usage.QuotaGB); err != nil {
+		log.Printf("Failed to check whether user %d exceeds their storage: %v", userID, err)
+	}
+	return usage, nil
+}
+
+func (s *storageUsageService) RecordVideoUsage(ctx context.Context, userID, usedBytes int64, measuredAt time.Time) error {
+	if usedBytes < 0 {
+		return fmt.Errorf("used bytes must not be negative: %w", ierr.ErrInvalidInput)
+	}
+	if measuredAt.IsZero() {
+		return fmt.Errorf("measurement time is required: %w", ierr.ErrInvalidInput)
+	}
+	saved, err := s.usageRepo.SaveVideoBytes(ctx, userID, usedBytes, measuredAt)
+	if err != nil || !saved {
+		return err
+	}
+
+	permissions, err := s.billingService.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get permissions: %w", err)
+	}
+	// Until Nextcloud usage was fetched, the next refresh checks the quota.
+	return s.overLimit.EvaluateQuota(ctx, userID, permissions.StorageQuotaGB)
+}
+
+// warnIfDue notifies the user once they reach a higher warning level than before. Falling back
+// below a level resets it, so that the next crossing is warned about again.
+func (s *storageUsageService) warnIfDue(ctx context.Context, usage *domain.StorageUsage) {
+	previous := usage.WarningLevel
+	level := domain.StorageWarningLevel(usage.UsedPercent)
+	if level == previous {
+		return
+	}
+	claimed, err := s.usageRepo.SetWarningLevel(ctx, usage.UserID, previous, level)
+	if err != nil {
+		log.Printf("Failed to update storage warning level of user %d: %v", usage.UserID, err)
+		return
+	}
+	if !claimed || level < previous {
+		return
+	}
+	usage.WarningLevel = level
+
+	subject := "Your storage is almost full"
+	message := fmt.Sprintf("You are using %.1f GB (%.0f%%) of your %d GB of storage. Free up space or upgrade your plan to keep uploading.",
+		gigabytes(usage.TotalBytes()), usage.UsedPercent, usage.QuotaGB)
+	if level >= 100 {
+		subject = "Your storage is full"
+		message = fmt.Sprintf("You are using %.1f GB of your %d GB of storage, so new uploads will fail. Free up space or upgrade your plan.",
+			gigabytes(usage.TotalBytes()), usage.QuotaGB)
+	}
+	if err := s.notifier.Notify(ctx, usage.UserID, subject, message); err != nil {
+		log.Printf("Failed to send storage warning to user %d: %v", usage.UserID, err)
+		// Let the next refresh try again.
+		if _, err := s.usageRepo.SetWarningLevel(ctx, usage.UserID, level, previous); err != nil {
+			log.Printf("Failed to reset storage warning level of user %d: %v", usage.UserID, err)
+		}
+	}
+}
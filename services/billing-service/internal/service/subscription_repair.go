@@ -0,0 +1,58 @@
+// services/billing-service/internal/service/subscription_repair.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"log"
+	"time"
+)
+
+// repairBatch is how many user ids are checked per page of user-service's user list.
+const repairBatch = 500
+
+func (s *billingService) RepairMissingSubscriptions(ctx context.Context) (*domain.SubscriptionRepair, error) {
+	repair := &domain.SubscriptionRepair{Assigned: []int64{}, StartedAt: time.Now()}
+
+	var afterID int64
+	for {
+		userIDs, err := s.userSvcClient.ListUserIDs(ctx, afterID, repairBatch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list users: %w", err)
+		}
+		if len(userIDs) == 0 {
+			break
+		}
+		repair.Checked += len(userIDs)
+		afterID = userIDs[len(userIDs)-1]
+
+		missing, err := s.subRepo.FindUserIDsWithoutLiveSubscription(ctx, userIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range missing {
+			repair.Missing++
+			created, err := s.createInitialSubscription(ctx, userID, defaultPlanName, "Repaired missing subscription")
+			if err != nil {
+				repair.Failed++
+				log.Printf("Failed to assign the %s plan to user %d without a subscription: %v", defaultPlanName, userID, err)
+				continue
+			}
+			if created {
+				repair.Assigned = append(repair.Assigned, userID)
+			}
+		}
+
+		if len(userIDs) < repairBatch {
+			break
+		}
+	}
+
+	repair.FinishedAt = time.Now()
+	if repair.Missing > 0 {
+		log.Printf("Subscription repair found %d of %d users without a subscription, assigned the %s plan to %d, %d failed.",
+			repair.Missing, repair.Checked, defaultPlanName, len(repair.Assigned), repair.Failed)
+	}
+	return repair, nil
+}
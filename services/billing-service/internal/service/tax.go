@@ -0,0 +1,110 @@
+// services/billing-service/internal/service/tax.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"strings"
+)
+
+// taxTreatment is how the charges of one customer are taxed.
+type taxTreatment struct {
+	rate          *domain.TaxRate // nil when no tax applies
+	reverseCharge bool
+	customerTaxID *string
+}
+
+// taxTreatmentFor determines the tax of a customer from their billing profile. Customers who
+// have not filled in a profile are taxed like consumers in the seller's country.
+//
+// A business with a tax ID in another EU country than the seller is reverse-charged: it is
+// invoiced without tax and accounts for the tax itself.
+func (s *billingService) taxTreatmentFor(ctx context.Context, userID int64) (taxTreatment, error) {
+	profile, err := s.profileRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, ierr.ErrNotFound) {
+			return taxTreatment{}, err
+		}
+		profile = &domain.BillingProfile{UserID: userID, Country: s.sellerCountry}
+	}
+
+	rate, err := s.taxRateRepo.FindForLocation(ctx, profile.Country, profile.Region)
+	if err != nil {
+		if errors.Is(err, ierr.ErrNotFound) {
+			return taxTreatment{}, nil
+		}
+		return taxTreatment{}, fmt.Errorf("could not load tax rate for %s: %w", profile.Country, err)
+	}
+
+	t := taxTreatment{rate: rate}
+	if profile.IsBusiness && profile.TaxID != nil && profile.Country != s.sellerCountry &&
+		domain.IsEUCountry(profile.Country) && domain.IsEUCountry(s.sellerCountry) {
+		t.reverseCharge = true
+		t.customerTaxID = profile.TaxID
+	}
+	return t, nil
+}
+
+// calculate returns the tax on `amount` and the total the customer pays.
+// Exclusive tax is added on top, inclusive tax is carved out of the amount, and reverse-charged
+// amounts are invoiced as they are, without tax.
+func (t taxTreatment) calculate(amount domain.Money) (*domain.TaxLine, domain.Money) {
+	if t.rate == nil {
+		return nil, amount
+	}
+
+	line := &domain.TaxLine{
+		Name:      t.rate.Name,
+		Country:   t.rate.Country,
+		Region:    t.rate.Region,
+		RateBps:   t.rate.RateBps,
+		Inclusive: t.rate.Inclusive,
+		Taxable:   amount,
+		Amount:    domain.NewMoney(0, amount.Currency),
+	}
+	switch {
+	case t.reverseCharge:
+		line.RateBps = 0
+		line.ReverseCharge = true
+		line.CustomerTaxID = t.customerTaxID
+		return line, amount
+	case t.rate.Inclusive:
+		// amount = net * (1 + rate), so net = amount * 10000 / (10000 + rate)
+		line.Taxable = amount.Prorate(10000, 10000+t.rate.RateBps)
+		line.Amount = amount.Sub(line.Taxable)
+		return line, amount
+	}
+	line.Amount = amount.MulBasisPoints(t.rate.RateBps)
+	return line, amount.Add(line.Amount)
+}
+
+// applyToPreview adds the tax to the amount due of a plan change.
+func (t taxTreatment) applyToPreview(preview *domain.ProrationPreview) {
+	preview.Subtotal = preview.AmountDue
+	preview.Tax, preview.AmountDue = t.calculate(preview.Subtotal)
+}
+
+// applyToInvoice taxes the invoice's total. Exclusive tax gets its own line so that the
+// lines keep adding up to the total.
+func (t taxTreatment) applyToInvoice(invoice *domain.Invoice) {
+	invoice.Subtotal = invoice.Total
+	invoice.Tax, invoice.Total = t.calculate(invoice.Subtotal)
+	if tax := invoice.Tax; tax != nil && !tax.Inclusive && tax.Amount.IsPositive() {
+		invoice.Lines = append(invoice.Lines, domain.InvoiceLine{
+			Kind:        domain.InvoiceLineTax,
+			Description: fmt.Sprintf("%s %s", tax.Name, formatTaxRate(tax.RateBps)),
+			Amount:      tax.Amount,
+		})
+	}
+}
+
+// formatTaxRate formats basis points as a percentage: 1900 is "19%", 750 is "7.5%".
+func formatTaxRate(bps int64) string {
+	if bps%100 == 0 {
+		return fmt.Sprintf("%d%%", bps/100)
+	}
+	return strings.TrimRight(fmt.Sprintf("%d.%02d", bps/100, bps%100), "0") + "%"
+}
@@ -0,0 +1,130 @@
+// services/billing-service/internal/service/tax_service.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"regexp"
+	"strings"
+)
+
+// TaxService manages tax rates (admin) and the billing profiles customers are taxed by.
+type TaxService interface {
+	GetTaxRates(ctx context.Context) ([]domain.TaxRate, error)
+	SaveTaxRate(ctx context.Context, rate *domain.TaxRate) (*domain.TaxRate, error)
+	DeleteTaxRate(ctx context.Context, id int64) error
+	GetBillingProfile(ctx context.Context, userID int64) (*domain.BillingProfile, error)
+	SaveBillingProfile(ctx context.Context, profile *domain.BillingProfile) (*domain.BillingProfile, error)
+}
+
+type taxService struct {
+	taxRateRepo repository.TaxRateRepository
+	profileRepo repository.BillingProfileRepository
+}
+
+func NewTaxService(taxRateRepo repository.TaxRateRepository, profileRepo repository.BillingProfileRepository) TaxService {
+	return &taxService{
+		taxRateRepo: taxRateRepo,
+		profileRepo: profileRepo,
+	}
+}
+
+var (
+	countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	regionPattern  = regexp.MustCompile(`^[A-Z0-9]{1,3}$`)
+	taxIDPattern   = regexp.MustCompile(`^[A-Z0-9]{4,20}$`)
+	// taxIDSeparators are stripped from tax IDs, users often type "DE 123.456.789".
+	taxIDSeparators = strings.NewReplacer(" ", "", ".", "", "-", "")
+)
+
+func (s *taxService) GetTaxRates(ctx context.Context) ([]domain.TaxRate, error) {
+	return s.taxRateRepo.FindAll(ctx)
+}
+
+// SaveTaxRate creates the rate of a country or region, or replaces the existing one.
+// Invoices keep a copy of the rate they were taxed with, so changing a rate never alters them.
+func (s *taxService) SaveTaxRate(ctx context.Context, rate *domain.TaxRate) (*domain.TaxRate, error) {
+	rate.Country = strings.ToUpper(strings.TrimSpace(rate.Country))
+	rate.Region = strings.ToUpper(strings.TrimSpace(rate.Region))
+	rate.Name = strings.TrimSpace(rate.Name)
+
+	if err := validateLocation(rate.Country, rate.Region); err != nil {
+		return nil, err
+	}
+	if rate.Name == "" {
+		return nil, fmt.Errorf("tax name is required: %w", ierr.ErrInvalidInput)
+	}
+	if rate.RateBps < 0 || rate.RateBps > 10000 {
+		return nil, fmt.Errorf("rate_bps must be between 0 and 10000: %w", ierr.ErrInvalidInput)
+	}
+
+	if err := s.taxRateRepo.Save(ctx, rate); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+func (s *taxService) DeleteTaxRate(ctx context.Context, id int64) error {
+	return s.taxRateRepo.Delete(ctx, id)
+}
+
+func (s *taxService) GetBillingProfile(ctx context.Context, userID int64) (*domain.BillingProfile, error) {
+	return s.profileRepo.FindByUserID(ctx, userID)
+}
+
+// SaveBillingProfile stores where the customer is located and whether they buy as a business.
+// Only businesses can have a tax ID; EU VAT numbers must carry the prefix of the profile's country.
+func (s *taxService) SaveBillingProfile(ctx context.Context, profile *domain.BillingProfile) (*domain.BillingProfile, error) {
+	profile.Country = strings.ToUpper(strings.TrimSpace(profile.Country))
+	profile.Region = strings.ToUpper(strings.TrimSpace(profile.Region))
+	if err := validateLocation(profile.Country, profile.Region); err != nil {
+		return nil, err
+	}
+
+	if !profile.IsBusiness {
+		profile.CompanyName = nil
+		profile.TaxID = nil
+	} else {
+		if profile.CompanyName != nil {
+			profile.CompanyName = optionalString(strings.TrimSpace(*profile.CompanyName))
+		}
+		if profile.CompanyName == nil {
+			return nil, fmt.Errorf("company name is required for businesses: %w", ierr.ErrInvalidInput)
+		}
+		if profile.TaxID != nil {
+			taxID := strings.ToUpper(taxIDSeparators.Replace(*profile.TaxID))
+			if err := validateTaxID(profile.Country, taxID); err != nil {
+				return nil, err
+			}
+			profile.TaxID = optionalString(taxID)
+		}
+	}
+
+	if err := s.profileRepo.Save(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func validateLocation(country, region string) error {
+	if !countryPattern.MatchString(country) {
+		return fmt.Errorf("country must be an ISO 3166-1 alpha-2 code: %w", ierr.ErrInvalidInput)
+	}
+	if region != "" && !regionPattern.MatchString(region) {
+		return fmt.Errorf("region must be the subdivision part of an ISO 3166-2 code: %w", ierr.ErrInvalidInput)
+	}
+	return nil
+}
+
+func validateTaxID(country, taxID string) error {
+	if !taxIDPattern.MatchString(taxID) {
+		return fmt.Errorf("tax id must be 4-20 letters and digits: %w", ierr.ErrInvalidInput)
+	}
+	if domain.IsEUCountry(country) && !strings.HasPrefix(taxID, domain.VATPrefix(country)) {
+		return fmt.Errorf("VAT numbers from %s start with '%s': %w", country, domain.VATPrefix(country), ierr.ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// services/billing-service/internal/service/tax_test.go
+package service
+
+import (
+	"jcloud-project/billing-service/internal/domain"
+	"testing"
+)
+
+func TestTaxTreatmentCalculate(t *testing.T) {
+	vat := &domain.TaxRate{Country: "DE", Name: "VAT", RateBps: 1900, Inclusive: true}
+	salesTax := &domain.TaxRate{Country: "US", Region: "US-NY", Name: "Sales tax", RateBps: 888}
+	taxID := "FR12345678901"
+	eur := func(amount int64) domain.Money { return domain.NewMoney(amount, "EUR") }
+
+	tests := []struct {
+		name      string
+		treatment taxTreatment
+		amount    domain.Money
+		wantTax   bool
+		taxable   domain.Money
+		tax       domain.Money
+		total     domain.Money
+		reverse   bool
+	}{
+		{
+			name: "no tax", treatment: taxTreatment{}, amount: eur(1190),
+			total: eur(1190),
+		},
+		{
+			name: "inclusive", treatment: taxTreatment{rate: vat}, amount: eur(1190),
+			wantTax: true, taxable: eur(1000), tax: eur(190), total: eur(1190),
+		},
+		{
+			name: "inclusive rounds the net amount", treatment: taxTreatment{rate: vat}, amount: eur(999),
+			wantTax: true, taxable: eur(839), tax: eur(160), total: eur(999), // 839.496
+		},
+		{
+			name: "exclusive", treatment: taxTreatment{rate: salesTax}, amount: domain.NewMoney(1000, "USD"),
+			wantTax: true, taxable: domain.NewMoney(1000, "USD"), tax: domain.NewMoney(89, "USD"), total: domain.NewMoney(1089, "USD"), // 88.8
+		},
+		{
+			name: "reverse charge", treatment: taxTreatment{rate: vat, reverseCharge: true, customerTaxID: &taxID}, amount: eur(1190),
+			wantTax: true, taxable: eur(1190), tax: eur(0), total: eur(1190), reverse: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, total := tt.treatment.calculate(tt.amount)
+			if total != tt.total {
+				t.Errorf("total = %v, want %v", total, tt.total)
+			}
+			if (line != nil) != tt.wantTax {
+				t.Fatalf("tax line = %+v, want one: %v", line, tt.wantTax)
+			}
+			if line == nil {
+				return
+			}
+			if line.Taxable != tt.taxable || line.Amount != tt.tax {
+				t.Errorf("taxable, tax = %v, %v, want %v, %v", line.Taxable, line.Amount, tt.taxable, tt.tax)
+			}
+			if line.ReverseCharge != tt.reverse {
+				t.Errorf("ReverseCharge = %v, want %v", line.ReverseCharge, tt.reverse)
+			}
+			if tt.reverse && (line.RateBps != 0 || line.CustomerTaxID == nil || *line.CustomerTaxID != taxID) {
+				t.Errorf("reverse-charged line = %+v, want rate 0 and the customer's tax ID", line)
+			}
+			if !tt.reverse && line.RateBps != tt.treatment.rate.RateBps {
+				t.Errorf("RateBps = %d, want %d", line.RateBps, tt.treatment.rate.RateBps)
+			}
+		})
+	}
+}
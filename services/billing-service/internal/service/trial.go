@@ -0,0 +1,157 @@
+// services/billing-service/internal/service/trial.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"strings"
+	"time"
+)
+
+// trialReminderLead is how long before the end of a trial the user is reminded about it.
+const trialReminderLead = 3 * 24 * time.Hour
+
+// StartTrial moves a user on a free plan to a trial of a paid plan. When autoConvert is false
+// the trial is scheduled for cancellation, so the user returns to the default plan when it ends.
+// The currency is chosen up front, since a converted trial is billed in it.
+func (s *billingService) StartTrial(ctx context.Context, userID, planID int64, autoConvert bool, pref CurrencyPreference) (*domain.UserSubscriptionDetails, error) {
+	plan, err := s.planRepo.FindByID(ctx, planID)
+	if err != nil {
+		return nil, fmt.Errorf("plan not found: %w", ierr.ErrNotFound)
+	}
+	if !plan.IsActive || plan.TrialDays <= 0 {
+		return nil, fmt.Errorf("plan '%s' does not offer a trial: %w", plan.Name, ierr.ErrConflict)
+	}
+
+	sub, err := s.subRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("active subscription not found: %w", err)
+	}
+	current, err := s.planRepo.FindByID(ctx, sub.PlanID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load current plan %d: %w", sub.PlanID, err)
+	}
+	if sub.Status != domain.SubscriptionStatusActive || !current.IsFree() {
+		return nil, fmt.Errorf("trials are only available on the %s plan: %w", defaultPlanName, ierr.ErrConflict)
+	}
+	currency, err := s.billingCurrency(sub, current, plan, pref)
+	if err != nil {
+		return nil, err
+	}
+
+	// user-service is the source of truth for the email the account was registered with
+	userDetails, err := s.userSvcClient.GetUserDetails(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user details: %w", err)
+	}
+	// Without a verified address, new accounts could start one trial after another
+	if !userDetails.EmailVerified {
+		return nil, fmt.Errorf("verify your email address to start a trial: %w", ierr.ErrConflict)
+	}
+	email := normalizeEmail(userDetails.Email)
+
+	used, err := s.trialRepo.Exists(ctx, userID, email)
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		return nil, fmt.Errorf("a trial has already been used for this account: %w", ierr.ErrConflict)
+	}
+
+	// Usage on the free plan is billed on its terms before the trial starts.
+	now := time.Now()
+	sub.Currency = currency
+	usage, err := s.closingUsageLines(ctx, sub, now)
+	if err != nil {
+		return nil, err
+	}
+	sub.UsageBilledUntil = now
+
+	trialEnd := now.AddDate(0, 0, plan.TrialDays)
+	sub.PlanID = plan.ID
+	sub.PlanVersionID = plan.CurrentVersionID
+	sub.Status = domain.SubscriptionStatusTrialing
+	sub.StartsAt = now
+	sub.EndsAt = trialEnd
+	sub.TrialEndsAt = &trialEnd
+	sub.TrialReminderSentAt = nil
+	sub.PendingPlanID = nil
+	clearCancellation(sub)
+	if !autoConvert {
+		sub.CancelAtPeriodEnd = true
+		sub.CanceledAt = &now
+	}
+	change := domain.ChangeByUser(domain.SubscriptionEventTrialStarted, userID, "")
+	// Both user_id and email of trial_redemptions are unique, a concurrent second trial fails here
+	writes := repository.SubscriptionWrites{Trial: &domain.TrialRedemption{UserID: userID, Email: email, PlanID: plan.ID}}
+	if len(usage) > 0 {
+		tax, err := s.taxTreatmentFor(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		writes.Invoice = linesInvoice(sub, usage)
+		tax.applyToInvoice(writes.Invoice)
+	}
+	if err := s.subRepo.UpdateWith(ctx, sub, change, writes); err != nil {
+		if errors.Is(err, ierr.ErrConflict) {
+			return nil, fmt.Errorf("a trial has already been used for this account: %w", err)
+		}
+		return nil, err
+	}
+
+	log.Printf("User %d started a %d-day trial of plan %d.", userID, plan.TrialDays, plan.ID)
+	return s.subRepo.FindDetailsByUserID(ctx, userID)
+}
+
+// sendTrialReminders notifies users whose trial ends within trialReminderLead, once per trial.
+func (s *billingService) sendTrialReminders(ctx context.Context, now time.Time) error {
+	subs, err := s.subRepo.FindTrialsEndingBefore(ctx, now.Add(trialReminderLead))
+	if err != nil {
+		return err
+	}
+
+	for i := range subs {
+		sub := &subs[i]
+		plan, err := s.planRepo.FindByID(ctx, sub.PlanID)
+		if err != nil {
+			log.Printf("Failed to load plan %d for trial reminder of user %d: %v", sub.PlanID, sub.UserID, err)
+			continue
+		}
+
+		message := fmt.Sprintf("Your %s trial ends on %s. It will then continue as a paid subscription.",
+			plan.Name, sub.EndsAt.Format(time.DateOnly))
+		if sub.CancelAtPeriodEnd {
+			message = fmt.Sprintf("Your %s trial ends on %s. You will then be moved to the %s plan.",
+				plan.Name, sub.EndsAt.Format(time.DateOnly), defaultPlanName)
+		}
+		if err := s.notifier.Notify(ctx, sub.UserID, "Your trial is ending soon", message); err != nil {
+			log.Printf("Failed to send trial reminder to user %d: %v", sub.UserID, err)
+			continue
+		}
+
+		sub.TrialReminderSentAt = &now
+		if err := s.subRepo.Update(ctx, sub, nil); err != nil {
+			log.Printf("Failed to mark trial reminder as sent for subscription %d: %v", sub.ID, err)
+		}
+	}
+	return nil
+}
+
+// normalizeEmail lowercases an address and drops its "+tag", so that
+// "John+trial@example.com" and "john@example.com" count as the same person.
+func normalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	local, domainPart, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+	if i := strings.IndexByte(local, '+'); i >= 0 {
+		local = local[:i]
+	}
+	return local + "@" + domainPart
+}
@@ -0,0 +1,180 @@
+// services/billing-service/internal/service/usage.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"sort"
+	"time"
+)
+
+// usageClockSkew is how far in the future a usage event may be timestamped, to tolerate
+// clock drift between services.
+const usageClockSkew = 5 * time.Minute
+
+// usageMetricNames are the invoice descriptions of the usage metrics.
+var usageMetricNames = map[string]string{
+	domain.UsageMetricStorageGBHours:   "Storage (GB-hours)",
+	domain.UsageMetricTranscodeMinutes: "Transcoding (minutes)",
+	domain.UsageMetricEgressBytes:      "Egress (bytes)",
+}
+
+// RecordUsage validates and stores a batch of usage events. Events that were already recorded
+// are skipped. Events from before the user's usage was last invoiced are not recorded either,
+// since no invoice would ever bill them, and are returned as late.
+func (s *billingService) RecordUsage(ctx context.Context, events []domain.UsageEvent) (*domain.UsageRecording, error) {
+	now := time.Now()
+	for i, e := range events {
+		switch {
+		case e.EventID == "" || len(e.EventID) > 128:
+			return nil, fmt.Errorf("event %d: event id must be 1-128 characters: %w", i, ierr.ErrInvalidInput)
+		case e.UserID <= 0:
+			return nil, fmt.Errorf("event %d: user id is required: %w", i, ierr.ErrInvalidInput)
+		case !domain.IsValidUsageMetric(e.Metric):
+			return nil, fmt.Errorf("event %d: unknown metric '%s': %w", i, e.Metric, ierr.ErrInvalidInput)
+		case e.Quantity < 0:
+			return nil, fmt.Errorf("event %d: quantity must not be negative: %w", i, ierr.ErrInvalidInput)
+		case e.OccurredAt.IsZero() || e.OccurredAt.After(now.Add(usageClockSkew)):
+			return nil, fmt.Errorf("event %d: occurred at must be set and not in the future: %w", i, ierr.ErrInvalidInput)
+		}
+	}
+
+	userIDs := make([]int64, 0, len(events))
+	seen := make(map[int64]bool, len(events))
+	for _, e := range events {
+		if !seen[e.UserID] {
+			seen[e.UserID] = true
+			userIDs = append(userIDs, e.UserID)
+		}
+	}
+	subs, err := s.subRepo.FindByUserIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	// Users without a live subscription have nothing invoiced to be late for.
+	billedUntil := make(map[int64]time.Time, len(subs))
+	for _, sub := range subs {
+		billedUntil[sub.UserID] = sub.UsageBilledUntil
+	}
+
+	result := &domain.UsageRecording{Late: []string{}}
+	current := make([]domain.UsageEvent, 0, len(events))
+	for _, e := range events {
+		if e.OccurredAt.Before(billedUntil[e.UserID]) {
+			result.Late = append(result.Late, e.EventID)
+			continue
+		}
+		current = append(current, e)
+	}
+	if len(result.Late) > 0 {
+		log.Printf("Rejected %d usage events that occurred before the last usage invoice: %v", len(result.Late), result.Late)
+	}
+
+	recorded, err := s.usageRepo.Record(ctx, current)
+	if err != nil {
+		return nil, err
+	}
+	result.Recorded = recorded
+	result.Duplicates = len(current) - recorded
+	return result, nil
+}
+
+// GetCurrentUsage summarizes the user's usage that was not invoiced yet, with the overage
+// charges it would cause if the period ended now.
+func (s *billingService) GetCurrentUsage(ctx context.Context, userID int64) ([]domain.UsageSummary, error) {
+	sub, err := s.subRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("active subscription not found: %w", err)
+	}
+	plan, err := s.subscribedPlan(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	return s.meteredUsage(ctx, sub, plan, sub.UsageBilledUntil, time.Now())
+}
+
+// meteredUsage sums the usage of [from, to) per metric and prices the overage by the plan's
+// metered prices in the subscription's currency.
+func (s *billingService) meteredUsage(ctx context.Context, sub *domain.UserSubscription, plan *domain.SubscriptionPlan, from, to time.Time) ([]domain.UsageSummary, error) {
+	totals, err := s.usageRepo.SumByMetric(ctx, sub.UserID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]*domain.MeteredPrice, len(plan.MeteredPrices))
+	for i := range plan.MeteredPrices {
+		prices[plan.MeteredPrices[i].Metric] = &plan.MeteredPrices[i]
+		if _, ok := totals[plan.MeteredPrices[i].Metric]; !ok {
+			totals[plan.MeteredPrices[i].Metric] = 0
+		}
+	}
+
+	summaries := make([]domain.UsageSummary, 0, len(totals))
+	for metric, quantity := range totals {
+		summary := domain.UsageSummary{Metric: metric, Quantity: quantity}
+		if mp, ok := prices[metric]; ok {
+			summary.Included = mp.Included
+			summary.Overage = mp.Overage(quantity)
+			if unitPrice, ok := mp.UnitPriceIn(sub.Currency); ok {
+				charge := unitPrice.Prorate(int64(summary.Overage), int64(mp.UnitSize))
+				summary.Charge = &charge
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Metric < summaries[j].Metric })
+	return summaries, nil
+}
+
+// finalUsageInvoice builds the taxed invoice for the usage of the last period of a subscription
+// that ends, nil if there is nothing to bill.
+func (s *billingService) finalUsageInvoice(ctx context.Context, sub *domain.UserSubscription, usage []domain.InvoiceLine) (*domain.Invoice, error) {
+	if len(usage) == 0 {
+		return nil, nil
+	}
+	tax, err := s.taxTreatmentFor(ctx, sub.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := linesInvoice(sub, usage)
+	tax.applyToInvoice(invoice)
+	return invoice, nil
+}
+
+// closingUsageLines prices the metered usage the subscription has not been invoiced for, up to
+// `until`, on the plan version it was used on. Trials are free, including their usage.
+func (s *billingService) closingUsageLines(ctx context.Context, sub *domain.UserSubscription, until time.Time) ([]domain.InvoiceLine, error) {
+	if sub.Status == domain.SubscriptionStatusTrialing || !until.After(sub.UsageBilledUntil) {
+		return nil, nil
+	}
+	plan, err := s.subscribedPlan(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.MeteredPrices) == 0 {
+		return nil, nil
+	}
+
+	summaries, err := s.meteredUsage(ctx, sub, plan, sub.UsageBilledUntil, until)
+	if err != nil {
+		return nil, fmt.Errorf("could not aggregate usage of subscription %d: %w", sub.ID, err)
+	}
+
+	var lines []domain.InvoiceLine
+	for _, u := range summaries {
+		if u.Charge == nil || !u.Charge.IsPositive() {
+			continue
+		}
+		lines = append(lines, domain.InvoiceLine{
+			Kind: domain.InvoiceLineUsage,
+			Description: fmt.Sprintf("%s: %s used, %s included, %s – %s", usageMetricNames[u.Metric], u.Quantity, u.Included,
+				sub.UsageBilledUntil.Format(time.DateOnly), until.Format(time.DateOnly)),
+			Amount: *u.Charge,
+		})
+	}
+	return lines, nil
+}
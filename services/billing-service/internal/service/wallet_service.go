@@ -0,0 +1,170 @@
+// services/billing-service/internal/service/wallet_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/client"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// maxWalletTransactions caps how many balance changes are listed at once.
+const maxWalletTransactions = 100
+
+// WalletService manages prepaid balances. Invoices are paid from them in BillingService.
+type WalletService interface {
+	GetBalances(ctx context.Context, userID int64) ([]domain.Money, error)
+	GetTransactions(ctx context.Context, userID int64, limit int) ([]domain.WalletTransaction, error)
+	StartTopUp(ctx context.Context, userID int64, amount domain.Money) (*domain.WalletTopUp, error)
+	HandlePaymentWebhook(ctx context.Context, payload []byte, signature string) error
+	RefundToBalance(ctx context.Context, userID int64, amount domain.Money, description, reference string) (*domain.LedgerTransaction, error)
+}
+
+type walletService struct {
+	walletRepo      repository.WalletRepository
+	paymentProvider client.PaymentProvider
+}
+
+func NewWalletService(walletRepo repository.WalletRepository, paymentProvider client.PaymentProvider) WalletService {
+	return &walletService{
+		walletRepo:      walletRepo,
+		paymentProvider: paymentProvider,
+	}
+}
+
+// topUpReferencePrefix marks payment references that belong to wallet top-ups.
+const topUpReferencePrefix = "topup:"
+
+func (s *walletService) GetBalances(ctx context.Context, userID int64) ([]domain.Money, error) {
+	return s.walletRepo.Balances(ctx, userID)
+}
+
+func (s *walletService) GetTransactions(ctx context.Context, userID int64, limit int) ([]domain.WalletTransaction, error) {
+	if limit <= 0 || limit > maxWalletTransactions {
+		limit = maxWalletTransactions
+	}
+	return s.walletRepo.FindWalletTransactions(ctx, userID, limit)
+}
+
+// StartTopUp creates a pending top-up and the checkout the user pays it at. The balance is
+// only credited once the payment provider confirms the payment.
+func (s *walletService) StartTopUp(ctx context.Context, userID int64, amount domain.Money) (*domain.WalletTopUp, error) {
+	if !domain.IsSupportedCurrency(amount.Currency) {
+		return nil, fmt.Errorf("unsupported currency '%s': %w", amount.Currency, ierr.ErrInvalidInput)
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("top-up amount must be positive: %w", ierr.ErrInvalidInput)
+	}
+
+	topUp := &domain.WalletTopUp{UserID: userID, Amount: amount, Refunded: domain.NewMoney(0, amount.Currency), Status: domain.TopUpStatusPending}
+	if err := s.walletRepo.CreateTopUp(ctx, topUp); err != nil {
+		return nil, err
+	}
+
+	reference := topUpReferencePrefix + strconv.FormatInt(topUp.ID, 10)
+	checkout, err := s.paymentProvider.CreateCheckout(ctx, reference, amount.Amount, amount.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start checkout of top-up %d: %w", topUp.ID, err)
+	}
+	topUp.CheckoutURL = checkout.URL
+
+	log.Printf("User %d started top-up %d of %s.", userID, topUp.ID, amount)
+	return topUp, nil
+}
+
+// HandlePaymentWebhook applies a payment status update of the provider. Providers retry
+// webhooks, so an update for a top-up that is no longer pending is acknowledged and ignored.
+func (s *walletService) HandlePaymentWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, err := s.paymentProvider.ParseWebhook(payload, signature)
+	if err != nil {
+		if errors.Is(err, client.ErrInvalidSignature) {
+			return fmt.Errorf("%v: %w", err, ierr.ErrForbidden)
+		}
+		return fmt.Errorf("%v: %w", err, ierr.ErrInvalidInput)
+	}
+
+	idPart, ok := strings.CutPrefix(event.Reference, topUpReferencePrefix)
+	if !ok {
+		log.Printf("Ignoring payment event for unknown reference '%s'.", event.Reference)
+		return nil
+	}
+	topUpID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid top-up reference '%s': %w", event.Reference, ierr.ErrInvalidInput)
+	}
+	topUp, err := s.walletRepo.FindTopUp(ctx, topUpID)
+	if err != nil {
+		return fmt.Errorf("top-up %d: %w", topUpID, err)
+	}
+	if topUp.Status != domain.TopUpStatusPending {
+		return nil
+	}
+
+	switch event.Type {
+	case client.PaymentEventSucceeded:
+		if event.AmountMinor != topUp.Amount.Amount || event.Currency != topUp.Amount.Currency {
+			log.Printf("CRITICAL: Payment %s of top-up %d is %d %s, expected %s.",
+				event.PaymentID, topUp.ID, event.AmountMinor, event.Currency, topUp.Amount)
+			return fmt.Errorf("payment amount does not match top-up %d: %w", topUp.ID, ierr.ErrConflict)
+		}
+		credit := domain.NewTransfer(topUp.UserID, domain.LedgerTransactionTopUp, "Balance top-up", event.Reference,
+			domain.LedgerAccountPaymentProvider, domain.LedgerAccountWallet, topUp.Amount)
+		completed, settled, err := s.walletRepo.CompleteTopUp(ctx, topUp.ID, event.PaymentID, credit)
+		if err != nil {
+			return fmt.Errorf("failed to complete top-up %d: %w", topUp.ID, err)
+		}
+		if completed {
+			log.Printf("Top-up %d credited %s to user %d.", topUp.ID, topUp.Amount, topUp.UserID)
+		}
+		if len(settled) > 0 {
+			log.Printf("Top-up %d settled invoices %v of user %d.", topUp.ID, settled, topUp.UserID)
+		}
+	case client.PaymentEventFailed:
+		if err := s.walletRepo.FailTopUp(ctx, topUp.ID, event.PaymentID); err != nil {
+			return err
+		}
+		log.Printf("Top-up %d of user %d failed.", topUp.ID, topUp.UserID)
+	default:
+		log.Printf("Ignoring payment event of type '%s' for top-up %d.", event.Type, topUp.ID)
+	}
+	return nil
+}
+
+// RefundToBalance credits a refund to the user's balance instead of paying it out. The
+// reference makes the refund idempotent: booking the same reference again is a conflict.
+func (s *walletService) RefundToBalance(ctx context.Context, userID int64, amount domain.Money, description, reference string) (*domain.LedgerTransaction, error) {
+	if !domain.IsSupportedCurrency(amount.Currency) {
+		return nil, fmt.Errorf("unsupported currency '%s': %w", amount.Currency, ierr.ErrInvalidInput)
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("refund amount must be positive: %w", ierr.ErrInvalidInput)
+	}
+	if reference == "" {
+		return nil, fmt.Errorf("refund reference is required: %w", ierr.ErrInvalidInput)
+	}
+	description = strings.TrimSpace(description)
+	if description == "" {
+		description = "Refund"
+	}
+
+	refund := domain.NewTransfer(userID, domain.LedgerTransactionRefund, description, "refund:"+reference,
+		domain.LedgerAccountRefunds, domain.LedgerAccountWallet, amount)
+	if err := refund.Validate(); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ierr.ErrInvalidInput)
+	}
+	if err := s.walletRepo.Post(ctx, refund); err != nil {
+		if errors.Is(err, ierr.ErrConflict) {
+			return nil, fmt.Errorf("refund '%s' was already booked: %w", reference, err)
+		}
+		return nil, err
+	}
+
+	log.Printf("Refunded %s to the balance of user %d (%s).", amount, userID, reference)
+	return refund, nil
+}
@@ -0,0 +1,272 @@
+// services/billing-service/internal/service/webhook_service.go
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"jcloud-project/billing-service/internal/client"
+	"jcloud-project/billing-service/internal/domain"
+	"jcloud-project/billing-service/internal/repository"
+	"jcloud-project/libs/go-common/ierr"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxListedWebhookDeliveries caps how many deliveries an admin listing returns.
+	maxListedWebhookDeliveries = 200
+	// webhookSecretPrefix marks webhook secrets, so that they are recognizable in config files.
+	webhookSecretPrefix = "whsec_"
+)
+
+// WebhookService manages webhook endpoints and delivers the events the repositories record in
+// the same transaction as the changes they describe.
+type WebhookService interface {
+	GetEndpoints(ctx context.Context) ([]domain.WebhookEndpoint, error)
+	// CreateEndpoint registers an endpoint with a new secret, which is only returned here and
+	// by RotateSecret.
+	CreateEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) (*domain.WebhookEndpoint, error)
+	PatchEndpoint(ctx context.Context, id int64, patch WebhookEndpointPatch) (*domain.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, id int64) error
+	// RotateSecret replaces the secret of an endpoint. Deliveries from then on, including
+	// retries of earlier events, are signed with the new one.
+	RotateSecret(ctx context.Context, id int64) (*domain.WebhookEndpoint, error)
+	GetDeliveries(ctx context.Context, endpointID int64, status string, limit int) ([]domain.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error)
+	// Redeliver sends a delivery again with a fresh set of attempts, whatever its status.
+	Redeliver(ctx context.Context, id int64) (*domain.WebhookDelivery, error)
+	// DeliverDue sends all due deliveries. Failures are retried with exponential backoff and
+	// the delivery is dead-lettered once it has failed maxAttempts times.
+	DeliverDue(ctx context.Context) error
+}
+
+// WebhookEndpointPatch holds the endpoint fields an admin may change, nil fields are left untouched.
+type WebhookEndpointPatch struct {
+	URL         *string
+	Description *string
+	EventTypes  *[]string
+	IsActive    *bool
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	sender      client.WebhookSender
+	outbox      outboxDelivery
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository, sender client.WebhookSender, maxAttempts int, retryDelay time.Duration) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		sender:      sender,
+		outbox:      outboxDelivery{maxAttempts: maxAttempts, retryDelay: retryDelay},
+	}
+}
+
+func (s *webhookService) GetEndpoints(ctx context.Context) ([]domain.WebhookEndpoint, error) {
+	return s.webhookRepo.FindAllEndpoints(ctx)
+}
+
+func (s *webhookService) CreateEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) (*domain.WebhookEndpoint, error) {
+	endpoint.IsActive = true
+	if err := validateWebhookEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Secret = secret
+
+	if err := s.webhookRepo.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	log.Printf("Webhook endpoint %d registered for %s.", endpoint.ID, endpoint.URL)
+	return endpoint, nil
+}
+
+func (s *webhookService) PatchEndpoint(ctx context.Context, id int64, patch WebhookEndpointPatch) (*domain.WebhookEndpoint, error) {
+	endpoint, err := s.webhookRepo.FindEndpointByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.URL != nil {
+		endpoint.URL = *patch.URL
+	}
+	if patch.Description != nil {
+		endpoint.Description = *patch.Description
+	}
+	if patch.EventTypes != nil {
+		endpoint.EventTypes = *patch.EventTypes
+	}
+	if patch.IsActive != nil {
+		endpoint.IsActive = *patch.IsActive
+	}
+	if err := validateWebhookEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+
+	if err := s.webhookRepo.UpdateEndpoint(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// DeleteEndpoint removes the endpoint with its delivery log. Deactivate it instead to keep the log.
+func (s *webhookService) DeleteEndpoint(ctx context.Context, id int64) error {
+	if err := s.webhookRepo.DeleteEndpoint(ctx, id); err != nil {
+		return err
+	}
+	log.Printf("Webhook endpoint %d was deleted.", id)
+	return nil
+}
+
+func (s *webhookService) RotateSecret(ctx context.Context, id int64) (*domain.WebhookEndpoint, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.webhookRepo.SetEndpointSecret(ctx, id, secret); err != nil {
+		return nil, err
+	}
+	endpoint, err := s.webhookRepo.FindEndpointByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Secret = secret
+	log.Printf("Secret of webhook endpoint %d was rotated.", id)
+	return endpoint, nil
+}
+
+func (s *webhookService) GetDeliveries(ctx context.Context, endpointID int64, status string, limit int) ([]domain.WebhookDelivery, error) {
+	switch status {
+	case "", domain.WebhookDeliveryPending, domain.WebhookDeliveryDelivered, domain.WebhookDeliveryDead:
+	default:
+		return nil, fmt.Errorf("unknown delivery status '%s': %w", status, ierr.ErrInvalidInput)
+	}
+	if _, err := s.webhookRepo.FindEndpointByID(ctx, endpointID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxListedWebhookDeliveries {
+		limit = maxListedWebhookDeliveries
+	}
+	return s.webhookRepo.FindDeliveries(ctx, endpointID, status, limit)
+}
+
+func (s *webhookService) GetDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	return s.webhookRepo.FindDeliveryByID(ctx, id)
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	if err := s.webhookRepo.Redeliver(ctx, id); err != nil {
+		if errors.Is(err, ierr.ErrNotFound) {
+			return nil, fmt.Errorf("webhook delivery %d: %w", id, err)
+		}
+		return nil, err
+	}
+	delivery, err := s.webhookRepo.FindDeliveryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Webhook delivery %d of event %d was scheduled for redelivery.", delivery.ID, delivery.EventID)
+	return delivery, nil
+}
+
+func (s *webhookService) DeliverDue(ctx context.Context) error {
+	return deliverDue(ctx, s.webhookRepo.ClaimDeliveries, s.deliver)
+}
+
+// webhookBody is what endpoints receive. It only depends on the stored event, so every
+// attempt sends the same bytes.
+type webhookBody struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func webhookEventID(eventID int64) string {
+	return fmt.Sprintf("evt_%d", eventID)
+}
+
+func (s *webhookService) deliver(ctx context.Context, delivery *domain.WebhookDelivery) {
+	event := delivery.Event
+	body, err := json.Marshal(webhookBody{
+		ID:        webhookEventID(event.ID),
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt.UTC(),
+		Data:      event.Data,
+	})
+	if err != nil {
+		log.Printf("Failed to encode event %d of webhook delivery %d: %v", event.ID, delivery.ID, err)
+		return
+	}
+
+	started := time.Now()
+	resp, err := s.sender.Send(ctx, delivery.URL, delivery.Secret, webhookEventID(event.ID), event.Type, body)
+	attempt := &domain.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		DurationMs: int(time.Since(started).Milliseconds()),
+	}
+	if resp != nil {
+		attempt.ResponseStatus = &resp.Status
+		attempt.ResponseBody = &resp.Body
+		if resp.Status < 200 || resp.Status > 299 {
+			err = fmt.Errorf("endpoint responded with status %d", resp.Status)
+		}
+	}
+
+	if err == nil {
+		if err := s.webhookRepo.MarkDelivered(ctx, attempt); err != nil {
+			log.Printf("Failed to mark webhook delivery %d as delivered: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	message := err.Error()
+	attempt.Error = &message
+	what := fmt.Sprintf("Webhook delivery %d of %s event %d to %s", delivery.ID, event.Type, event.ID, delivery.URL)
+	s.outbox.recordFailure(what, delivery.Attempts+1, err, func(next time.Time, dead bool) error {
+		return s.webhookRepo.MarkFailed(ctx, attempt, next, dead)
+	})
+}
+
+func validateWebhookEndpoint(e *domain.WebhookEndpoint) error {
+	e.URL = strings.TrimSpace(e.URL)
+	e.Description = strings.TrimSpace(e.Description)
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL: %w", ierr.ErrInvalidInput)
+	}
+
+	if len(e.EventTypes) == 0 {
+		return fmt.Errorf("at least one event type is required: %w", ierr.ErrInvalidInput)
+	}
+	seen := make(map[string]bool, len(e.EventTypes))
+	eventTypes := make([]string, 0, len(e.EventTypes))
+	for _, t := range e.EventTypes {
+		if !domain.IsWebhookEventType(t) {
+			return fmt.Errorf("unknown event type '%s': %w", t, ierr.ErrInvalidInput)
+		}
+		if !seen[t] {
+			seen[t] = true
+			eventTypes = append(eventTypes, t)
+		}
+	}
+	e.EventTypes = eventTypes
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,37 @@
+// services/billing-service/internal/worker/nextcloud_sync_worker.go
+package worker
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/service"
+	"log"
+	"time"
+)
+
+// NextcloudSyncWorker periodically delivers due syncs from the Nextcloud outbox.
+type NextcloudSyncWorker struct {
+	service  service.NextcloudSyncService
+	interval time.Duration
+}
+
+func NewNextcloudSyncWorker(s service.NextcloudSyncService, interval time.Duration) *NextcloudSyncWorker {
+	return &NextcloudSyncWorker{service: s, interval: interval}
+}
+
+// Run blocks until ctx is canceled, delivering due syncs once per interval.
+func (w *NextcloudSyncWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.service.DeliverDue(ctx); err != nil {
+			log.Printf("Nextcloud sync worker failed to deliver due syncs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
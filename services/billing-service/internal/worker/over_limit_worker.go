@@ -0,0 +1,38 @@
+// services/billing-service/internal/worker/over_limit_worker.go
+package worker
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/service"
+	"log"
+	"time"
+)
+
+// OverLimitWorker periodically reminds over-limit users before their grace period ends and
+// expires the grace periods that have ended.
+type OverLimitWorker struct {
+	service  service.OverLimitService
+	interval time.Duration
+}
+
+func NewOverLimitWorker(s service.OverLimitService, interval time.Duration) *OverLimitWorker {
+	return &OverLimitWorker{service: s, interval: interval}
+}
+
+// Run blocks until ctx is canceled, processing grace periods once per interval.
+func (w *OverLimitWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.service.ProcessGracePeriods(ctx); err != nil {
+			log.Printf("Over-limit worker failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// services/billing-service/internal/worker/quota_reconciliation_worker.go
+package worker
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/service"
+	"log"
+	"time"
+)
+
+// QuotaReconciliationWorker periodically compares Nextcloud quotas with what users are
+// entitled to.
+type QuotaReconciliationWorker struct {
+	service  service.QuotaReconciliationService
+	interval time.Duration
+	dryRun   bool
+}
+
+func NewQuotaReconciliationWorker(s service.QuotaReconciliationService, interval time.Duration, dryRun bool) *QuotaReconciliationWorker {
+	return &QuotaReconciliationWorker{service: s, interval: interval, dryRun: dryRun}
+}
+
+// Run blocks until ctx is canceled, reconciling once per interval. Unlike the other workers it
+// waits a full interval before the first run, so restarts do not trigger a full scan.
+func (w *QuotaReconciliationWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := w.service.Reconcile(ctx, w.dryRun); err != nil {
+			log.Printf("Quota reconciliation worker failed: %v", err)
+		}
+	}
+}
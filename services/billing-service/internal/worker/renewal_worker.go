@@ -0,0 +1,37 @@
+// services/billing-service/internal/worker/renewal_worker.go
+package worker
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/service"
+	"log"
+	"time"
+)
+
+// RenewalWorker periodically moves subscriptions whose billing period has ended into the next period.
+type RenewalWorker struct {
+	service  service.BillingService
+	interval time.Duration
+}
+
+func NewRenewalWorker(s service.BillingService, interval time.Duration) *RenewalWorker {
+	return &RenewalWorker{service: s, interval: interval}
+}
+
+// Run blocks until ctx is canceled, processing due subscriptions once per interval.
+func (w *RenewalWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.service.ProcessDueSubscriptions(ctx); err != nil {
+			log.Printf("Renewal worker failed to process due subscriptions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// services/billing-service/internal/worker/storage_usage_worker.go
+package worker
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/service"
+	"log"
+	"time"
+)
+
+// StorageUsageWorker periodically reads every subscriber's storage usage from Nextcloud, so
+// that quota warnings are sent even to users who do not look at their usage.
+type StorageUsageWorker struct {
+	service  service.StorageUsageService
+	interval time.Duration
+}
+
+func NewStorageUsageWorker(s service.StorageUsageService, interval time.Duration) *StorageUsageWorker {
+	return &StorageUsageWorker{service: s, interval: interval}
+}
+
+// Run blocks until ctx is canceled, refreshing once per interval. Like the reconciliation it
+// waits a full interval before the first run.
+func (w *StorageUsageWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := w.service.RefreshAll(ctx); err != nil {
+			log.Printf("Storage usage worker failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// services/billing-service/internal/worker/subscription_repair_worker.go
+package worker
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/service"
+	"log"
+	"time"
+)
+
+// SubscriptionRepairWorker periodically puts users who ended up without a subscription, e.g.
+// because billing-service was down when they registered, on the default plan.
+type SubscriptionRepairWorker struct {
+	service  service.BillingService
+	interval time.Duration
+}
+
+func NewSubscriptionRepairWorker(s service.BillingService, interval time.Duration) *SubscriptionRepairWorker {
+	return &SubscriptionRepairWorker{service: s, interval: interval}
+}
+
+// Run blocks until ctx is canceled, repairing once per interval. Like the reconciliation it
+// waits a full interval before the first run.
+func (w *SubscriptionRepairWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := w.service.RepairMissingSubscriptions(ctx); err != nil {
+			log.Printf("Subscription repair worker failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// services/billing-service/internal/worker/webhook_worker.go
+package worker
+
+import (
+	"context"
+	"jcloud-project/billing-service/internal/service"
+	"log"
+	"time"
+)
+
+// WebhookWorker periodically sends due webhook deliveries.
+type WebhookWorker struct {
+	service  service.WebhookService
+	interval time.Duration
+}
+
+func NewWebhookWorker(s service.WebhookService, interval time.Duration) *WebhookWorker {
+	return &WebhookWorker{service: s, interval: interval}
+}
+
+// Run blocks until ctx is canceled, sending due deliveries once per interval.
+func (w *WebhookWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.service.DeliverDue(ctx); err != nil {
+			log.Printf("Webhook worker failed to send due deliveries: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,26 @@
+-- services/billing-service/migrations/001_subscription_proration.sql
+-- Scheduled (end-of-period) plan changes and invoices for prorated charges.
+
+ALTER TABLE user_subscriptions
+    ADD COLUMN IF NOT EXISTS pending_plan_id BIGINT REFERENCES subscription_plans (id);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT        NOT NULL,
+    subscription_id BIGINT        NOT NULL REFERENCES user_subscriptions (id),
+    status          VARCHAR(20)   NOT NULL DEFAULT 'OPEN',
+    total           NUMERIC(12, 2) NOT NULL,
+    created_at      TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_user_id ON invoices (user_id);
+
+CREATE TABLE IF NOT EXISTS invoice_lines (
+    id          BIGSERIAL PRIMARY KEY,
+    invoice_id  BIGINT         NOT NULL REFERENCES invoices (id) ON DELETE CASCADE,
+    kind        VARCHAR(32)    NOT NULL,
+    description TEXT           NOT NULL,
+    amount      NUMERIC(12, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_subscriptions_renewal ON user_subscriptions (ends_at) WHERE status = 'ACTIVE';
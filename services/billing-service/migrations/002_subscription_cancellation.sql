@@ -0,0 +1,8 @@
+-- services/billing-service/migrations/002_subscription_cancellation.sql
+-- Cancel-at-period-end flag and the optional cancellation survey.
+
+ALTER TABLE user_subscriptions
+    ADD COLUMN IF NOT EXISTS cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS canceled_at          TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS cancel_reason        VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS cancel_feedback      TEXT;
@@ -0,0 +1,22 @@
+-- services/billing-service/migrations/003_trials.sql
+-- Free trials: plan configuration, trial state on subscriptions and one trial per user/email.
+
+ALTER TABLE subscription_plans
+    ADD COLUMN IF NOT EXISTS trial_days INTEGER NOT NULL DEFAULT 0 CHECK (trial_days >= 0);
+
+ALTER TABLE user_subscriptions
+    ADD COLUMN IF NOT EXISTS trial_ends_at          TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS trial_reminder_sent_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS trial_redemptions (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT       NOT NULL UNIQUE,
+    email      VARCHAR(255) NOT NULL UNIQUE, -- normalized, see service.normalizeEmail
+    plan_id    BIGINT       NOT NULL REFERENCES subscription_plans (id),
+    started_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+UPDATE subscription_plans SET trial_days = 14 WHERE name = 'Pro';
+
+DROP INDEX IF EXISTS idx_user_subscriptions_renewal;
+CREATE INDEX IF NOT EXISTS idx_user_subscriptions_renewal ON user_subscriptions (ends_at) WHERE status IN ('ACTIVE', 'TRIALING');
@@ -0,0 +1,36 @@
+-- services/billing-service/migrations/004_coupons.sql
+-- Discount coupons and their redemptions.
+
+CREATE TABLE IF NOT EXISTS coupons (
+    id              BIGSERIAL PRIMARY KEY,
+    code            VARCHAR(64)    NOT NULL UNIQUE,
+    discount_type   VARCHAR(16)    NOT NULL CHECK (discount_type IN ('PERCENT', 'FIXED')),
+    percent_off     NUMERIC(5, 2)  NOT NULL DEFAULT 0,
+    amount_off      NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    duration        VARCHAR(16)    NOT NULL CHECK (duration IN ('ONCE', 'REPEATING', 'FOREVER')),
+    duration_months INTEGER,
+    max_redemptions INTEGER,
+    times_redeemed  INTEGER        NOT NULL DEFAULT 0,
+    expires_at      TIMESTAMPTZ,
+    plan_ids        BIGINT[]       NOT NULL DEFAULT '{}',
+    is_active       BOOLEAN        NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id                BIGSERIAL PRIMARY KEY,
+    coupon_id         BIGINT      NOT NULL REFERENCES coupons (id),
+    user_id           BIGINT      NOT NULL,
+    subscription_id   BIGINT      NOT NULL REFERENCES user_subscriptions (id),
+    periods_remaining INTEGER, -- NULL means the discount applies forever
+    is_active         BOOLEAN     NOT NULL DEFAULT TRUE,
+    redeemed_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (coupon_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupon_redemptions_active
+    ON coupon_redemptions (subscription_id) WHERE is_active;
+
+ALTER TABLE invoice_lines
+    ADD COLUMN IF NOT EXISTS coupon_id BIGINT REFERENCES coupons (id);
@@ -0,0 +1,12 @@
+-- services/billing-service/migrations/005_plan_admin.sql
+-- Ordering and uniqueness for admin-managed plans.
+
+ALTER TABLE subscription_plans
+    ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0;
+
+UPDATE subscription_plans p
+SET sort_order = o.position
+FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY price, id) - 1 AS position FROM subscription_plans) o
+WHERE p.id = o.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_plans_name ON subscription_plans (name);
@@ -0,0 +1,42 @@
+-- services/billing-service/migrations/006_plan_versions.sql
+-- Immutable plan versions: subscriptions are pinned to the price and permissions they signed up for.
+
+CREATE TABLE IF NOT EXISTS plan_versions (
+    id          BIGSERIAL PRIMARY KEY,
+    plan_id     BIGINT         NOT NULL REFERENCES subscription_plans (id),
+    version     INTEGER        NOT NULL,
+    price       NUMERIC(12, 2) NOT NULL,
+    permissions JSONB          NOT NULL,
+    created_at  TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    UNIQUE (plan_id, version)
+);
+
+INSERT INTO plan_versions (plan_id, version, price, permissions)
+SELECT id, 1, price, permissions FROM subscription_plans
+WHERE NOT EXISTS (SELECT 1 FROM plan_versions v WHERE v.plan_id = subscription_plans.id);
+
+ALTER TABLE subscription_plans
+    ADD COLUMN IF NOT EXISTS current_version_id BIGINT REFERENCES plan_versions (id);
+
+UPDATE subscription_plans p SET current_version_id = v.id
+FROM plan_versions v WHERE v.plan_id = p.id AND v.version = 1 AND p.current_version_id IS NULL;
+
+ALTER TABLE user_subscriptions
+    ADD COLUMN IF NOT EXISTS plan_version_id BIGINT REFERENCES plan_versions (id);
+
+UPDATE user_subscriptions s SET plan_version_id = p.current_version_id
+FROM subscription_plans p WHERE p.id = s.plan_id AND s.plan_version_id IS NULL;
+
+ALTER TABLE user_subscriptions ALTER COLUMN plan_version_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_subscriptions_plan_version ON user_subscriptions (plan_version_id);
+
+CREATE TABLE IF NOT EXISTS plan_version_migrations (
+    id              BIGSERIAL PRIMARY KEY,
+    plan_id         BIGINT      NOT NULL REFERENCES subscription_plans (id),
+    from_version_id BIGINT      NOT NULL REFERENCES plan_versions (id),
+    to_version_id   BIGINT      NOT NULL REFERENCES plan_versions (id),
+    effective_at    TIMESTAMPTZ NOT NULL,
+    completed_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,40 @@
+-- services/billing-service/migrations/007_money.sql
+-- Exact money: amounts in integer minor units with an ISO 4217 currency, per-currency plan prices.
+-- Existing amounts were all in USD.
+
+CREATE TABLE IF NOT EXISTS plan_prices (
+    plan_version_id BIGINT     NOT NULL REFERENCES plan_versions (id),
+    currency        VARCHAR(3) NOT NULL,
+    amount_minor    BIGINT     NOT NULL CHECK (amount_minor >= 0),
+    PRIMARY KEY (plan_version_id, currency)
+);
+
+INSERT INTO plan_prices (plan_version_id, currency, amount_minor)
+SELECT id, 'USD', ROUND(price * 100)::BIGINT FROM plan_versions
+WHERE price > 0
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE plan_versions DROP COLUMN IF EXISTS price;
+ALTER TABLE subscription_plans DROP COLUMN IF EXISTS price;
+
+ALTER TABLE user_subscriptions
+    ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE invoices
+    ADD COLUMN IF NOT EXISTS total_minor BIGINT,
+    ADD COLUMN IF NOT EXISTS currency    VARCHAR(3) NOT NULL DEFAULT 'USD';
+UPDATE invoices SET total_minor = ROUND(total * 100)::BIGINT WHERE total_minor IS NULL;
+ALTER TABLE invoices ALTER COLUMN total_minor SET NOT NULL;
+ALTER TABLE invoices DROP COLUMN IF EXISTS total;
+
+ALTER TABLE invoice_lines ADD COLUMN IF NOT EXISTS amount_minor BIGINT;
+UPDATE invoice_lines SET amount_minor = ROUND(amount * 100)::BIGINT WHERE amount_minor IS NULL;
+ALTER TABLE invoice_lines ALTER COLUMN amount_minor SET NOT NULL;
+ALTER TABLE invoice_lines DROP COLUMN IF EXISTS amount;
+
+ALTER TABLE coupons
+    ADD COLUMN IF NOT EXISTS amount_off_minor    BIGINT,
+    ADD COLUMN IF NOT EXISTS amount_off_currency VARCHAR(3);
+UPDATE coupons SET amount_off_minor = ROUND(amount_off * 100)::BIGINT, amount_off_currency = 'USD'
+WHERE discount_type = 'FIXED' AND amount_off_minor IS NULL;
+ALTER TABLE coupons DROP COLUMN IF EXISTS amount_off;
@@ -0,0 +1,46 @@
+-- services/billing-service/migrations/008_tax.sql
+-- Tax engine: rates by country/region, customer billing profiles and the tax applied to invoices.
+
+CREATE TABLE IF NOT EXISTS tax_rates (
+    id         BIGSERIAL PRIMARY KEY,
+    country    VARCHAR(2)  NOT NULL,
+    region     VARCHAR(3)  NOT NULL DEFAULT '', -- '' is the country-wide rate
+    name       VARCHAR(64) NOT NULL,
+    rate_bps   BIGINT      NOT NULL CHECK (rate_bps BETWEEN 0 AND 10000),
+    inclusive  BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (country, region)
+);
+
+INSERT INTO tax_rates (country, name, rate_bps, inclusive) VALUES
+    ('DE', 'VAT', 1900, TRUE),
+    ('FR', 'VAT', 2000, TRUE),
+    ('NL', 'VAT', 2100, TRUE),
+    ('PL', 'VAT', 2300, TRUE),
+    ('GB', 'VAT', 2000, TRUE)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS billing_profiles (
+    user_id      BIGINT PRIMARY KEY,
+    country      VARCHAR(2)   NOT NULL,
+    region       VARCHAR(3)   NOT NULL DEFAULT '',
+    is_business  BOOLEAN      NOT NULL DEFAULT FALSE,
+    company_name VARCHAR(255),
+    tax_id       VARCHAR(32),
+    updated_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE invoices
+    ADD COLUMN IF NOT EXISTS subtotal_minor     BIGINT,
+    ADD COLUMN IF NOT EXISTS tax_name           VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS tax_country        VARCHAR(2),
+    ADD COLUMN IF NOT EXISTS tax_region         VARCHAR(3),
+    ADD COLUMN IF NOT EXISTS tax_rate_bps       BIGINT,
+    ADD COLUMN IF NOT EXISTS tax_inclusive      BOOLEAN,
+    ADD COLUMN IF NOT EXISTS tax_reverse_charge BOOLEAN,
+    ADD COLUMN IF NOT EXISTS customer_tax_id    VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS tax_taxable_minor  BIGINT,
+    ADD COLUMN IF NOT EXISTS tax_minor          BIGINT;
+UPDATE invoices SET subtotal_minor = total_minor WHERE subtotal_minor IS NULL;
+ALTER TABLE invoices ALTER COLUMN subtotal_minor SET NOT NULL;
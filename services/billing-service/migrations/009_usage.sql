@@ -0,0 +1,18 @@
+-- services/billing-service/migrations/009_usage.sql
+-- Usage metering: raw usage events and metered overage prices on plan versions.
+
+CREATE TABLE IF NOT EXISTS usage_events (
+    id          BIGSERIAL PRIMARY KEY,
+    event_id    VARCHAR(128) NOT NULL UNIQUE, -- Producer's idempotency key
+    user_id     BIGINT       NOT NULL,
+    metric      VARCHAR(32)  NOT NULL,
+    quantity    BIGINT       NOT NULL CHECK (quantity >= 0), -- Thousandths of the metric's unit
+    occurred_at TIMESTAMPTZ  NOT NULL,
+    received_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_events_user_period ON usage_events (user_id, occurred_at);
+
+-- [{"metric": "...", "included": "100", "unit_size": "1", "unit_prices": [{"amount": "0.05", "currency": "USD"}]}]
+ALTER TABLE plan_versions
+    ADD COLUMN IF NOT EXISTS metered_prices JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,24 @@
+-- services/billing-service/migrations/010_add_ons.sql
+-- Add-ons: products bought on top of a plan that add to its numeric permissions.
+
+CREATE TABLE IF NOT EXISTS add_ons (
+    id                BIGSERIAL PRIMARY KEY,
+    name              VARCHAR(100) NOT NULL UNIQUE,
+    description       TEXT         NOT NULL DEFAULT '',
+    prices            JSONB        NOT NULL DEFAULT '[]', -- [{"amount": "4.99", "currency": "USD"}], per unit and month
+    permission_deltas JSONB        NOT NULL,              -- {"storage_quota_gb": 100}, per unit
+    is_active         BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at        TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+-- Add-ons belong to the user rather than to a subscription row, so they carry over plan changes.
+CREATE TABLE IF NOT EXISTS user_add_ons (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    add_on_id  BIGINT      NOT NULL REFERENCES add_ons (id),
+    quantity   INTEGER     NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, add_on_id)
+);
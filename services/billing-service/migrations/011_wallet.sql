@@ -0,0 +1,68 @@
+-- services/billing-service/migrations/011_wallet.sql
+-- Prepaid balances: an append-only double-entry ledger, top-ups and invoices paid from balance.
+
+CREATE TABLE IF NOT EXISTS ledger_transactions (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT       NOT NULL,
+    kind        VARCHAR(32)  NOT NULL,
+    description TEXT         NOT NULL,
+    reference   VARCHAR(128) NOT NULL UNIQUE, -- What caused the transaction, booked at most once
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id             BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT      NOT NULL REFERENCES ledger_transactions (id),
+    user_id        BIGINT      NOT NULL, -- Copied from the transaction for balance lookups
+    account        VARCHAR(32) NOT NULL,
+    amount_minor   BIGINT      NOT NULL CHECK (amount_minor <> 0), -- Positive credits, negative debits
+    currency       VARCHAR(3)  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_balance ON ledger_entries (user_id, account, currency);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction ON ledger_entries (transaction_id);
+
+-- Ledger rows are never changed; mistakes are corrected with a new transaction.
+CREATE OR REPLACE FUNCTION forbid_ledger_changes() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_transactions_immutable ON ledger_transactions;
+CREATE TRIGGER ledger_transactions_immutable BEFORE UPDATE OR DELETE ON ledger_transactions
+    FOR EACH ROW EXECUTE FUNCTION forbid_ledger_changes();
+
+DROP TRIGGER IF EXISTS ledger_entries_immutable ON ledger_entries;
+CREATE TRIGGER ledger_entries_immutable BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION forbid_ledger_changes();
+
+-- Every transaction must balance, checked when the inserting database transaction commits.
+CREATE OR REPLACE FUNCTION check_ledger_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM ledger_entries WHERE transaction_id = NEW.transaction_id
+               GROUP BY currency HAVING SUM(amount_minor) <> 0) THEN
+        RAISE EXCEPTION 'ledger transaction % does not balance', NEW.transaction_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_balanced ON ledger_entries;
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced AFTER INSERT ON ledger_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_ledger_balanced();
+
+CREATE TABLE IF NOT EXISTS wallet_top_ups (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             BIGINT      NOT NULL,
+    amount_minor        BIGINT      NOT NULL CHECK (amount_minor > 0),
+    currency            VARCHAR(3)  NOT NULL,
+    status              VARCHAR(16) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'SUCCEEDED', 'FAILED')),
+    provider_payment_id VARCHAR(128),
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at        TIMESTAMPTZ
+);
+
+ALTER TABLE invoices
+    ADD COLUMN IF NOT EXISTS paid_from_balance_minor BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,24 @@
+-- services/billing-service/migrations/012_credit_notes.sql
+-- Refunds: credit notes against paid invoices, refunded to the balance or through the provider.
+
+CREATE TABLE IF NOT EXISTS credit_notes (
+    id                    BIGSERIAL PRIMARY KEY,
+    invoice_id            BIGINT      NOT NULL REFERENCES invoices (id),
+    user_id               BIGINT      NOT NULL,
+    amount_minor          BIGINT      NOT NULL CHECK (amount_minor > 0),
+    tax_minor             BIGINT      NOT NULL DEFAULT 0,
+    currency              VARCHAR(3)  NOT NULL,
+    reason                TEXT        NOT NULL,
+    refund_to             VARCHAR(16) NOT NULL CHECK (refund_to IN ('WALLET', 'PROVIDER')),
+    top_up_id             BIGINT REFERENCES wallet_top_ups (id),
+    provider_refund_id    VARCHAR(128),
+    ledger_transaction_id BIGINT      NOT NULL REFERENCES ledger_transactions (id),
+    issued_by             BIGINT      NOT NULL,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_notes_invoice ON credit_notes (invoice_id);
+
+-- How much of a top-up was already paid back through the provider.
+ALTER TABLE wallet_top_ups
+    ADD COLUMN IF NOT EXISTS refunded_minor BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,35 @@
+-- services/billing-service/migrations/013_subscription_history.sql
+-- Append-only history of every subscription change: the state after it, why and by whom.
+
+CREATE TABLE IF NOT EXISTS subscription_history (
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT      NOT NULL REFERENCES user_subscriptions (id),
+    user_id         BIGINT      NOT NULL,
+    event           VARCHAR(32) NOT NULL,
+    plan_id         BIGINT      NOT NULL REFERENCES subscription_plans (id),
+    plan_version_id BIGINT      NOT NULL REFERENCES plan_versions (id),
+    status          VARCHAR(50) NOT NULL,
+    starts_at       TIMESTAMPTZ NOT NULL,
+    ends_at         TIMESTAMPTZ NOT NULL,
+    currency        VARCHAR(3)  NOT NULL,
+    pending_plan_id BIGINT REFERENCES subscription_plans (id),
+    reason          TEXT,
+    actor_type      VARCHAR(16) NOT NULL CHECK (actor_type IN ('USER', 'ADMIN', 'SYSTEM')),
+    actor_id        BIGINT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_history_user ON subscription_history (user_id, created_at);
+
+-- Same append-only guard as the ledger (011_wallet.sql).
+DROP TRIGGER IF EXISTS subscription_history_immutable ON subscription_history;
+CREATE TRIGGER subscription_history_immutable BEFORE UPDATE OR DELETE ON subscription_history
+    FOR EACH ROW EXECUTE FUNCTION forbid_ledger_changes();
+
+-- Earlier changes were not kept; start every existing subscription's history with its current state.
+INSERT INTO subscription_history (subscription_id, user_id, event, plan_id, plan_version_id, status, starts_at, ends_at,
+                                  currency, pending_plan_id, reason, actor_type, created_at)
+SELECT s.id, s.user_id, 'CREATED', s.plan_id, s.plan_version_id, s.status, s.starts_at, s.ends_at,
+       s.currency, s.pending_plan_id, 'Recorded when the subscription history was introduced', 'SYSTEM', s.starts_at
+FROM user_subscriptions s
+WHERE NOT EXISTS (SELECT 1 FROM subscription_history h WHERE h.subscription_id = s.id);
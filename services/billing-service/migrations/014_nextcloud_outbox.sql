@@ -0,0 +1,22 @@
+-- services/billing-service/migrations/014_nextcloud_outbox.sql
+-- Outbox of Nextcloud syncs, written in the same transaction as the change that requires them.
+
+CREATE TABLE IF NOT EXISTS nextcloud_outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT      NOT NULL,
+    kind            VARCHAR(32) NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'DELIVERED', 'DEAD')),
+    attempts        INT         NOT NULL DEFAULT 0,
+    generation      BIGINT      NOT NULL DEFAULT 1,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ
+);
+
+-- Requests for a user who already has a pending sync are merged into it.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_nextcloud_outbox_pending_user
+    ON nextcloud_outbox (user_id, kind) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_nextcloud_outbox_due ON nextcloud_outbox (next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_nextcloud_outbox_status ON nextcloud_outbox (status, updated_at);
@@ -0,0 +1,19 @@
+-- services/billing-service/migrations/015_quota_reconciliations.sql
+-- Reports of the periodic comparison of Nextcloud quotas with what users are entitled to.
+
+CREATE TABLE IF NOT EXISTS quota_reconciliations (
+    id            BIGSERIAL PRIMARY KEY,
+    dry_run       BOOLEAN     NOT NULL,
+    checked       INT         NOT NULL,
+    in_sync       INT         NOT NULL,
+    drifted       INT         NOT NULL,
+    corrected     INT         NOT NULL,
+    unmanaged     INT         NOT NULL,
+    missing       INT         NOT NULL,
+    failed        INT         NOT NULL,
+    discrepancies JSONB       NOT NULL DEFAULT '[]',
+    started_at    TIMESTAMPTZ NOT NULL,
+    finished_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_quota_reconciliations_started ON quota_reconciliations (started_at DESC);
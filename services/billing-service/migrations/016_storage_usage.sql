@@ -0,0 +1,12 @@
+-- services/billing-service/migrations/016_storage_usage.sql
+-- Last Nextcloud storage usage fetched per user, and the quota warnings already sent for it.
+
+CREATE TABLE IF NOT EXISTS storage_usage (
+    user_id       BIGINT PRIMARY KEY,
+    used_bytes    BIGINT           NOT NULL,
+    free_bytes    BIGINT           NOT NULL,
+    quota_gb      INT              NOT NULL,
+    used_percent  DOUBLE PRECISION NOT NULL,
+    warning_level INT              NOT NULL DEFAULT 0,
+    fetched_at    TIMESTAMPTZ      NOT NULL
+);
@@ -0,0 +1,53 @@
+-- services/billing-service/migrations/017_webhooks.sql
+-- Outgoing webhooks: endpoints registered by admins, the events they receive and a log of every delivery attempt.
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id          BIGSERIAL PRIMARY KEY,
+    url         TEXT        NOT NULL,
+    description TEXT        NOT NULL DEFAULT '',
+    secret      TEXT        NOT NULL, -- Signs the deliveries, needed in plain text
+    event_types TEXT[]      NOT NULL,
+    is_active   BOOLEAN     NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Events are written in the same transaction as the change they describe, and only while an
+-- active endpoint is subscribed to their type.
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id         BIGSERIAL PRIMARY KEY,
+    type       VARCHAR(64) NOT NULL,
+    user_id    BIGINT,
+    data       JSONB       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id                   BIGSERIAL PRIMARY KEY,
+    event_id             BIGINT      NOT NULL REFERENCES webhook_events (id),
+    endpoint_id          BIGINT      NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+    status               VARCHAR(16) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'DELIVERED', 'DEAD')),
+    attempts             INT         NOT NULL DEFAULT 0,
+    next_attempt_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error           TEXT,
+    last_response_status INT,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at         TIMESTAMPTZ,
+    UNIQUE (event_id, endpoint_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries (endpoint_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id              BIGSERIAL PRIMARY KEY,
+    delivery_id     BIGINT      NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    response_status INT,  -- Unset if no response was received
+    response_body   TEXT, -- Truncated
+    error           TEXT,
+    duration_ms     INT         NOT NULL,
+    attempted_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts (delivery_id, attempted_at);
@@ -0,0 +1,6 @@
+-- services/billing-service/migrations/018_report_indexes.sql
+-- Indexes for the revenue reports, which read the state of every subscription at period boundaries.
+
+CREATE INDEX IF NOT EXISTS idx_subscription_history_subscription
+    ON subscription_history (subscription_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_subscription_history_event ON subscription_history (event, created_at);
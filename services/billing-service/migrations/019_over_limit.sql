@@ -0,0 +1,19 @@
+-- services/billing-service/migrations/019_over_limit.sql
+-- Users who store more than their plan allows, e.g. after a downgrade, and their grace period.
+
+CREATE TABLE IF NOT EXISTS over_limit_cases (
+    id               BIGSERIAL PRIMARY KEY,
+    user_id          BIGINT      NOT NULL,
+    status           VARCHAR(20) NOT NULL DEFAULT 'OPEN', -- OPEN, EXPIRED, RESOLVED
+    used_bytes       BIGINT      NOT NULL,
+    quota_gb         INT         NOT NULL,
+    grace_ends_at    TIMESTAMPTZ NOT NULL,
+    reminder_sent_at TIMESTAMPTZ,
+    opened_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expired_at       TIMESTAMPTZ,
+    resolved_at      TIMESTAMPTZ
+);
+
+-- A user has at most one unresolved case.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_over_limit_cases_unresolved ON over_limit_cases (user_id) WHERE status <> 'RESOLVED';
+CREATE INDEX IF NOT EXISTS idx_over_limit_cases_grace ON over_limit_cases (grace_ends_at) WHERE status = 'OPEN';
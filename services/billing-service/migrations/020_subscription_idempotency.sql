@@ -0,0 +1,39 @@
+-- services/billing-service/migrations/020_subscription_idempotency.sql
+-- At most one live subscription per user, and idempotency keys for retried internal writes.
+
+-- Retried registrations left some users with several live subscriptions. Keep the one changed
+-- last, which is the one an upgrade went to, and cancel the others.
+WITH duplicates AS (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY updated_at DESC, id DESC) AS rank
+        FROM user_subscriptions
+        WHERE status IN ('ACTIVE', 'TRIALING')
+    ) ranked
+    WHERE rank > 1
+), canceled AS (
+    UPDATE user_subscriptions s
+    SET status = 'CANCELED', ends_at = NOW(), canceled_at = NOW(), cancel_reason = 'OTHER',
+        cancel_feedback = 'Duplicate subscription', pending_plan_id = NULL, cancel_at_period_end = FALSE,
+        updated_at = NOW()
+    FROM duplicates d
+    WHERE s.id = d.id
+    RETURNING s.*
+)
+INSERT INTO subscription_history (subscription_id, user_id, event, plan_id, plan_version_id, status, starts_at, ends_at,
+                                  currency, pending_plan_id, reason, actor_type)
+SELECT id, user_id, 'CANCELED', plan_id, plan_version_id, status, starts_at, ends_at,
+       currency, pending_plan_id, 'Duplicate subscription', 'SYSTEM'
+FROM canceled;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_subscriptions_live ON user_subscriptions (user_id) WHERE status IN ('ACTIVE', 'TRIALING');
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope         VARCHAR(255) NOT NULL, -- Method and path, e.g. 'POST /internal/v1/subscriptions'
+    key           VARCHAR(255) NOT NULL,
+    request_hash  VARCHAR(64)  NOT NULL, -- SHA-256 of the body, a reused key must come with the same one
+    status_code   INT,                   -- NULL while the first request is in progress
+    content_type  VARCHAR(255),
+    response_body BYTEA,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (scope, key)
+);
@@ -0,0 +1,16 @@
+-- services/billing-service/migrations/021_coupon_redemption_end.sql
+-- When a coupon redemption stopped discounting, so that revenue reports can apply discounts
+-- as they were at any point in time.
+
+ALTER TABLE coupon_redemptions ADD COLUMN IF NOT EXISTS ended_at TIMESTAMPTZ;
+
+-- The end of earlier redemptions was not recorded. The last invoice discounted with the coupon
+-- is the closest known point; redemptions that never discounted one end when they started.
+UPDATE coupon_redemptions r
+SET ended_at = GREATEST(r.redeemed_at, (
+    SELECT MAX(i.created_at)
+    FROM invoices i
+    JOIN invoice_lines l ON l.invoice_id = i.id
+    WHERE i.subscription_id = r.subscription_id AND l.coupon_id = r.coupon_id
+))
+WHERE NOT r.is_active AND r.ended_at IS NULL;
@@ -0,0 +1,9 @@
+-- services/billing-service/migrations/022_video_storage.sql
+-- Bytes each user stores in video-service, as last reported by it. They count towards the
+-- storage quota together with the Nextcloud usage.
+
+CREATE TABLE IF NOT EXISTS video_storage_usage (
+    user_id     BIGINT PRIMARY KEY,
+    used_bytes  BIGINT      NOT NULL,
+    measured_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,9 @@
+-- services/billing-service/migrations/023_past_due.sql
+-- Past-due subscriptions: a renewal the balance did not cover keeps the subscription live until
+-- it is paid or its grace period ends.
+
+DROP INDEX IF EXISTS idx_user_subscriptions_live;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_subscriptions_live ON user_subscriptions (user_id) WHERE status IN ('ACTIVE', 'TRIALING', 'PAST_DUE');
+
+-- Open invoices are settled oldest first when the user tops up their balance.
+CREATE INDEX IF NOT EXISTS idx_invoices_open ON invoices (user_id, currency, created_at) WHERE status = 'OPEN';
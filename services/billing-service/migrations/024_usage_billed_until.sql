@@ -0,0 +1,12 @@
+-- services/billing-service/migrations/024_usage_billed_until.sql
+-- Where the invoiced metered usage of a subscription ends. Plan changes invoice the usage up to
+-- the change, so it can differ from the start of the billing period.
+
+ALTER TABLE user_subscriptions
+    ADD COLUMN IF NOT EXISTS usage_billed_until TIMESTAMPTZ;
+
+UPDATE user_subscriptions SET usage_billed_until = starts_at WHERE usage_billed_until IS NULL;
+
+ALTER TABLE user_subscriptions
+    ALTER COLUMN usage_billed_until SET DEFAULT NOW(),
+    ALTER COLUMN usage_billed_until SET NOT NULL;
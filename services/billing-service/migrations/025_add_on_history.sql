@@ -0,0 +1,20 @@
+-- services/billing-service/migrations/025_add_on_history.sql
+-- History of the add-ons users hold, so revenue reports can price them at any past point in time.
+
+CREATE TABLE IF NOT EXISTS user_add_on_history (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT      NOT NULL,
+    add_on_id  BIGINT      NOT NULL REFERENCES add_ons (id),
+    quantity   INTEGER     NOT NULL CHECK (quantity >= 0), -- After the change, 0 once removed
+    prices     JSONB       NOT NULL,                       -- The add-on's unit prices at the time
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_add_on_history_user ON user_add_on_history (user_id, add_on_id, created_at);
+
+-- Add-ons held before the history existed count from their last change on, at today's prices.
+INSERT INTO user_add_on_history (user_id, add_on_id, quantity, prices, created_at)
+SELECT ua.user_id, ua.add_on_id, ua.quantity, a.prices, ua.updated_at
+FROM user_add_ons ua
+JOIN add_ons a ON a.id = ua.add_on_id
+WHERE NOT EXISTS (SELECT 1 FROM user_add_on_history h WHERE h.user_id = ua.user_id AND h.add_on_id = ua.add_on_id);
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log"
 
+	"jcloud-project/libs/go-common/entitlements"
 	commontypes "jcloud-project/libs/go-common/types/jwt"
+	"jcloud-project/user-service/internal/client"
 	"jcloud-project/user-service/internal/config"
 	"jcloud-project/user-service/internal/handler"
 	"jcloud-project/user-service/internal/repository"
@@ -37,7 +39,10 @@ func main() {
 	// Dependency Injection
 	//
 	userRepo := repository.NewUserPostgresRepository(dbpool)
-	userService := service.NewUserService(userRepo, cfg.JWT.Secret)
+	entitlementsCache := entitlements.NewCache(entitlements.NewBillingSource(cfg.Billing.URL), cfg.Billing.EntitlementsCacheTTL)
+	go entitlements.Listen(context.Background(), dbpool, entitlementsCache)
+	userService := service.NewUserService(userRepo, entitlementsCache, client.NewBillingClient(cfg.Billing.URL, cfg.Billing.Timeout),
+		client.NewLogMailer(), cfg.JWT.Secret, cfg.Email.VerificationURL)
 
 	// Инициализируем каждый обработчик отдельно
 	authHandler := handler.NewAuthHandler(userService)
@@ -58,6 +63,8 @@ func main() {
 	// Public routes for authentication
 	api.POST("/users/register", authHandler.Register)
 	api.POST("/users/login", authHandler.Login)
+	api.POST("/users/verify-email", authHandler.VerifyEmail)
+	api.POST("/users/resend-verification", authHandler.ResendVerification)
 
 	// JWT Middleware Config
 	jwtConfig := echojwt.Config{
@@ -76,6 +83,7 @@ func main() {
 	// Internal routes
 	internalAPI := e.Group("/internal/v1")
 	internalAPI.GET("/users/:userId", internalApiHandler.GetInternalUserDetails)
+	internalAPI.GET("/user-ids", internalApiHandler.GetInternalUserIDs)
 
 	// Start server
 	log.Println("Starting user-service on :8080")
@@ -0,0 +1,64 @@
+// services/user-service/internal/client/billing_client.go
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+//
+// Billing Service Client
+//
+
+type BillingClient interface {
+	// CreateSubscription puts the user on the named plan. billing-service runs requests with
+	// the same idempotency key at most once. It reports whether a failure is worth retrying.
+	CreateSubscription(ctx context.Context, userID int64, planName, idempotencyKey string) (bool, error)
+}
+
+type billingClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewBillingClient calls billing-service's internal API, e.g. at "http://billing-service:8082".
+// timeout bounds a single request.
+func NewBillingClient(baseURL string, timeout time.Duration) BillingClient {
+	return &billingClient{baseURL: baseURL, httpClient: &http.Client{Timeout: timeout}}
+}
+
+func (c *billingClient) CreateSubscription(ctx context.Context, userID int64, planName, idempotencyKey string) (bool, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"userId":   userID,
+		"planName": planName,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/internal/v1/subscriptions", bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create billing-service request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to call billing service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK: // 200 if it already exists
+		return false, nil
+	case resp.StatusCode == http.StatusConflict || resp.StatusCode >= 500: // 409 while an earlier attempt is in progress
+		return true, fmt.Errorf("billing service returned status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("billing service returned status %d", resp.StatusCode)
+	}
+}
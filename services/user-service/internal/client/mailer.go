@@ -0,0 +1,29 @@
+// services/user-service/internal/client/mailer.go
+package client
+
+import (
+	"context"
+	"log"
+)
+
+//
+// Mailer
+//
+
+// Mailer sends emails to users, e.g. the link to verify their address.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type logMailer struct{}
+
+// NewLogMailer returns a Mailer that only writes emails to the service log.
+// It is used until the platform has a real email delivery.
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+func (m *logMailer) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("Email to %s: %s: %s", to, subject, body)
+	return nil
+}
@@ -4,6 +4,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
@@ -13,6 +14,8 @@ type Config struct {
 	Env      string `env:"ENV" env-default:"local"`
 	Postgres PostgresConfig
 	JWT      JWTConfig
+	Billing  BillingConfig
+	Email    EmailConfig
 }
 
 type PostgresConfig struct {
@@ -27,6 +30,20 @@ type JWTConfig struct {
 	Secret string `env:"JWT_SECRET" env-required:"true"`
 }
 
+type BillingConfig struct {
+	URL string `env:"BILLING_URL" env-default:"http://billing-service:8082"`
+	// Timeout bounds a single request to billing-service.
+	Timeout time.Duration `env:"BILLING_TIMEOUT" env-default:"10s"`
+	// EntitlementsCacheTTL is how long a user's permissions are cached. Changes published by
+	// billing-service drop them earlier.
+	EntitlementsCacheTTL time.Duration `env:"ENTITLEMENTS_CACHE_TTL" env-default:"30s"`
+}
+
+type EmailConfig struct {
+	// VerificationURL is the page that email verification links point to.
+	VerificationURL string `env:"EMAIL_VERIFICATION_URL" env-default:"http://localhost:3000/verify-email"`
+}
+
 func MustLoad() *Config {
 	// Загружаем .env файл только если он существует.
 	// В Docker-окружении переменные будут предоставлены через docker-compose.
@@ -8,12 +8,19 @@ import "time"
 //
 
 type User struct {
-	ID        int64     `json:"id"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"` // "-" means do not include this field in JSON responses
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       int64  `json:"id"`
+	Email    string `json:"email"`
+	Password string `json:"-"` // "-" means do not include this field in JSON responses
+	Role     string `json:"role"`
+	// EmailVerifiedAt is when the user confirmed they own Email, unset until they did.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// EmailVerified reports whether the user confirmed their current email address.
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 // UserPublic represents the data of a user that is safe to be exposed to clients.
@@ -53,3 +53,38 @@ func (h *AuthHandler) Login(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, echo.Map{"token": token})
 }
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (h *AuthHandler) VerifyEmail(c echo.Context) error {
+	var req verifyEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	if err := h.service.VerifyEmail(c.Request().Context(), req.Token); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+type resendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// ResendVerification always answers 202, whether or not the address belongs to a user.
+func (h *AuthHandler) ResendVerification(c echo.Context) error {
+	var req resendVerificationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request format"})
+	}
+
+	if err := h.service.ResendVerification(c.Request().Context(), req.Email); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
@@ -30,6 +30,9 @@ func CustomHTTPErrorHandler(err error, c echo.Context) {
 	case errors.Is(err, ierr.ErrConflict):
 		httpCode = http.StatusConflict
 		errMsg = err.Error()
+	case errors.Is(err, ierr.ErrInvalidInput):
+		httpCode = http.StatusBadRequest
+		errMsg = err.Error()
 	case errors.Is(err, ierr.ErrForbidden):
 		httpCode = http.StatusForbidden
 		errMsg = ierr.ErrForbidden.Error()
@@ -31,7 +31,34 @@ func (h *InternalApiHandler) GetInternalUserDetails(c echo.Context) error {
 
 	// Возвращаем только необходимые поля для внутренних клиентов
 	return c.JSON(http.StatusOK, echo.Map{
-		"id":    user.ID,
-		"email": user.Email,
+		"id":             user.ID,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified(),
 	})
 }
+
+// GetInternalUserIDs pages through the ids of all users, e.g. for billing-service's repair of
+// missing subscriptions. Pass the last id of a page as ?afterId to get the next one.
+func (h *InternalApiHandler) GetInternalUserIDs(c echo.Context) error {
+	var afterID int64
+	if raw := c.QueryParam("afterId"); raw != "" {
+		var err error
+		if afterID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid afterId"})
+		}
+	}
+	limit := 0
+	if raw := c.QueryParam("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid limit"})
+		}
+	}
+
+	ids, err := h.service.GetUserIDs(c.Request().Context(), afterID, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"user_ids": ids})
+}
@@ -4,6 +4,7 @@ package repository
 import (
 	"context"
 	"jcloud-project/user-service/internal/domain"
+	"time"
 )
 
 type UserRepository interface {
@@ -12,4 +13,13 @@ type UserRepository interface {
 	FindByID(ctx context.Context, id int64) (*domain.User, error)
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
 	FindAll(ctx context.Context) ([]domain.UserPublic, error)
+	// FindIDsAfter returns up to limit user ids greater than afterID in ascending order.
+	FindIDsAfter(ctx context.Context, afterID int64, limit int) ([]int64, error)
+	// CreateEmailVerification stores the hash of a token that verifies the user's current email
+	// until expiresAt.
+	CreateEmailVerification(ctx context.Context, userID int64, email, tokenHash string, expiresAt time.Time) error
+	// VerifyEmail marks the email of the token's user as verified and deletes their tokens.
+	// Returns ierr.ErrNotFound if the token is unknown, expired or for an address the user no
+	// longer has.
+	VerifyEmail(ctx context.Context, tokenHash string) error
 }
@@ -39,15 +39,15 @@ func (r *userPostgresRepository) Create(ctx context.Context, user *domain.User)
 }
 
 func (r *userPostgresRepository) Update(ctx context.Context, user *domain.User) error {
-	query := `UPDATE users SET email = $1, role = $2, updated_at = $3 WHERE id = $4`
-	_, err := r.db.Exec(ctx, query, user.Email, user.Role, time.Now(), user.ID)
+	query := `UPDATE users SET email = $1, role = $2, email_verified_at = $3, updated_at = $4 WHERE id = $5`
+	_, err := r.db.Exec(ctx, query, user.Email, user.Role, user.EmailVerifiedAt, time.Now(), user.ID)
 	return err
 }
 
 func (r *userPostgresRepository) FindByID(ctx context.Context, id int64) (*domain.User, error) {
-	query := `SELECT id, email, password, role, created_at, updated_at FROM users WHERE id = $1`
+	query := `SELECT id, email, password, role, email_verified_at, created_at, updated_at FROM users WHERE id = $1`
 	var u domain.User
-	err := r.db.QueryRow(ctx, query, id).Scan(&u.ID, &u.Email, &u.Password, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, id).Scan(&u.ID, &u.Email, &u.Password, &u.Role, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ierr.ErrNotFound
@@ -58,9 +58,9 @@ func (r *userPostgresRepository) FindByID(ctx context.Context, id int64) (*domai
 }
 
 func (r *userPostgresRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
-	query := `SELECT id, email, password, role, created_at, updated_at FROM users WHERE email = $1`
+	query := `SELECT id, email, password, role, email_verified_at, created_at, updated_at FROM users WHERE email = $1`
 	var u domain.User
-	err := r.db.QueryRow(ctx, query, email).Scan(&u.ID, &u.Email, &u.Password, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, email).Scan(&u.ID, &u.Email, &u.Password, &u.Role, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ierr.ErrNotFound
@@ -89,3 +89,53 @@ func (r *userPostgresRepository) FindAll(ctx context.Context) ([]domain.UserPubl
 
 	return users, nil
 }
+
+func (r *userPostgresRepository) FindIDsAfter(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `SELECT id FROM users WHERE id > $1 ORDER BY id ASC LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *userPostgresRepository) CreateEmailVerification(ctx context.Context, userID int64, email, tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO email_verifications (token_hash, user_id, email, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.Exec(ctx, query, tokenHash, userID, email, expiresAt)
+	return err
+}
+
+func (r *userPostgresRepository) VerifyEmail(ctx context.Context, tokenHash string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE users u SET email_verified_at = COALESCE(u.email_verified_at, NOW()), updated_at = NOW()
+		FROM email_verifications v
+		WHERE v.token_hash = $1 AND v.expires_at > NOW() AND u.id = v.user_id AND u.email = v.email
+		RETURNING u.id`
+	var userID int64
+	if err := tx.QueryRow(ctx, query, tokenHash).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ierr.ErrNotFound
+		}
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM email_verifications WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
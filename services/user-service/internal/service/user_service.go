@@ -2,16 +2,20 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"jcloud-project/libs/go-common/entitlements"
 	"jcloud-project/libs/go-common/ierr"
 	commontypes "jcloud-project/libs/go-common/types/jwt"
+	"jcloud-project/user-service/internal/client"
 	"jcloud-project/user-service/internal/domain"
 	"jcloud-project/user-service/internal/repository"
 	"log"
-	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -23,18 +27,46 @@ type UserService interface {
 	Login(ctx context.Context, email, password string) (string, error)
 	GetProfile(ctx context.Context, userID int64) (*domain.User, error)
 	GetAllUsers(ctx context.Context) ([]domain.UserPublic, error)
+	// GetUserIDs pages through the ids of all users for other services.
+	GetUserIDs(ctx context.Context, afterID int64, limit int) ([]int64, error)
 	PatchUser(ctx context.Context, userID int64, email *string, role *string) (*domain.User, error)
+	// VerifyEmail confirms the address a verification link was sent to.
+	VerifyEmail(ctx context.Context, token string) error
+	// ResendVerification sends a new verification link to an unverified address. It does not
+	// tell whether the address belongs to a user.
+	ResendVerification(ctx context.Context, email string) error
 }
 
+const (
+	// maxUserIDsPage caps how many user ids GetUserIDs returns at once.
+	maxUserIDsPage = 1000
+	// subscriptionAttempts is how often assigning the default subscription is tried.
+	subscriptionAttempts = 4
+	// subscriptionRetryDelay is the wait before the first retry, doubled for every further one.
+	subscriptionRetryDelay = 250 * time.Millisecond
+	// verificationTTL is how long an email verification link is valid.
+	verificationTTL = 48 * time.Hour
+)
+
 type userService struct {
-	repo      repository.UserRepository
-	jwtSecret string
+	repo            repository.UserRepository
+	entitlements    entitlements.Source
+	billing         client.BillingClient
+	mailer          client.Mailer
+	jwtSecret       string
+	verificationURL string
 }
 
-func NewUserService(repo repository.UserRepository, jwtSecret string) UserService {
+// NewUserService creates the user service. verificationURL is the page that verification links
+// point to, the token is appended as ?token=.
+func NewUserService(repo repository.UserRepository, entitlementsSource entitlements.Source, billing client.BillingClient, mailer client.Mailer, jwtSecret, verificationURL string) UserService {
 	return &userService{
-		repo:      repo,
-		jwtSecret: jwtSecret,
+		repo:            repo,
+		entitlements:    entitlementsSource,
+		billing:         billing,
+		mailer:          mailer,
+		jwtSecret:       jwtSecret,
+		verificationURL: verificationURL,
 	}
 }
 
@@ -57,8 +89,13 @@ func (s *userService) Register(ctx context.Context, email, password string) (*do
 	}
 
 	if err := s.assignDefaultSubscription(ctx, user.ID); err != nil {
+		// billing-service's repair job assigns it later.
 		log.Printf("CRITICAL: Failed to assign default subscription for new user %d: %v", user.ID, err)
 	}
+	if err := s.sendVerification(ctx, user); err != nil {
+		// The user can ask for a new link.
+		log.Printf("Failed to send email verification to user %d: %v", user.ID, err)
+	}
 
 	return user, nil
 }
@@ -76,16 +113,17 @@ func (s *userService) Login(ctx context.Context, email, password string) (string
 		return "", ierr.ErrInvalidCredentials
 	}
 
-	permissions, err := s.fetchUserPermissions(ctx, user.ID)
+	// Only a snapshot for clients; services check the live permissions.
+	permissions, err := s.entitlements.Entitlements(ctx, user.ID)
 	if err != nil {
 		log.Printf("Warning: Could not fetch permissions for user %d: %v", user.ID, err)
-		permissions = make(map[string]interface{})
+		permissions = entitlements.Default()
 	}
 
 	claims := &commontypes.JwtCustomClaims{
 		UserID:      user.ID,
 		Role:        user.Role,
-		Permissions: permissions,
+		Permissions: &permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 72)),
 		},
@@ -109,14 +147,22 @@ func (s *userService) GetAllUsers(ctx context.Context) ([]domain.UserPublic, err
 	return s.repo.FindAll(ctx)
 }
 
+func (s *userService) GetUserIDs(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	if limit <= 0 || limit > maxUserIDsPage {
+		limit = maxUserIDsPage
+	}
+	return s.repo.FindIDsAfter(ctx, afterID, limit)
+}
+
 func (s *userService) PatchUser(ctx context.Context, userID int64, email *string, role *string) (*domain.User, error) {
 	user, err := s.repo.FindByID(ctx, userID)
 	if err != nil {
 		return nil, err // repo.FindByID уже возвращает ierr.ErrNotFound
 	}
 
-	if email != nil {
+	if email != nil && *email != user.Email {
 		user.Email = *email
+		user.EmailVerifiedAt = nil // The new address has to be verified again
 	}
 	if role != nil {
 		user.Role = *role
@@ -129,50 +175,81 @@ func (s *userService) PatchUser(ctx context.Context, userID int64, email *string
 	return user, nil
 }
 
-// --- Private methods ---
+func (s *userService) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("verification token is required: %w", ierr.ErrInvalidInput)
+	}
+	if err := s.repo.VerifyEmail(ctx, hashToken(token)); err != nil {
+		if errors.Is(err, ierr.ErrNotFound) {
+			return fmt.Errorf("verification link is invalid or expired: %w", ierr.ErrInvalidInput)
+		}
+		return err
+	}
+	return nil
+}
 
-func (s *userService) fetchUserPermissions(ctx context.Context, userID int64) (map[string]interface{}, error) {
-	billingURL := fmt.Sprintf("http://billing-service:8082/internal/v1/permissions/%d", userID)
-	req, err := http.NewRequestWithContext(ctx, "GET", billingURL, nil)
+func (s *userService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, ierr.ErrNotFound) {
+			return nil
+		}
+		return err
 	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call billing service: %w", err)
+	if user.EmailVerified() {
+		return nil
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("billing service returned status %d", resp.StatusCode)
+	return s.sendVerification(ctx, user)
+}
+
+// --- Private methods ---
+
+// sendVerification emails the user a link that verifies their current address. Only the hash of
+// its token is stored.
+func (s *userService) sendVerification(ctx context.Context, user *domain.User) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
 	}
-	var permissions map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&permissions); err != nil {
-		return nil, fmt.Errorf("failed to decode permissions response: %w", err)
+	token := hex.EncodeToString(raw)
+
+	expiresAt := time.Now().Add(verificationTTL)
+	if err := s.repo.CreateEmailVerification(ctx, user.ID, user.Email, hashToken(token), expiresAt); err != nil {
+		return fmt.Errorf("failed to store verification token: %w", err)
 	}
-	return permissions, nil
+
+	link := s.verificationURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Confirm your email address by opening %s. The link expires in %s.", link, verificationTTL)
+	return s.mailer.Send(ctx, user.Email, "Verify your email address", body)
+}
+
+// hashToken is how verification tokens are stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
+// assignDefaultSubscription puts the new user on the Free plan. It retries failed calls with
+// the same idempotency key, which billing-service runs at most once.
 func (s *userService) assignDefaultSubscription(ctx context.Context, userID int64) error {
-	billingURL := "http://billing-service:8082/internal/v1/subscriptions"
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"userId":   userID,
-		"planName": "Free",
-	})
-	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %w", err)
-	}
-	req, err := http.NewRequestWithContext(ctx, "POST", billingURL, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return err
+	idempotencyKey := fmt.Sprintf("default-subscription-%d", userID)
+
+	delay := subscriptionRetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := s.billing.CreateSubscription(ctx, userID, "Free", idempotencyKey)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == subscriptionAttempts {
+			return err
+		}
+		log.Printf("Assigning default subscription for user %d failed (attempt %d), retrying: %v", userID, attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
 	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to call billing service: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("billing service returned non-201 status: %d", resp.StatusCode)
-	}
-	return nil
 }
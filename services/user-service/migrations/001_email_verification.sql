@@ -0,0 +1,15 @@
+-- services/user-service/migrations/001_email_verification.sql
+-- Email verification: when a user's address was confirmed and the links sent to confirm it.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    token_hash CHAR(64)     PRIMARY KEY, -- SHA-256 of the token, the token itself is only sent
+    user_id    BIGINT       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email      VARCHAR(255) NOT NULL, -- The address the link was sent to, a changed email needs a new link
+    expires_at TIMESTAMPTZ  NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user ON email_verifications (user_id);
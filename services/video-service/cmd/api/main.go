@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"log"
 
+	"jcloud-project/libs/go-common/entitlements"
 	commontypes "jcloud-project/libs/go-common/types/jwt"
+	"jcloud-project/video-service/internal/client"
 	"jcloud-project/video-service/internal/config"
 	"jcloud-project/video-service/internal/handler"
 	"jcloud-project/video-service/internal/repository"
 	"jcloud-project/video-service/internal/service"
+	"jcloud-project/video-service/internal/worker"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -37,9 +40,21 @@ func main() {
 	// Dependency Injection
 	//
 	videoRepo := repository.NewVideoPostgresRepository(dbpool)
-	videoService := service.NewVideoService(videoRepo)
+	entitlementsCache := entitlements.NewCache(entitlements.NewBillingSource(cfg.Billing.URL), cfg.Billing.EntitlementsCacheTTL)
+	go entitlements.Listen(context.Background(), dbpool, entitlementsCache)
+	billingClient := client.NewBillingClient(cfg.Billing.URL)
+	storageService := service.NewStorageService(videoRepo, billingClient)
+	videoService := service.NewVideoService(videoRepo, entitlementsCache, storageService)
 	videoHandler := handler.NewVideoHandler(videoService)
 
+	//
+	// Background Workers
+	//
+	storageReportWorker := worker.NewStorageReportWorker(storageService, cfg.Billing.StorageReportInterval)
+	go storageReportWorker.Run(context.Background())
+	storageUsageWorker := worker.NewStorageUsageWorker(storageService)
+	go storageUsageWorker.Run(context.Background())
+
 	//
 	// HTTP Server (Echo)
 	//
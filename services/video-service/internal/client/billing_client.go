@@ -0,0 +1,98 @@
+// services/video-service/internal/client/billing_client.go
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+//
+// Billing Service Client
+//
+
+type BillingClient interface {
+	// ReportVideoStorage tells billing-service how many bytes the user's videos took at
+	// measuredAt, which counts towards their storage quota. Re-sending a report is safe.
+	ReportVideoStorage(ctx context.Context, userID, usedBytes int64, measuredAt time.Time) error
+	// RecordUsage sends metered usage. Events are deduplicated by EventID, so a batch may be
+	// sent again after a failure. It returns the IDs of events billing-service dropped because
+	// their billing period was invoiced already.
+	RecordUsage(ctx context.Context, events []UsageEvent) ([]string, error)
+}
+
+// Usage metrics billing-service meters.
+const (
+	UsageMetricStorageGBHours = "STORAGE_GB_HOURS"
+)
+
+// UsageEvent is one usage measurement. EventID must be the same whenever the same measurement
+// is sent, so that billing-service counts it once.
+type UsageEvent struct {
+	EventID    string    `json:"eventId"`
+	UserID     int64     `json:"userId"`
+	Metric     string    `json:"metric"`
+	Quantity   string    `json:"quantity"` // Decimal with at most three fractional digits
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+type billingClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewBillingClient calls billing-service's internal API, e.g. at "http://billing-service:8082".
+func NewBillingClient(baseURL string) BillingClient {
+	return &billingClient{baseURL: baseURL, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (c *billingClient) ReportVideoStorage(ctx context.Context, userID, usedBytes int64, measuredAt time.Time) error {
+	body, err := json.Marshal(map[string]interface{}{"usedBytes": usedBytes, "measuredAt": measuredAt})
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/internal/v1/storage/%d/video", c.baseURL, userID)
+	return c.send(ctx, "PUT", endpoint, body, http.StatusNoContent, nil)
+}
+
+func (c *billingClient) RecordUsage(ctx context.Context, events []UsageEvent) ([]string, error) {
+	body, err := json.Marshal(map[string]interface{}{"events": events})
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Late []string `json:"late"`
+	}
+	if err := c.send(ctx, "POST", c.baseURL+"/internal/v1/usage", body, http.StatusAccepted, &result); err != nil {
+		return nil, err
+	}
+	return result.Late, nil
+}
+
+// send performs a request and decodes the response into `result` if set.
+func (c *billingClient) send(ctx context.Context, method, endpoint string, body []byte, wantStatus int, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create billing-service request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute billing-service request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		return fmt.Errorf("billing-service returned status %d", resp.StatusCode)
+	}
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to decode billing-service response: %w", err)
+		}
+	}
+	return nil
+}
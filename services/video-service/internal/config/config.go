@@ -4,6 +4,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
@@ -13,6 +14,7 @@ type Config struct {
 	Env      string `env:"ENV" env-default:"local"`
 	Postgres PostgresConfig
 	JWT      JWTConfig
+	Billing  BillingConfig
 }
 
 type PostgresConfig struct {
@@ -27,6 +29,16 @@ type JWTConfig struct {
 	Secret string `env:"JWT_SECRET" env-required:"true"`
 }
 
+type BillingConfig struct {
+	URL string `env:"BILLING_URL" env-default:"http://billing-service:8082"`
+	// EntitlementsCacheTTL is how long a user's permissions are cached. Changes published by
+	// billing-service drop them earlier.
+	EntitlementsCacheTTL time.Duration `env:"ENTITLEMENTS_CACHE_TTL" env-default:"30s"`
+	// StorageReportInterval is how often every user's video storage is reported, on top of
+	// the report after each upload.
+	StorageReportInterval time.Duration `env:"VIDEO_STORAGE_REPORT_INTERVAL" env-default:"1h"`
+}
+
 func MustLoad() *Config {
 	// Загружаем .env файл только если он существует.
 	if _, err := os.Stat("../../.env"); err == nil {
@@ -8,4 +8,8 @@ import (
 
 type VideoRepository interface {
 	Create(ctx context.Context, video *domain.Video) error
+	// FindFilePathsByUserID returns where the user's videos are stored.
+	FindFilePathsByUserID(ctx context.Context, userID int64) ([]string, error)
+	// FindOwnerIDs returns the users who have uploaded at least one video.
+	FindOwnerIDs(ctx context.Context) ([]int64, error)
 }
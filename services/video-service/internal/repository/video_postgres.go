@@ -32,3 +32,39 @@ func (r *videoPostgresRepository) Create(ctx context.Context, video *domain.Vide
 
 	return err
 }
+
+func (r *videoPostgresRepository) FindFilePathsByUserID(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT file_path FROM videos WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+func (r *videoPostgresRepository) FindOwnerIDs(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `SELECT DISTINCT user_id FROM videos ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
@@ -0,0 +1,133 @@
+// services/video-service/internal/service/storage_service.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"jcloud-project/video-service/internal/client"
+	"jcloud-project/video-service/internal/repository"
+	"log"
+	"os"
+	"time"
+)
+
+// usageBatch is how many usage events are sent to billing-service at once.
+const usageBatch = 500
+
+// StorageService measures how much disk space users' videos take and reports it to
+// billing-service, where it counts towards their storage quota and is metered in GB-hours.
+type StorageService interface {
+	// Report measures the user's videos and reports their size.
+	Report(ctx context.Context, userID int64) error
+	// ReportAll reports every user who has uploaded a video, so that changes made outside of
+	// uploads are picked up too.
+	ReportAll(ctx context.Context) error
+	// RecordStorageHour meters the storage of every user who has uploaded a video for the
+	// hour that contains `at`, as one GB-hour event per user and hour. Recording the same hour
+	// again, e.g. after a restart, has no effect.
+	RecordStorageHour(ctx context.Context, at time.Time) error
+}
+
+type storageService struct {
+	repo          repository.VideoRepository
+	billingClient client.BillingClient
+}
+
+func NewStorageService(repo repository.VideoRepository, billingClient client.BillingClient) StorageService {
+	return &storageService{repo: repo, billingClient: billingClient}
+}
+
+func (s *storageService) Report(ctx context.Context, userID int64) error {
+	measuredAt := time.Now()
+	usedBytes, err := s.storedBytes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.billingClient.ReportVideoStorage(ctx, userID, usedBytes, measuredAt); err != nil {
+		return fmt.Errorf("failed to report video storage of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (s *storageService) ReportAll(ctx context.Context) error {
+	userIDs, err := s.repo.FindOwnerIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := s.Report(ctx, userID); err != nil {
+			log.Printf("Failed to report video storage of user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func (s *storageService) RecordStorageHour(ctx context.Context, at time.Time) error {
+	userIDs, err := s.repo.FindOwnerIDs(ctx)
+	if err != nil {
+		return err
+	}
+	hour := at.UTC().Truncate(time.Hour)
+
+	events := make([]client.UsageEvent, 0, len(userIDs))
+	for _, userID := range userIDs {
+		usedBytes, err := s.storedBytes(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to measure video storage of user %d: %v", userID, err)
+			continue
+		}
+		if usedBytes == 0 {
+			continue
+		}
+		events = append(events, client.UsageEvent{
+			// One event per user and hour, whenever and however often it is measured.
+			EventID:    fmt.Sprintf("video-storage-%d-%s", userID, hour.Format("2006010215")),
+			UserID:     userID,
+			Metric:     client.UsageMetricStorageGBHours,
+			Quantity:   gigabyteHours(usedBytes),
+			OccurredAt: at,
+		})
+	}
+
+	for start := 0; start < len(events); start += usageBatch {
+		batch := events[start:min(start+usageBatch, len(events))]
+		late, err := s.billingClient.RecordUsage(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to record storage usage of %s: %w", hour.Format(time.RFC3339), err)
+		}
+		if len(late) > 0 {
+			log.Printf("Billing-service dropped %d storage usage events of already invoiced periods: %v", len(late), late)
+		}
+	}
+	return nil
+}
+
+// gigabyteHours formats one hour of storing `bytes` in binary GB-hours with the three
+// fractional digits billing-service accepts, rounded up so that small libraries are metered.
+func gigabyteHours(bytes int64) string {
+	thousandths := (bytes*1000 + (1<<30 - 1)) >> 30
+	return fmt.Sprintf("%d.%03d", thousandths/1000, thousandths%1000)
+}
+
+// storedBytes sums the size of the user's video files on disk. Files that no longer exist
+// take no space.
+func (s *storageService) storedBytes(ctx context.Context, userID int64) (int64, error) {
+	paths, err := s.repo.FindFilePathsByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find videos of user %d: %w", userID, err)
+	}
+	var total int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return 0, fmt.Errorf("failed to measure %s: %w", path, err)
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
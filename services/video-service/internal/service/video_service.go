@@ -5,10 +5,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"jcloud-project/libs/go-common/entitlements"
 	"jcloud-project/libs/go-common/ierr"
 	commontypes "jcloud-project/libs/go-common/types/jwt"
 	"jcloud-project/video-service/internal/domain"
 	"jcloud-project/video-service/internal/repository"
+	"log"
 	"mime/multipart"
 	"os"
 	"path/filepath"
@@ -20,23 +22,33 @@ type VideoService interface {
 }
 
 type videoService struct {
-	repo repository.VideoRepository
+	repo         repository.VideoRepository
+	entitlements entitlements.Source
+	storage      StorageService
 }
 
-func NewVideoService(repo repository.VideoRepository) VideoService {
-	return &videoService{repo: repo}
+func NewVideoService(repo repository.VideoRepository, entitlementsSource entitlements.Source, storage StorageService) VideoService {
+	return &videoService{repo: repo, entitlements: entitlementsSource, storage: storage}
 }
 
 // ProcessNewVideoUpload handles the business logic of saving a video file and creating a DB record.
 func (s *videoService) ProcessNewVideoUpload(ctx context.Context, claims *commontypes.JwtCustomClaims, title, description string, fileHeader *multipart.FileHeader) (*domain.Video, error) {
-	// Шаг 1: Проверка прав доступа из JWT
-	maxSizeMb, ok := claims.Permissions["max_upload_size_mb"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("permission 'max_upload_size_mb' is missing: %w", ierr.ErrForbidden)
+	// Шаг 1: Проверка прав доступа
+	// The JWT holds the permissions at login, which miss any upgrade since; use the live ones.
+	permissions, err := s.entitlements.Entitlements(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions of user %d: %w", claims.UserID, err)
+	}
+	if permissions.ReadOnly {
+		return nil, fmt.Errorf("your storage exceeds your plan, uploads are paused until you free up space or upgrade: %w", ierr.ErrForbidden)
+	}
+	maxSizeMb := permissions.MaxUploadSizeMB
+	if maxSizeMb <= 0 {
+		return nil, fmt.Errorf("uploads are not included in the plan: %w", ierr.ErrForbidden)
 	}
 
-	if fileHeader.Size > int64(maxSizeMb*1024*1024) {
-		return nil, fmt.Errorf("file size exceeds the allowed limit of %.0f MB: %w", maxSizeMb, ierr.ErrForbidden)
+	if fileHeader.Size > int64(maxSizeMb)*1024*1024 {
+		return nil, fmt.Errorf("file size exceeds the allowed limit of %d MB: %w", maxSizeMb, ierr.ErrForbidden)
 	}
 
 	// Шаг 2: Сохранение файла (временная логика)
@@ -73,5 +85,10 @@ func (s *videoService) ProcessNewVideoUpload(ctx context.Context, claims *common
 		return nil, fmt.Errorf("failed to create video record in database: %w", err)
 	}
 
+	// The upload counts towards the user's storage quota; the storage report worker retries.
+	if err := s.storage.Report(ctx, claims.UserID); err != nil {
+		log.Printf("Failed to report storage after upload of video %d: %v", video.ID, err)
+	}
+
 	return video, nil
 }
@@ -0,0 +1,38 @@
+// services/video-service/internal/worker/storage_report_worker.go
+package worker
+
+import (
+	"context"
+	"jcloud-project/video-service/internal/service"
+	"log"
+	"time"
+)
+
+// StorageReportWorker periodically reports every user's video storage to billing-service.
+// Uploads report right away; this catches reports that failed and files changed on disk.
+type StorageReportWorker struct {
+	service  service.StorageService
+	interval time.Duration
+}
+
+func NewStorageReportWorker(s service.StorageService, interval time.Duration) *StorageReportWorker {
+	return &StorageReportWorker{service: s, interval: interval}
+}
+
+// Run blocks until ctx is canceled, reporting once per interval.
+func (w *StorageReportWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := w.service.ReportAll(ctx); err != nil {
+			log.Printf("Storage report worker failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// services/video-service/internal/worker/storage_usage_worker.go
+package worker
+
+import (
+	"context"
+	"jcloud-project/video-service/internal/service"
+	"log"
+	"time"
+)
+
+// StorageUsageWorker meters every user's video storage once per hour, which billing-service
+// bills in GB-hours.
+type StorageUsageWorker struct {
+	service service.StorageService
+}
+
+func NewStorageUsageWorker(s service.StorageService) *StorageUsageWorker {
+	return &StorageUsageWorker{service: s}
+}
+
+// storageUsageInterval is how often the current hour is recorded. Recording an hour again is a
+// no-op, so ticking several times an hour only retries an hour whose recording failed.
+const storageUsageInterval = 10 * time.Minute
+
+// Run blocks until ctx is canceled, recording the current hour right away and then every
+// storageUsageInterval.
+func (w *StorageUsageWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(storageUsageInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.service.RecordStorageHour(ctx, time.Now()); err != nil {
+			log.Printf("Storage usage worker failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}